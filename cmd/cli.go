@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"fc-pr-tracker/internal/config"
+	"fc-pr-tracker/internal/logger"
+	"fc-pr-tracker/internal/provider"
+	"fc-pr-tracker/pkg/models"
+)
+
+// defaultConfigPath is used when neither --config nor PR_TRACKER_CONFIG is set
+const defaultConfigPath = "config.yaml"
+
+// configEnv names the environment variable pointing at an alternate configuration file
+const configEnv = "PR_TRACKER_CONFIG"
+
+// session holds what a command runs with
+type session struct {
+	cfg        *config.Config
+	configPath string
+	catalog    *provider.Catalog // nil for commands that do not talk to the SCM providers
+	opts       options
+	quiet      bool // keep logs off stdout, which carries the command output
+}
+
+// loadConfig loads the configuration file and sets up logging as it describes
+func (s *session) loadConfig() (*config.Config, error) {
+	cfg, err := config.Load(s.configPath)
+	if err != nil {
+		return nil, err
+	}
+	if s.quiet {
+		// Logs still go to the log file
+		cfg.Log.Stdout = false
+	}
+	if s.cfg == nil || !reflect.DeepEqual(s.cfg.Log, cfg.Log) {
+		logger.Init(cfg)
+	}
+	return cfg, nil
+}
+
+// reload loads the configuration file again and creates the providers and notifiers it describes
+func (s *session) reload() (*service, error) {
+	cfg, err := s.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	s.cfg = cfg
+	return newService(cfg, provider.NewCatalog(cfg)), nil
+}
+
+// command is a CLI subcommand
+type command struct {
+	name    string
+	summary string
+	// connect creates the providers and tests their connection before running
+	connect bool
+	// output commands write their result to stdout, so logs are kept off it
+	output bool
+	flags  func(fs *flag.FlagSet, opts *options)
+	run    func(ctx context.Context, s *session) int
+}
+
+func dryRunFlag(fs *flag.FlagSet, opts *options) {
+	fs.BoolVar(&opts.dryRun, "dry-run", opts.dryRun, "write every notifier's output to stdout instead of sending it")
+}
+
+// commands lists the subcommands in the order they are shown in the usage
+var commands = []command{
+	{name: "serve", summary: "monitor PRs and notify on the configured interval (default)", connect: true, flags: dryRunFlag, run: serve},
+	{name: "check", summary: "run a single cycle; exit 0 none stale, 2 stale PRs, 3 partial failure", connect: true, flags: dryRunFlag, run: check},
+	{name: "list", summary: "print the stale PRs (--all: every open PR) as a table, JSON, CSV or Markdown", connect: true, output: true, flags: listFlags, run: list},
+	{name: "validate-config", summary: "load the configuration and report every problem", output: true, run: validateConfig},
+	{name: "test-notifiers", summary: "send a sample message to every configured notifier", flags: dryRunFlag, run: testNotifiers},
+}
+
+func lookupCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// execute parses the command line, runs the selected command and returns the process exit code
+func execute(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	var configPath string
+	var runOnce bool
+	opts := options{out: stdout}
+
+	global := flag.NewFlagSet("pr-tracker", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	configFlag(global, &configPath)
+	global.BoolVar(&runOnce, "run-once", false, "same as the check command")
+	dryRunFlag(global, &opts)
+	global.Usage = func() { printUsage(stderr, global) }
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	name, rest := "serve", global.Args()
+	if len(rest) > 0 {
+		name, rest = rest[0], rest[1:]
+	} else if runOnce {
+		name = "check"
+	}
+	cmd, ok := lookupCommand(name)
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", name)
+		printUsage(stderr, global)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("pr-tracker "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configFlag(fs, &configPath)
+	if cmd.flags != nil {
+		cmd.flags(fs, &opts)
+	}
+	if err := fs.Parse(rest); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		return exitUsage
+	}
+
+	s := &session{configPath: resolveConfigPath(configPath), opts: opts, quiet: cmd.output || opts.dryRun}
+	cfg, err := s.loadConfig()
+	if err != nil {
+		// The logger is configured by the file that failed to load
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	s.cfg = cfg
+	slog.Info("PR tracker started",
+		"command", cmd.name,
+		"config", s.configPath,
+		"log_file", s.cfg.Log.File,
+		"log_level", s.cfg.Log.Level,
+		"dry_run", opts.dryRun)
+
+	if cmd.connect {
+		s.catalog = provider.NewCatalog(s.cfg)
+		if err := testConnections(ctx, s.catalog); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+	}
+	return cmd.run(ctx, s)
+}
+
+func configFlag(fs *flag.FlagSet, path *string) {
+	fs.StringVar(path, "config", *path, "configuration file (default $"+configEnv+" or "+defaultConfigPath+")")
+}
+
+// resolveConfigPath picks the configuration file: --config, then PR_TRACKER_CONFIG, then config.yaml
+func resolveConfigPath(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if env := os.Getenv(configEnv); env != "" {
+		return env
+	}
+	return defaultConfigPath
+}
+
+func printUsage(w io.Writer, global *flag.FlagSet) {
+	fmt.Fprintf(w, "Usage: pr-tracker [--config file] <command> [flags]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-16s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(w, "\nGlobal flags:\n")
+	global.PrintDefaults()
+	fmt.Fprintf(w, "\nRun 'pr-tracker <command> --help' for the flags of a command.\n")
+}
+
+// testConnections checks every configured SCM provider
+func testConnections(ctx context.Context, catalog *provider.Catalog) error {
+	for _, p := range catalog.Providers {
+		if err := p.TestConnection(ctx); err != nil {
+			slog.Error("Provider connection test failed", "provider", p.Name(), "error", err)
+			return fmt.Errorf("%s connection test failed: %v", p.Name(), err)
+		}
+		slog.Info("Provider connection test succeeded", "provider", p.Name())
+	}
+	return nil
+}
+
+// validateConfig reports the configuration that was loaded, without contacting any server
+func validateConfig(ctx context.Context, s *session) int {
+	catalog := provider.NewCatalog(s.cfg)
+	var names []string
+	for _, p := range catalog.Providers {
+		names = append(names, p.Name())
+	}
+	var notifiers []string
+	for _, t := range newTiers(s.cfg) {
+		for _, n := range t.notifiers {
+			notifiers = append(notifiers, t.label(n))
+		}
+	}
+
+	out := s.opts.out
+	fmt.Fprintf(out, "Configuration %s is valid\n", s.configPath)
+	fmt.Fprintf(out, "  providers:    %s\n", orNone(names))
+	fmt.Fprintf(out, "  repositories: %d listed, %d projects discovered\n", len(catalog.Configured()), discoveryProjects(s.cfg))
+	fmt.Fprintf(out, "  notifiers:    %s\n", orNone(notifiers))
+	return exitOK
+}
+
+// discoveryProjects counts the projects whose repositories are discovered at run time
+func discoveryProjects(cfg *config.Config) int {
+	n := len(cfg.Bitbucket.Discovery.Projects)
+	for _, t := range cfg.Bitbucket.Targets {
+		if len(t.Repositories) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func orNone(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+	return strings.Join(items, ", ")
+}
+
+// testNotifiers sends a sample report to every configured notifier, escalation tiers included
+func testNotifiers(ctx context.Context, s *session) int {
+	code := exitOK
+	for _, t := range newTiers(s.cfg) {
+		report := sampleReport(s.cfg)
+		if t.name != "" {
+			report.Tier, report.StaleAfterDays = t.name, t.afterDays
+		}
+		for _, n := range t.notifiers {
+			var err error
+			if s.opts.dryRun {
+				fmt.Fprintf(s.opts.out, "===== %s =====\n", t.label(n))
+				err = n.Preview(s.opts.out, report)
+				fmt.Fprintln(s.opts.out)
+			} else {
+				err = n.Notify(ctx, report)
+			}
+			if err != nil {
+				slog.Error("Test notification failed", "notifier", t.label(n), "error", err)
+				fmt.Fprintf(s.opts.out, "%s: FAILED (%v)\n", t.label(n), err)
+				code = exitError
+				continue
+			}
+			if !s.opts.dryRun {
+				fmt.Fprintf(s.opts.out, "%s: sent\n", t.label(n))
+			}
+		}
+	}
+	return code
+}
+
+// sampleReport builds the report used by test-notifiers
+func sampleReport(cfg *config.Config) *models.Report {
+	idle := time.Now().AddDate(0, 0, -cfg.PRFilter.StaleAfterDays-1)
+	pr := models.PullRequest{
+		ID:          1,
+		Title:       "PR Tracker test notification",
+		Description: "Sample pull request sent by 'pr-tracker test-notifiers'",
+		State:       "OPEN",
+		Open:        true,
+		CreatedDate: idle.UnixMilli(),
+		UpdatedDate: idle.UnixMilli(),
+		Ref:         models.PRRef{Project: "TEST", Repo: "sample-repository", ID: 1},
+	}
+	pr.Author.User.DisplayName = "PR Tracker"
+	pr.Author.User.Username = "pr-tracker"
+	pr.Links.Self = append(pr.Links.Self, struct {
+		Href string `json:"href"`
+	}{Href: "https://example.com/pr-tracker/test"})
+
+	return &models.Report{
+		GeneratedAt:    time.Now(),
+		StaleAfterDays: cfg.PRFilter.StaleAfterDays,
+		BusinessDays:   cfg.PRFilter.BusinessDays.Enabled,
+		PRs: []models.EnrichedPR{{
+			PullRequest:  pr,
+			Reviewers:    1,
+			LastActivity: idle,
+			DaysInactive: cfg.PRFilter.StaleAfterDays + 1,
+			Stale:        true,
+		}},
+	}
+}
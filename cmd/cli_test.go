@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fc-pr-tracker/internal/config"
+)
+
+// testSettings completes the configuration files written by the tests
+const testSettings = `
+pr_filter:
+  stale_after_days: 5
+notification:
+  interval_hours: 24
+`
+
+// testNotifierSettings configures the email and Teams notifiers
+const testNotifierSettings = `
+notifiers:
+  smtp:
+    host: "smtp.example.com"
+    from: "tracker@example.com"
+    to: ["team@example.com"]
+  teams:
+    webhook_url: "https://webhook.invalid"
+`
+
+// writeTestConfig writes a configuration file, logging to the same temporary directory
+func writeTestConfig(t *testing.T, body string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	content := fmt.Sprintf("log:\n  file: %q\n  level: \"error\"\n%s%s", filepath.Join(dir, "test.log"), testSettings, body)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+// testCycleConfig returns a configuration for cycles run against the mock servers
+func testCycleConfig(staleDays int) *config.Config {
+	return &config.Config{
+		PRFilter:    config.PRFilterConfig{StaleAfterDays: staleDays},
+		Concurrency: config.ConcurrencyConfig{Workers: 2},
+	}
+}
+
+func TestResolveConfigPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		flag     string
+		env      string
+		expected string
+	}{
+		{"default", "", "", defaultConfigPath},
+		{"environment", "", "/etc/pr-tracker.yaml", "/etc/pr-tracker.yaml"},
+		{"flag wins over environment", "custom.yaml", "/etc/pr-tracker.yaml", "custom.yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(configEnv, tt.env)
+			if got := resolveConfigPath(tt.flag); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestExecute_Usage(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		expectedErr  string
+	}{
+		{"unknown command", []string{"frobnicate"}, exitUsage, `unknown command "frobnicate"`},
+		{"unknown flag", []string{"check", "--verbose"}, exitUsage, "flag provided but not defined"},
+		{"extra arguments", []string{"validate-config", "extra"}, exitUsage, "unexpected arguments: extra"},
+		{"help", []string{"--help"}, exitOK, "test-notifiers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := execute(context.Background(), tt.args, &stdout, &stderr)
+			if code != tt.expectedCode {
+				t.Errorf("Expected exit code %d, got %d", tt.expectedCode, code)
+			}
+			if !strings.Contains(stderr.String(), tt.expectedErr) {
+				t.Errorf("Expected stderr to contain %q, got:\n%s", tt.expectedErr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestExecute_ValidateConfig(t *testing.T) {
+	path := writeTestConfig(t, `
+bitbucket:
+  domain: "bitbucket.example.com"
+  repositories: ["api", "web"]
+  discovery:
+    projects: ["OPS"]
+`+testNotifierSettings)
+
+	tests := []struct {
+		name string
+		args []string
+		env  string
+	}{
+		{"config flag", []string{"--config", path, "validate-config"}, ""},
+		{"config flag after the command", []string{"validate-config", "--config", path}, ""},
+		{"environment variable", []string{"validate-config"}, path},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(configEnv, tt.env)
+			var stdout, stderr bytes.Buffer
+			if code := execute(context.Background(), tt.args, &stdout, &stderr); code != exitOK {
+				t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitOK, code, stderr.String())
+			}
+			for _, want := range []string{path + " is valid", "providers:    bitbucket", "2 listed, 1 projects discovered", "notifiers:    email, teams"} {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("Expected output to contain %q, got:\n%s", want, stdout.String())
+				}
+			}
+		})
+	}
+}
+
+func TestExecute_InvalidConfig(t *testing.T) {
+	path := writeTestConfig(t, `
+bitbucket:
+  repositories: ["api"]
+`)
+
+	var stdout, stderr bytes.Buffer
+	if code := execute(context.Background(), []string{"validate-config", "--config", path}, &stdout, &stderr); code != exitError {
+		t.Errorf("Expected exit code %d, got %d", exitError, code)
+	}
+	for _, want := range []string{"bitbucket.domain: required", "notifiers: set smtp.to"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("Expected stderr to contain %q, got:\n%s", want, stderr.String())
+		}
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("Expected nothing on stdout, got:\n%s", stdout.String())
+	}
+}
+
+func TestExecute_TestNotifiersDryRun(t *testing.T) {
+	path := writeTestConfig(t, `
+bitbucket_cloud:
+  workspace: "acme"
+  repositories: ["site"]
+`+testNotifierSettings)
+
+	var stdout, stderr bytes.Buffer
+	code := execute(context.Background(), []string{"--config", path, "test-notifiers", "--dry-run"}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitOK, code, stderr.String())
+	}
+	for _, want := range []string{"===== email =====", "To: team@example.com", "PR Tracker test notification", "===== teams =====", "TEST · Repository: sample-repository"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, stdout.String())
+		}
+	}
+}
+
+func TestExecute_ConnectionFailure(t *testing.T) {
+	// Nothing listens on port 1, so the connection test fails right away
+	path := writeTestConfig(t, `
+bitbucket:
+  domain: "127.0.0.1"
+  port: 1
+  repositories: ["api"]
+`+testNotifierSettings)
+
+	var stdout, stderr bytes.Buffer
+	if code := execute(context.Background(), []string{"--config", path, "list"}, &stdout, &stderr); code != exitError {
+		t.Errorf("Expected exit code %d, got %d", exitError, code)
+	}
+	if !strings.Contains(stderr.String(), "connection test failed") {
+		t.Errorf("Expected a connection error on stderr, got:\n%s", stderr.String())
+	}
+}
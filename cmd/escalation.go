@@ -0,0 +1,81 @@
+package main
+
+import (
+	"sort"
+
+	"fc-pr-tracker/internal/config"
+	"fc-pr-tracker/internal/notifier"
+	"fc-pr-tracker/pkg/models"
+)
+
+// tier is a set of notifiers and the inactivity from which stale PRs are sent to them.
+// The first tier of a service is unnamed: it holds the notifiers section and the stale PRs
+// below every escalation tier.
+type tier struct {
+	name      string
+	afterDays int
+	notifiers []notifier.Notifier
+}
+
+// label names notifier n of the tier in logs and previews, e.g. "email (Managers)"
+func (t tier) label(n notifier.Notifier) string {
+	if t.name == "" {
+		return n.Name()
+	}
+	return n.Name() + " (" + t.name + ")"
+}
+
+// newTiers creates the notifiers section and the escalation tiers of cfg, by increasing threshold
+func newTiers(cfg *config.Config) []tier {
+	tiers := []tier{{notifiers: newNotifiers(cfg)}}
+	for _, t := range cfg.Escalation.Tiers {
+		tiers = append(tiers, tier{name: t.Name, afterDays: t.AfterDays, notifiers: newNotifiers(cfg.ForTier(t))})
+	}
+	sort.SliceStable(tiers[1:], func(a, b int) bool { return tiers[1+a].afterDays < tiers[1+b].afterDays })
+	return tiers
+}
+
+// shareDigestLimit makes the digest notifiers of every tier count against
+// notifiers.digests.max_recipients, so the setting caps the digests of a whole cycle
+func shareDigestLimit(cfg *config.Config, tiers []tier) *notifier.DigestLimit {
+	limit := notifier.NewDigestLimit(cfg.Notifiers.Digests.MaxRecipients)
+	for _, t := range tiers {
+		for _, n := range t.notifiers {
+			if d, ok := n.(*notifier.DigestNotifier); ok {
+				d.ShareLimit(limit)
+			}
+		}
+	}
+	return limit
+}
+
+// tierReports splits report by tier: every stale PR goes to the last tier whose threshold
+// its inactivity reached. The reports follow the order of tiers.
+func tierReports(report *models.Report, tiers []tier) []*models.Report {
+	reports := make([]*models.Report, len(tiers))
+	for i, t := range tiers {
+		r := *report
+		r.PRs = nil
+		if i > 0 {
+			r.Tier, r.StaleAfterDays = t.name, t.afterDays
+		}
+		reports[i] = &r
+	}
+	for _, pr := range report.PRs {
+		i := len(tiers) - 1
+		for i > 0 && pr.DaysInactive < tiers[i].afterDays {
+			i--
+		}
+		reports[i].PRs = append(reports[i].PRs, pr)
+	}
+	return reports
+}
+
+// notifierCount counts the notifiers of every tier
+func notifierCount(tiers []tier) int {
+	n := 0
+	for _, t := range tiers {
+		n += len(t.notifiers)
+	}
+	return n
+}
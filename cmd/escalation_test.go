@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"fc-pr-tracker/internal/config"
+	"fc-pr-tracker/internal/provider"
+	"fc-pr-tracker/pkg/models"
+)
+
+// escalationConfig has a reminder tier on Teams and a manager tier by email
+func escalationConfig(staleDays int) *config.Config {
+	cfg := testCycleConfig(staleDays)
+	cfg.Notifiers.SMTP = config.SMTPConfig{Host: "smtp.example.com", From: "tracker@example.com", To: []string{"team@example.com"}}
+	cfg.Escalation.Tiers = []config.EscalationTier{
+		{Name: "Managers", AfterDays: 14, Notifiers: config.NotifiersConfig{SMTP: config.SMTPConfig{To: []string{"managers@example.com"}}}},
+		{Name: "Reminder", AfterDays: 7, Notifiers: config.NotifiersConfig{Teams: config.TeamsConfig{WebhookURL: "https://webhook.invalid"}}},
+	}
+	return cfg
+}
+
+func TestNewTiers(t *testing.T) {
+	tiers := newTiers(escalationConfig(3))
+
+	var got []string
+	for _, tier := range tiers {
+		for _, n := range tier.notifiers {
+			got = append(got, fmt.Sprintf("%s@%d", tier.label(n), tier.afterDays))
+		}
+	}
+	if expected := "[email@0 teams (Reminder)@7 email (Managers)@14]"; fmt.Sprint(got) != expected {
+		t.Errorf("Expected tiers %s by increasing threshold, got %v", expected, got)
+	}
+	if n := notifierCount(tiers); n != 3 {
+		t.Errorf("Expected 3 notifiers, got %d", n)
+	}
+}
+
+func TestNewTiers_OnlyConfiguredChannels(t *testing.T) {
+	cfg := testCycleConfig(3)
+	cfg.Notifiers = config.NotifiersConfig{Slack: config.SlackConfig{WebhookURL: "https://hooks.slack.invalid/x"}}
+
+	tiers := newTiers(cfg)
+	if len(tiers[0].notifiers) != 1 || tiers[0].notifiers[0].Name() != "slack" {
+		t.Errorf("Expected only the Slack notifier without smtp.to, got %v", tiers[0].notifiers)
+	}
+}
+
+func TestTierReports(t *testing.T) {
+	tiers := newTiers(escalationConfig(3))
+	report := &models.Report{StaleAfterDays: 3}
+	for id, days := range []int{3, 6, 7, 13, 14, 40} {
+		pr := models.EnrichedPR{DaysInactive: days, Stale: true}
+		pr.ID = id
+		report.PRs = append(report.PRs, pr)
+	}
+
+	reports := tierReports(report, tiers)
+	expected := []struct {
+		tier      string
+		threshold int
+		ids       string
+	}{
+		{"", 3, "[0 1]"},
+		{"Reminder", 7, "[2 3]"},
+		{"Managers", 14, "[4 5]"},
+	}
+	for i, want := range expected {
+		var ids []int
+		for _, pr := range reports[i].PRs {
+			ids = append(ids, pr.ID)
+		}
+		if reports[i].Tier != want.tier || reports[i].StaleAfterDays != want.threshold || fmt.Sprint(ids) != want.ids {
+			t.Errorf("Tier %d: expected %q after %d days with PRs %s, got %q after %d days with %v",
+				i, want.tier, want.threshold, want.ids, reports[i].Tier, reports[i].StaleAfterDays, ids)
+		}
+	}
+}
+
+func TestRunOnce_Escalation(t *testing.T) {
+	// The PR served is 30 days old, so it reaches the Managers tier only
+	server, client := createStaleBitbucketServer(200)
+	defer server.Close()
+	catalog := provider.NewStaticCatalog([]provider.Repository{{Name: "repo1", Provider: client}})
+
+	var out bytes.Buffer
+	code := runOnce(context.Background(), newService(escalationConfig(3), catalog), options{dryRun: true, out: &out})
+	if code != exitStale {
+		t.Errorf("Expected exit code %d, got %d", exitStale, code)
+	}
+	for _, want := range []string{"===== email (Managers) =====", "To: managers@example.com", "Subject: [Managers] Stale Pull Requests Alert"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected dry-run output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+	for _, unwanted := range []string{"===== email =====", "teams (Reminder)"} {
+		if strings.Contains(out.String(), unwanted) {
+			t.Errorf("Expected no %q notification, got:\n%s", unwanted, out.String())
+		}
+	}
+}
+
+func TestNewTiers_Digests(t *testing.T) {
+	cfg := escalationConfig(3)
+	cfg.Notifiers.SMTP.To = nil
+	cfg.Notifiers.Digests = config.DigestsConfig{Reviewers: true}
+	cfg.Escalation.Tiers[1].Notifiers.Digests = config.DigestsConfig{Authors: true}
+
+	var got []string
+	for _, tier := range newTiers(cfg) {
+		for _, n := range tier.notifiers {
+			got = append(got, tier.label(n))
+		}
+	}
+	if expected := "[digest digest (Reminder) teams (Reminder) email (Managers)]"; fmt.Sprint(got) != expected {
+		t.Errorf("Expected notifiers %s, got %v", expected, got)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"fc-pr-tracker/internal/calendar"
+	"fc-pr-tracker/internal/tracker"
+	"fc-pr-tracker/pkg/models"
+)
+
+// List output formats
+const (
+	formatTable    = "table"
+	formatJSON     = "json"
+	formatCSV      = "csv"
+	formatMarkdown = "markdown"
+)
+
+// listFormats maps every accepted --format value, aliases included, to its writer
+var listFormats = map[string]func(w io.Writer, rows []listRow) error{
+	formatTable:    writeTable,
+	formatJSON:     writeJSON,
+	formatCSV:      writeCSV,
+	formatMarkdown: writeMarkdown,
+	"md":           writeMarkdown,
+}
+
+// listFlags registers the flags of the list command
+func listFlags(fs *flag.FlagSet, opts *options) {
+	fs.BoolVar(&opts.all, "all", false, "include open PRs that are not stale")
+	opts.format = formatTable
+	fs.Func("format", "output format: table, json, csv or markdown (default table)", func(v string) error {
+		v = strings.ToLower(v)
+		if _, ok := listFormats[v]; !ok {
+			return fmt.Errorf("unsupported format %q", v)
+		}
+		opts.format = v
+		return nil
+	})
+}
+
+// listRow is one PR as printed by the list command
+type listRow struct {
+	Server    string    `json:"server,omitempty"`
+	Project   string    `json:"project,omitempty"`
+	Repo      string    `json:"repo"`
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	Author    string    `json:"author"`
+	Created   time.Time `json:"created"`
+	AgeDays   int       `json:"age_days"`
+	IdleDays  int       `json:"idle_days"`
+	Approvals int       `json:"approvals"`
+	Reviewers int       `json:"reviewers"`
+	Stale     bool      `json:"stale"`
+	Link      string    `json:"link"`
+}
+
+// repoName is the repository as shown in the table, CSV and Markdown outputs
+func (r listRow) repoName() string {
+	if r.Project == "" {
+		return r.Repo
+	}
+	return r.Project + "/" + r.Repo
+}
+
+// list runs a cycle without notifying and prints the stale PRs, or every open PR with --all
+func list(ctx context.Context, s *session) int {
+	cycleCtx, cancel := cycleContext(ctx, s.cfg)
+	result := tracker.Collect(cycleCtx, s.cfg, s.catalog.Repositories(cycleCtx))
+	cycleErr := cycleCtx.Err()
+	cancel()
+	if ctx.Err() != nil {
+		return exitError
+	}
+	if cycleErr != nil {
+		slog.Warn("Cycle deadline exceeded, listing the PRs collected so far", "timeout_minutes", s.cfg.Notification.CycleTimeoutMinutes)
+	}
+
+	prs := result.Report.PRs
+	if s.opts.all {
+		prs = result.Open
+	}
+	format := s.opts.format
+	if format == "" {
+		format = formatTable
+	}
+	// Ages are counted like the inactivity; Collect already logged a calendar that fails to load
+	cal, _ := s.cfg.NewCalendar()
+	if err := listFormats[format](s.opts.out, listRows(prs, result.Report.GeneratedAt, cal)); err != nil {
+		return exitError
+	}
+	if result.Failures > 0 || cycleErr != nil {
+		return exitPartial
+	}
+	return exitOK
+}
+
+// listRows flattens the PRs, computing their age at now in the days counted by cal
+func listRows(prs []models.EnrichedPR, now time.Time, cal *calendar.Calendar) []listRow {
+	rows := make([]listRow, 0, len(prs))
+	for _, pr := range prs {
+		row := listRow{
+			Server:    pr.Ref.Server,
+			Project:   pr.Ref.Project,
+			Repo:      pr.Ref.Repo,
+			ID:        pr.ID,
+			Title:     pr.Title,
+			Author:    pr.Author.User.DisplayName,
+			IdleDays:  pr.DaysInactive,
+			Approvals: pr.Approvals,
+			Reviewers: pr.Reviewers,
+			Stale:     pr.Stale,
+			Link:      pr.Link(),
+		}
+		if row.Author == "" {
+			row.Author = pr.Author.User.Username
+		}
+		if pr.CreatedDate > 0 {
+			row.Created = time.UnixMilli(pr.CreatedDate).UTC()
+			row.AgeDays = cal.Days(row.Created, now)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// listHeader names the columns of the table, CSV and Markdown outputs
+var listHeader = []string{"REPO", "PR", "TITLE", "AUTHOR", "AGE", "IDLE", "APPROVALS", "STALE", "LINK"}
+
+// fields formats the row in the order of listHeader
+func (r listRow) fields() []string {
+	stale := "no"
+	if r.Stale {
+		stale = "yes"
+	}
+	return []string{
+		r.repoName(),
+		strconv.Itoa(r.ID),
+		r.Title,
+		r.Author,
+		fmt.Sprintf("%dd", r.AgeDays),
+		fmt.Sprintf("%dd", r.IdleDays),
+		fmt.Sprintf("%d/%d", r.Approvals, r.Reviewers),
+		stale,
+		r.Link,
+	}
+}
+
+// writeTable prints one line per PR, aligned in columns
+func writeTable(w io.Writer, rows []listRow) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(listHeader, "\t"))
+	for _, row := range rows {
+		fields := row.fields()
+		for i, f := range fields {
+			// Tabs and newlines in titles would break the alignment
+			fields[i] = strings.Join(strings.Fields(f), " ")
+		}
+		fmt.Fprintln(tw, strings.Join(fields, "\t"))
+	}
+	return tw.Flush()
+}
+
+// writeJSON prints the PRs as an indented JSON array
+func writeJSON(w io.Writer, rows []listRow) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rows)
+}
+
+// writeCSV prints the PRs as CSV with a header record
+func writeCSV(w io.Writer, rows []listRow) error {
+	cw := csv.NewWriter(w)
+	header := make([]string, len(listHeader))
+	for i, h := range listHeader {
+		header[i] = strings.ToLower(h)
+	}
+	cw.Write(header)
+	for _, row := range rows {
+		cw.Write(row.fields())
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// markdownEscaper keeps cell contents from breaking the Markdown table
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ")
+
+// writeMarkdown prints the PRs as a Markdown table, linking each PR ID
+func writeMarkdown(w io.Writer, rows []listRow) error {
+	header := []string{"Repo", "PR", "Title", "Author", "Age", "Idle", "Approvals", "Stale"}
+	if _, err := fmt.Fprintf(w, "| %s |\n|%s\n", strings.Join(header, " | "), strings.Repeat("---|", len(header))); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		fields := row.fields()[:len(header)]
+		for i, f := range fields {
+			fields[i] = markdownEscaper.Replace(f)
+		}
+		if row.Link != "" {
+			fields[1] = fmt.Sprintf("[#%d](%s)", row.ID, row.Link)
+		}
+		if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(fields, " | ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"fc-pr-tracker/internal/provider"
+	"fc-pr-tracker/pkg/models"
+)
+
+func TestList(t *testing.T) {
+	server, client := createStaleBitbucketServer(200)
+	defer server.Close()
+	catalog := provider.NewStaticCatalog([]provider.Repository{{Name: "repo1", Project: "WEB", Provider: client}})
+
+	tests := []struct {
+		name      string
+		staleDays int
+		all       bool
+		expectPR  bool
+	}{
+		{"stale PR", 7, false, true},
+		{"nothing stale", 60, false, false},
+		{"all open PRs", 60, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			s := &session{cfg: testCycleConfig(tt.staleDays), catalog: catalog, opts: options{all: tt.all, format: formatJSON, out: &out}}
+			if code := list(context.Background(), s); code != exitOK {
+				t.Fatalf("Expected exit code %d, got %d", exitOK, code)
+			}
+
+			var rows []listRow
+			if err := json.Unmarshal(out.Bytes(), &rows); err != nil {
+				t.Fatalf("Expected a JSON array, got %v:\n%s", err, out.String())
+			}
+			if !tt.expectPR {
+				if len(rows) != 0 {
+					t.Errorf("Expected no PRs, got %+v", rows)
+				}
+				return
+			}
+			if len(rows) != 1 {
+				t.Fatalf("Expected one PR, got %+v", rows)
+			}
+			row := rows[0]
+			if row.Project != "WEB" || row.Repo != "repo1" || row.ID != 42 || row.Title != "Forgotten PR" {
+				t.Errorf("Unexpected PR identity: %+v", row)
+			}
+			if row.IdleDays != 30 || row.AgeDays != 30 || row.Approvals != 0 || row.Reviewers != 1 {
+				t.Errorf("Unexpected PR activity: %+v", row)
+			}
+			if row.Stale != (tt.staleDays <= 30) {
+				t.Errorf("Expected stale=%v, got %v", tt.staleDays <= 30, row.Stale)
+			}
+			if row.Link != "https://bitbucket.example.com/pr/42" {
+				t.Errorf("Unexpected link %q", row.Link)
+			}
+		})
+	}
+}
+
+// testListRows returns two PRs, one of them with characters that need escaping
+func testListRows() []listRow {
+	now := time.Date(2026, 3, 20, 12, 0, 0, 0, time.UTC)
+	newPR := func(project, repo string, id int, title, author string, created time.Time, idle, approvals, reviewers int) models.EnrichedPR {
+		pr := models.EnrichedPR{DaysInactive: idle, Approvals: approvals, Reviewers: reviewers, Stale: idle >= 7}
+		pr.ID = id
+		pr.Title = title
+		pr.Author.User.DisplayName = author
+		pr.CreatedDate = created.UnixMilli()
+		pr.Ref = models.PRRef{Server: "bitbucket.example.com", Project: project, Repo: repo, ID: id}
+		pr.Links.Self = append(pr.Links.Self, struct {
+			Href string `json:"href"`
+		}{Href: "https://bitbucket.example.com/" + repo + "/" + title[:3]})
+		return pr
+	}
+	return listRows([]models.EnrichedPR{
+		newPR("WEB", "site", 7, "Fix header", "Ana", now.AddDate(0, 0, -12), 9, 1, 2),
+		newPR("CORE", "api", 12, "Add a|b, \"quoted\"", "Bruno", now.AddDate(0, 0, -3), 2, 0, 0),
+	}, now, nil)
+}
+
+func TestListRows(t *testing.T) {
+	rows := testListRows()
+	if rows[0].AgeDays != 12 || rows[1].AgeDays != 3 {
+		t.Errorf("Expected ages 12 and 3, got %d and %d", rows[0].AgeDays, rows[1].AgeDays)
+	}
+	if got := rows[0].repoName(); got != "WEB/site" {
+		t.Errorf("Expected repo WEB/site, got %q", got)
+	}
+	if got := (listRow{Repo: "site"}).repoName(); got != "site" {
+		t.Errorf("Expected repo without project to be site, got %q", got)
+	}
+}
+
+func TestListFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		check  func(t *testing.T, out string)
+	}{
+		{formatTable, func(t *testing.T, out string) {
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			if len(lines) != 3 {
+				t.Fatalf("Expected header and two PRs, got:\n%s", out)
+			}
+			if strings.Index(lines[0], "TITLE") != strings.Index(lines[1], "Fix header") {
+				t.Errorf("Expected the TITLE column to be aligned, got:\n%s", out)
+			}
+			for _, want := range []string{"WEB/site", "7", "Ana", "12d", "9d", "1/2", "yes", "https://bitbucket.example.com/site/Fix"} {
+				if !strings.Contains(lines[1], want) {
+					t.Errorf("Expected %q in %q", want, lines[1])
+				}
+			}
+		}},
+		{formatJSON, func(t *testing.T, out string) {
+			var rows []map[string]any
+			if err := json.Unmarshal([]byte(out), &rows); err != nil {
+				t.Fatalf("Invalid JSON: %v", err)
+			}
+			if len(rows) != 2 || rows[1]["title"] != `Add a|b, "quoted"` || rows[0]["age_days"] != 12.0 || rows[0]["server"] != "bitbucket.example.com" {
+				t.Errorf("Unexpected JSON rows: %v", rows)
+			}
+		}},
+		{formatCSV, func(t *testing.T, out string) {
+			records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+			if err != nil {
+				t.Fatalf("Invalid CSV: %v", err)
+			}
+			if len(records) != 3 || records[0][0] != "repo" || records[2][2] != `Add a|b, "quoted"` || records[2][6] != "0/0" {
+				t.Errorf("Unexpected CSV records: %q", records)
+			}
+		}},
+		{formatMarkdown, func(t *testing.T, out string) {
+			for _, want := range []string{
+				"| Repo | PR | Title | Author | Age | Idle | Approvals | Stale |\n|---|---|---|---|---|---|---|---|\n",
+				"| WEB/site | [#7](https://bitbucket.example.com/site/Fix) | Fix header | Ana | 12d | 9d | 1/2 | yes |\n",
+				`| CORE/api | [#12](https://bitbucket.example.com/api/Add) | Add a\|b, "quoted" | Bruno |`,
+			} {
+				if !strings.Contains(out, want) {
+					t.Errorf("Expected Markdown to contain %q, got:\n%s", want, out)
+				}
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := listFormats[tt.format](&out, testListRows()); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			tt.check(t, out.String())
+		})
+	}
+}
+
+func TestListFlags_Format(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+		wantErr  bool
+	}{
+		{nil, formatTable, false},
+		{[]string{"--format", "JSON"}, formatJSON, false},
+		{[]string{"--format=md"}, "md", false},
+		{[]string{"--format", "xml"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			var opts options
+			fs := flag.NewFlagSet("list", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			listFlags(fs, &opts)
+			err := fs.Parse(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && opts.format != tt.expected {
+				t.Errorf("Expected format %q, got %q", tt.expected, opts.format)
+			}
+		})
+	}
+}
@@ -2,50 +2,46 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
+	_ "time/tzdata" // IANA time zones for notification.timezone, even on hosts without them
 
-	"fc-pr-tracker/internal/bitbucket"
 	"fc-pr-tracker/internal/config"
-	"fc-pr-tracker/internal/logger"
 	"fc-pr-tracker/internal/notifier"
+	"fc-pr-tracker/internal/provider"
+	"fc-pr-tracker/internal/schedule"
+	"fc-pr-tracker/internal/tracker"
 	"fc-pr-tracker/pkg/models"
 )
 
-func main() {
-	// Load configuration
-	cfg := config.Load("config.yaml")
-
-	// Initialize logger
-	logger.Init(cfg)
-
-	slog.Info("PR monitoring service started",
-		"log_file", cfg.Log.File,
-		"log_level", cfg.Log.Level)
+// Exit codes of the check command
+const (
+	exitOK      = 0  // the cycle completed and no PR is stale
+	exitError   = 1  // configuration, connection or state failure
+	exitStale   = 2  // the cycle completed and stale PRs were reported
+	exitPartial = 3  // the cycle completed, but API calls or notifiers failed
+	exitUsage   = 64 // invalid command line
+)
 
-	// Test Bitbucket connection
-	bitbucketClient := bitbucket.NewClient(cfg)
-	err := bitbucketClient.TestConnection()
-	if err != nil {
-		slog.Error("Bitbucket connection test failed", "error", err)
-		os.Exit(1)
-	}
+// stateFile records when the last notification was sent
+const stateFile = "tmp/last_notification.txt"
 
-	slog.Info("Bitbucket connection test succeeded")
-	slog.Info("Loaded configuration",
-		"workspace", cfg.Bitbucket.Workspace,
-		"user", cfg.Bitbucket.User,
-		"repositories", cfg.Bitbucket.Repositories,
-		"stale_after_days", cfg.PRFilter.StaleAfterDays,
-		"email_recipients", cfg.Notifiers.SMTP.To,
-		"notification_interval_hours", cfg.Notification.IntervalHours,
-	)
+// options holds the command line switches
+type options struct {
+	dryRun bool
+	all    bool      // list: include open PRs that are not stale
+	format string    // list: output format
+	out    io.Writer // where command output and dry-run previews are written
+}
 
+func main() {
 	// Setup context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 
 	// Handle graceful shutdown
 	sigs := make(chan os.Signal, 1)
@@ -56,136 +52,293 @@ func main() {
 		cancel()
 	}()
 
+	code := execute(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	cancel()
+	os.Exit(code)
+}
+
+// serve runs the monitoring loop until the process is interrupted
+func serve(ctx context.Context, s *session) int {
+	slog.Info("Loaded configuration",
+		"workspace", s.cfg.Bitbucket.Workspace,
+		"user", s.cfg.Bitbucket.User,
+		"repositories", s.cfg.Bitbucket.Repositories,
+		"discovery_projects", s.cfg.Bitbucket.Discovery.Projects,
+		"cloud_workspace", s.cfg.BitbucketCloud.Workspace,
+		"cloud_repositories", s.cfg.BitbucketCloud.Repositories,
+		"github_repositories", s.cfg.GitHub.Repositories,
+		"gitlab_repositories", s.cfg.GitLab.Repositories,
+		"stale_after_days", s.cfg.PRFilter.StaleAfterDays,
+		"pr_filter_overrides", len(s.cfg.PRFilter.Overrides),
+		"email_recipients", s.cfg.Notifiers.SMTP.To,
+		"reviewer_digests", s.cfg.Notifiers.Digests.Reviewers,
+		"author_digests", s.cfg.Notifiers.Digests.Authors,
+		"slack", s.cfg.Notifiers.Slack.Enabled(),
+		"escalation_tiers", len(s.cfg.Escalation.Tiers),
+		"notification_interval_hours", s.cfg.Notification.IntervalHours,
+		"notification_schedule", s.cfg.Notification.Schedule,
+		"timezone", s.cfg.Notification.Timezone,
+		"quiet_hours", s.cfg.Notification.QuietHours,
+		"skip_weekends", s.cfg.Notification.SkipWeekends,
+		"workers", s.cfg.Concurrency.Workers,
+		"per_host", s.cfg.Concurrency.PerHost,
+		"cycle_timeout_minutes", s.cfg.Notification.CycleTimeoutMinutes,
+	)
+
+	// Reload the configuration when its file changes or on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	watcher, err := newConfigWatcher(s.configPath, reloadPollInterval, s.reload)
+	if err != nil {
+		slog.Error("Cannot watch the configuration file", "path", s.configPath, "error", err)
+		return exitError
+	}
+
 	// Run the service
-	err = run(ctx, cfg)
+	err = run(ctx, newService(s.cfg, s.catalog), watcher.watch(ctx, hup), s.opts)
 	if err != nil {
 		slog.Error("Application error", "error", err)
-		os.Exit(1)
+		return exitError
 	}
 
 	slog.Info("Shutdown complete.")
+	return exitOK
 }
 
-// run contains the main monitoring logic
-func run(ctx context.Context, cfg *config.Config) error {
-	// Initialize notifiers
-	notifiers := []notifier.Notifier{
-		notifier.NewEmailNotifier(cfg),
-	}
+// check performs a single cycle and reports its outcome through the exit code
+func check(ctx context.Context, s *session) int {
+	code := runOnce(ctx, newService(s.cfg, s.catalog), s.opts)
+	slog.Info("Single cycle complete.", "exit_code", code)
+	return code
+}
 
+// newNotifiers creates the notifiers of the channels cfg configures
+func newNotifiers(cfg *config.Config) []notifier.Notifier {
+	var notifiers []notifier.Notifier
+	if len(cfg.Notifiers.SMTP.To) > 0 {
+		notifiers = append(notifiers, notifier.NewEmailNotifier(cfg))
+	}
+	if cfg.Notifiers.Digests.Enabled() {
+		notifiers = append(notifiers, notifier.NewDigestNotifier(cfg))
+	}
 	if cfg.Notifiers.Teams.WebhookURL != "" {
 		notifiers = append(notifiers, notifier.NewTeamsNotifier(cfg))
 	}
+	if cfg.Notifiers.Slack.Enabled() {
+		notifiers = append(notifiers, notifier.NewSlackNotifier(cfg))
+	}
+	return notifiers
+}
 
-	// Initialize state store
-	stateStore := &models.FileNotificationStateStore{Path: "tmp/last_notification.txt"}
-	checkFreq := time.Duration(cfg.Notification.IntervalHours) * time.Hour
+// service is the configuration a cycle runs with, along with the providers, notifiers and
+// schedule it describes. A reload replaces it as a whole, between cycles.
+type service struct {
+	cfg      *config.Config
+	catalog  *provider.Catalog
+	tiers    []tier // the notifiers section first, then the escalation tiers
+	digests  *notifier.DigestLimit
+	schedule *schedule.Schedule
+}
+
+// newService creates the notifiers, escalation tiers and schedule of cfg; catalog serves the repositories to track
+func newService(cfg *config.Config, catalog *provider.Catalog) *service {
+	sched, err := cfg.Notification.NewSchedule()
+	if err != nil {
+		// Load validates the schedule, so only hand-built configurations get here
+		slog.Error("Invalid notification schedule, using interval_hours", "error", err)
+		sched = &schedule.Schedule{Trigger: schedule.Every(interval(cfg))}
+	}
+	tiers := newTiers(cfg)
+	return &service{cfg: cfg, catalog: catalog, tiers: tiers, digests: shareDigestLimit(cfg, tiers), schedule: sched}
+}
 
-	// Initialize Bitbucket client
-	bitbucketClient := bitbucket.NewClient(cfg)
+// interval is the time between two checks when no cron expression is set
+func interval(cfg *config.Config) time.Duration {
+	return time.Duration(cfg.Notification.IntervalHours) * time.Hour
+}
+
+// firstCheck returns when the first cycle after startup is due. Without a cron expression,
+// a notification sent less than interval_hours ago, before a restart, delays it.
+func (s *service) firstCheck(now, lastNotified time.Time) time.Time {
+	if s.cfg.Notification.Schedule != "" {
+		return s.schedule.Next(now)
+	}
+	due := now
+	if next := lastNotified.Add(interval(s.cfg)); !lastNotified.IsZero() && next.After(now) {
+		slog.Info("No notification sent (interval not reached)", "last_notified", lastNotified)
+		due = next
+	}
+	return s.schedule.Defer(due)
+}
 
+// run contains the main monitoring logic. Cycles run on the configured schedule, outside
+// quiet hours and skipped days. Every service received from reloads replaces the current
+// one before the next cycle, which is then scheduled again from the last one.
+func run(ctx context.Context, svc *service, reloads <-chan *service, opts options) error {
+	// Initialize state store
+	stateStore := &models.FileNotificationStateStore{Path: stateFile}
+	lastNotified, err := stateStore.GetLastNotificationTime()
+	if err != nil {
+		return err
+	}
+
+	next := svc.firstCheck(time.Now(), lastNotified)
+	var lastCycle time.Time
 	for {
+		var due <-chan time.Time
+		var timer *time.Timer
+		if next.IsZero() {
+			slog.Warn("The notification schedule has no upcoming check; waiting for a configuration reload")
+		} else {
+			slog.Info("Sleeping until next check...", "next_check", next.Format(time.RFC3339), "in", time.Until(next).Round(time.Second))
+			timer = time.NewTimer(time.Until(next))
+			due = timer.C
+		}
+
 		select {
 		case <-ctx.Done():
+			stopTimer(timer)
 			return nil
-		default:
-			lastNotified, err := stateStore.GetLastNotificationTime()
-			if err != nil {
-				return err
+		case reloaded := <-reloads:
+			stopTimer(timer)
+			svc = reloaded
+			if lastCycle.IsZero() {
+				next = svc.firstCheck(time.Now(), lastNotified)
+			} else {
+				next = svc.schedule.Next(lastCycle)
 			}
+			slog.Info("Configuration reloaded",
+				"repositories", len(svc.catalog.Configured()),
+				"providers", len(svc.catalog.Providers),
+				"notifiers", notifierCount(svc.tiers),
+				"next_check", next.Format(time.RFC3339))
+			continue
+		case <-due:
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
 
-			interval := time.Duration(cfg.Notification.IntervalHours) * time.Hour
-			shouldNotify := lastNotified.IsZero() || time.Since(lastNotified) >= interval
-
-			if !shouldNotify {
-				slog.Info("No notification sent (interval not reached)", "last_notified", lastNotified)
-				slog.Info("Sleeping until next check...", "hours", cfg.Notification.IntervalHours)
-				select {
-				case <-ctx.Done():
-					return nil
-				case <-time.After(checkFreq):
-					// continue loop
-				}
-				continue
+		lastCycle = time.Now()
+		outcome := runCycle(ctx, svc, opts)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if outcome.stale > 0 && !opts.dryRun {
+			lastNotified = time.Now()
+			err = stateStore.SetLastNotificationTime(lastNotified)
+			if err != nil {
+				slog.Error("Error updating last notification time", "error", err)
 			}
+		}
+		next = svc.schedule.Next(lastCycle)
+	}
+}
 
-			var allPRsToNotify []models.PullRequest
-			repoPRsToNotify := make(map[string][]models.PullRequest)
-			prParticipants := make(map[int][]models.Participant)
-
-			for _, repo := range cfg.Bitbucket.Repositories {
-				slog.Info("Fetching open PRs for repository", "repo", repo)
-				prs, err := bitbucketClient.ListOpenPRs(repo)
-				if err != nil {
-					slog.Error("Error fetching PRs for repository", "repo", repo, "error", err)
-					continue
-				}
-				slog.Info("Total open PRs", "repo", repo, "total", len(prs))
-
-				filtered := bitbucket.FilterPRs(prs, cfg.PRFilter.IgnoreKeywords)
-				slog.Info("PRs after keyword filter", "repo", repo, "filtered_total", len(filtered))
-
-				for _, pr := range filtered {
-					participants, err := bitbucketClient.GetParticipants(repo, pr.ID)
-					if err != nil {
-						slog.Error("Error fetching PR participants", "repo", repo, "pr_id", pr.ID, "error", err)
-						continue
-					}
-					prParticipants[pr.ID] = participants
-
-					if bitbucket.IsPRApproved(participants) {
-						continue
-					}
-
-					comments, err := bitbucketClient.GetComments(repo, pr.ID)
-					if err != nil {
-						slog.Error("Error fetching PR comments", "repo", repo, "pr_id", pr.ID, "error", err)
-						continue
-					}
-
-					lastActivity := bitbucket.GetLastActivity(pr, comments)
-					if lastActivity == "" {
-						slog.Warn("No last activity date found for PR", "repo", repo, "pr_id", pr.ID, "title", pr.Title)
-						continue
-					}
-
-					lastTime, err := time.Parse(time.RFC3339, lastActivity)
-					if err != nil {
-						slog.Warn("Error parsing PR last activity date", "repo", repo, "pr_id", pr.ID, "title", pr.Title, "date", lastActivity, "error", err)
-						continue
-					}
-
-					daysWithoutActivity := int(time.Since(lastTime).Hours() / 24)
-					if daysWithoutActivity >= cfg.PRFilter.StaleAfterDays {
-						allPRsToNotify = append(allPRsToNotify, pr)
-						repoPRsToNotify[repo] = append(repoPRsToNotify[repo], pr)
-					}
-				}
-			}
+func stopTimer(t *time.Timer) {
+	if t != nil {
+		t.Stop()
+	}
+}
+
+// runOnce performs a single cycle, regardless of the notification interval, and returns the exit code
+func runOnce(ctx context.Context, svc *service, opts options) int {
+	outcome := runCycle(ctx, svc, opts)
+	if ctx.Err() != nil {
+		return exitError
+	}
+	if outcome.stale > 0 && !opts.dryRun {
+		stateStore := &models.FileNotificationStateStore{Path: stateFile}
+		if err := stateStore.SetLastNotificationTime(time.Now()); err != nil {
+			slog.Error("Error updating last notification time", "error", err)
+		}
+	}
+	return outcome.exitCode()
+}
 
-			if len(allPRsToNotify) > 0 {
-				slog.Info("Sending summary notification email", "prs_to_notify", len(allPRsToNotify))
-				for _, notifier := range notifiers {
-					err := notifier.Notify(allPRsToNotify, repoPRsToNotify, prParticipants, cfg.PRFilter.StaleAfterDays)
-					if err != nil {
-						slog.Error("Error notifying", "error", err)
-					}
-				}
-				err = stateStore.SetLastNotificationTime(time.Now())
-				if err != nil {
-					slog.Error("Error updating last notification time", "error", err)
-				}
+// cycleOutcome summarizes one monitoring cycle
+type cycleOutcome struct {
+	stale        int  // stale PRs reported
+	failures     int  // repositories and PRs skipped because an API call failed
+	notifyErrors int  // notifiers that failed to deliver
+	deadline     bool // the cycle deadline was exceeded
+}
+
+// exitCode maps the outcome to the run-once exit status; failures take precedence over stale PRs
+func (o cycleOutcome) exitCode() int {
+	switch {
+	case o.failures > 0 || o.notifyErrors > 0 || o.deadline:
+		return exitPartial
+	case o.stale > 0:
+		return exitStale
+	default:
+		return exitOK
+	}
+}
+
+// runCycle collects the stale PRs and hands the PRs of every escalation tier to its notifiers,
+// or to their previews in dry-run mode
+func runCycle(ctx context.Context, svc *service, opts options) cycleOutcome {
+	cfg := svc.cfg
+	cycleCtx, cancelCycle := cycleContext(ctx, cfg)
+	// Discovered repositories are refreshed on every cycle
+	repos := svc.catalog.Repositories(cycleCtx)
+	result := tracker.Collect(cycleCtx, cfg, repos)
+	cycleErr := cycleCtx.Err()
+	cancelCycle()
+
+	outcome := cycleOutcome{stale: len(result.Report.PRs), failures: result.Failures}
+	if ctx.Err() != nil {
+		return outcome
+	}
+	if cycleErr != nil {
+		outcome.deadline = true
+		slog.Warn("Cycle deadline exceeded, notifying the PRs collected so far", "timeout_minutes", cfg.Notification.CycleTimeoutMinutes)
+	}
+
+	report := &result.Report
+	if len(report.PRs) == 0 {
+		slog.Info("No PRs to notify in this cycle.")
+		return outcome
+	}
+
+	if opts.dryRun {
+		slog.Info("Dry run: writing notifications to stdout", "prs_to_notify", len(report.PRs))
+	} else {
+		slog.Info("Sending summary notification email", "prs_to_notify", len(report.PRs))
+	}
+	svc.digests.Reset()
+	for i, r := range tierReports(report, svc.tiers) {
+		t := svc.tiers[i]
+		if len(r.PRs) == 0 {
+			continue
+		}
+		if t.name != "" {
+			slog.Info("Escalating PRs", "tier", t.name, "after_days", t.afterDays, "prs", len(r.PRs))
+		}
+		for _, n := range t.notifiers {
+			var err error
+			if opts.dryRun {
+				fmt.Fprintf(opts.out, "===== %s =====\n", t.label(n))
+				err = n.Preview(opts.out, r)
+				fmt.Fprintln(opts.out)
 			} else {
-				slog.Info("No PRs to notify in this cycle.")
+				err = n.Notify(ctx, r)
 			}
-
-			slog.Info("Sleeping until next check...", "hours", cfg.Notification.IntervalHours)
-			select {
-			case <-ctx.Done():
-				return nil
-			case <-time.After(checkFreq):
-				// continue loop
+			if err != nil {
+				slog.Error("Error notifying", "notifier", t.label(n), "error", err)
+				outcome.notifyErrors++
 			}
 		}
 	}
+	return outcome
+}
+
+// cycleContext derives the context of one monitoring cycle, bounded by the configured deadline
+func cycleContext(ctx context.Context, cfg *config.Config) (context.Context, context.CancelFunc) {
+	if cfg.Notification.CycleTimeoutMinutes <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(cfg.Notification.CycleTimeoutMinutes)*time.Minute)
 }
@@ -1,141 +1,28 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fc-pr-tracker/internal/bitbucket"
 	"fc-pr-tracker/internal/config"
-	"fc-pr-tracker/internal/notifier"
+	"fc-pr-tracker/internal/provider"
 	"fc-pr-tracker/pkg/models"
-	"log/slog"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
 
-// runWithMock allows injecting a mock Bitbucket client for testing
+// runWithMock runs the monitoring loop with every configured repository served by the mock client
 func runWithMock(ctx context.Context, cfg *config.Config, mockClient *bitbucket.Client) error {
-	// Initialize notifiers
-	notifiers := []notifier.Notifier{
-		notifier.NewEmailNotifier(cfg),
-	}
-
-	if cfg.Notifiers.Teams.WebhookURL != "" {
-		notifiers = append(notifiers, notifier.NewTeamsNotifier(cfg))
-	}
-
-	// Initialize state store
-	stateStore := &models.FileNotificationStateStore{Path: "tmp/last_notification.txt"}
-	checkFreq := time.Duration(cfg.Notification.IntervalHours) * time.Hour
-
-	// Use the mock client instead of creating a new one
-	bitbucketClient := mockClient
-
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		default:
-			lastNotified, err := stateStore.GetLastNotificationTime()
-			if err != nil {
-				return err
-			}
-
-			interval := time.Duration(cfg.Notification.IntervalHours) * time.Hour
-			shouldNotify := lastNotified.IsZero() || time.Since(lastNotified) >= interval
-
-			if !shouldNotify {
-				slog.Info("No notification sent (interval not reached)", "last_notified", lastNotified)
-				slog.Info("Sleeping until next check...", "hours", cfg.Notification.IntervalHours)
-				select {
-				case <-ctx.Done():
-					return nil
-				case <-time.After(checkFreq):
-					// continue loop
-				}
-				continue
-			}
-
-			var allPRsToNotify []models.PullRequest
-			repoPRsToNotify := make(map[string][]models.PullRequest)
-			prParticipants := make(map[int][]models.Participant)
-
-			for _, repo := range cfg.Bitbucket.Repositories {
-				slog.Info("Fetching open PRs for repository", "repo", repo)
-				prs, err := bitbucketClient.ListOpenPRs(repo)
-				if err != nil {
-					slog.Error("Error fetching PRs for repository", "repo", repo, "error", err)
-					continue
-				}
-				slog.Info("Total open PRs", "repo", repo, "total", len(prs))
-
-				filtered := bitbucket.FilterPRs(prs, cfg.PRFilter.IgnoreKeywords)
-				slog.Info("PRs after keyword filter", "repo", repo, "filtered_total", len(filtered))
-
-				for _, pr := range filtered {
-					participants, err := bitbucketClient.GetParticipants(repo, pr.ID)
-					if err != nil {
-						slog.Error("Error fetching PR participants", "repo", repo, "pr_id", pr.ID, "error", err)
-						continue
-					}
-					prParticipants[pr.ID] = participants
-
-					if bitbucket.IsPRApproved(participants) {
-						continue
-					}
-
-					comments, err := bitbucketClient.GetComments(repo, pr.ID)
-					if err != nil {
-						slog.Error("Error fetching PR comments", "repo", repo, "pr_id", pr.ID, "error", err)
-						continue
-					}
-
-					lastActivity := bitbucket.GetLastActivity(pr, comments)
-					if lastActivity == "" {
-						slog.Warn("No last activity date found for PR", "repo", repo, "pr_id", pr.ID, "title", pr.Title)
-						continue
-					}
-
-					lastTime, err := time.Parse(time.RFC3339, lastActivity)
-					if err != nil {
-						slog.Warn("Error parsing PR last activity date", "repo", repo, "pr_id", pr.ID, "title", pr.Title, "date", lastActivity, "error", err)
-						continue
-					}
-
-					daysWithoutActivity := int(time.Since(lastTime).Hours() / 24)
-					if daysWithoutActivity >= cfg.PRFilter.StaleAfterDays {
-						allPRsToNotify = append(allPRsToNotify, pr)
-						repoPRsToNotify[repo] = append(repoPRsToNotify[repo], pr)
-					}
-				}
-			}
-
-			if len(allPRsToNotify) > 0 {
-				slog.Info("Sending summary notification email", "prs_to_notify", len(allPRsToNotify))
-				for _, notifier := range notifiers {
-					err := notifier.Notify(allPRsToNotify, repoPRsToNotify, prParticipants, cfg.PRFilter.StaleAfterDays)
-					if err != nil {
-						slog.Error("Error notifying", "error", err)
-					}
-				}
-				err = stateStore.SetLastNotificationTime(time.Now())
-				if err != nil {
-					slog.Error("Error updating last notification time", "error", err)
-				}
-			} else {
-				slog.Info("No PRs to notify in this cycle.")
-			}
-
-			slog.Info("Sleeping until next check...", "hours", cfg.Notification.IntervalHours)
-			select {
-			case <-ctx.Done():
-				return nil
-			case <-time.After(checkFreq):
-				// continue loop
-			}
-		}
+	var repos []provider.Repository
+	for _, name := range cfg.Bitbucket.Repositories {
+		repos = append(repos, provider.Repository{Name: name, Provider: mockClient})
 	}
+	return run(ctx, newService(cfg, provider.NewStaticCatalog(repos)), nil, options{})
 }
 
 // createMockBitbucketServer creates a mock Bitbucket server for testing
@@ -147,14 +34,7 @@ func createMockBitbucketServer() (*httptest.Server, *bitbucket.Client) {
 	}))
 
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{
+		Bitbucket: config.BitbucketConfig{
 			Workspace: "test-workspace",
 		},
 	}
@@ -171,14 +51,7 @@ func createMockBitbucketServer() (*httptest.Server, *bitbucket.Client) {
 func TestRun_EmptyConfig(t *testing.T) {
 	// Create a minimal config for testing
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{
+		Bitbucket: config.BitbucketConfig{
 			Domain:       "bitbucket.org",
 			Port:         443,
 			Workspace:    "test-workspace",
@@ -186,34 +59,12 @@ func TestRun_EmptyConfig(t *testing.T) {
 			AppPassword:  "test-password",
 			Repositories: []string{},
 		},
-		PRFilter: struct {
-			IgnoreKeywords []string `yaml:"ignore_keywords"`
-			StaleAfterDays int      `yaml:"stale_after_days"`
-		}{
+		PRFilter: config.PRFilterConfig{
 			IgnoreKeywords: []string{"WIP", "DRAFT"},
 			StaleAfterDays: 7,
 		},
-		Notifiers: struct {
-			SMTP struct {
-				Host     string   `yaml:"host"`
-				Port     int      `yaml:"port"`
-				User     string   `yaml:"user"`
-				Password string   `yaml:"password"`
-				From     string   `yaml:"from"`
-				To       []string `yaml:"to"`
-			} `yaml:"smtp"`
-			Teams struct {
-				WebhookURL string `yaml:"webhook_url"`
-			} `yaml:"teams"`
-		}{
-			SMTP: struct {
-				Host     string   `yaml:"host"`
-				Port     int      `yaml:"port"`
-				User     string   `yaml:"user"`
-				Password string   `yaml:"password"`
-				From     string   `yaml:"from"`
-				To       []string `yaml:"to"`
-			}{
+		Notifiers: config.NotifiersConfig{
+			SMTP: config.SMTPConfig{
 				Host:     "smtp.gmail.com",
 				Port:     587,
 				User:     "test@example.com",
@@ -222,9 +73,7 @@ func TestRun_EmptyConfig(t *testing.T) {
 				To:       []string{"admin@example.com"},
 			},
 		},
-		Notification: struct {
-			IntervalHours int `yaml:"interval_hours"`
-		}{
+		Notification: config.NotificationConfig{
 			IntervalHours: 24,
 		},
 	}
@@ -247,14 +96,7 @@ func TestRun_EmptyConfig(t *testing.T) {
 func TestRun_WithRepositories(t *testing.T) {
 	// Create a config with repositories
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{
+		Bitbucket: config.BitbucketConfig{
 			Domain:       "bitbucket.org",
 			Port:         443,
 			Workspace:    "test-workspace",
@@ -262,34 +104,12 @@ func TestRun_WithRepositories(t *testing.T) {
 			AppPassword:  "test-password",
 			Repositories: []string{"test-repo"},
 		},
-		PRFilter: struct {
-			IgnoreKeywords []string `yaml:"ignore_keywords"`
-			StaleAfterDays int      `yaml:"stale_after_days"`
-		}{
+		PRFilter: config.PRFilterConfig{
 			IgnoreKeywords: []string{"WIP", "DRAFT"},
 			StaleAfterDays: 7,
 		},
-		Notifiers: struct {
-			SMTP struct {
-				Host     string   `yaml:"host"`
-				Port     int      `yaml:"port"`
-				User     string   `yaml:"user"`
-				Password string   `yaml:"password"`
-				From     string   `yaml:"from"`
-				To       []string `yaml:"to"`
-			} `yaml:"smtp"`
-			Teams struct {
-				WebhookURL string `yaml:"webhook_url"`
-			} `yaml:"teams"`
-		}{
-			SMTP: struct {
-				Host     string   `yaml:"host"`
-				Port     int      `yaml:"port"`
-				User     string   `yaml:"user"`
-				Password string   `yaml:"password"`
-				From     string   `yaml:"from"`
-				To       []string `yaml:"to"`
-			}{
+		Notifiers: config.NotifiersConfig{
+			SMTP: config.SMTPConfig{
 				Host:     "smtp.gmail.com",
 				Port:     587,
 				User:     "test@example.com",
@@ -298,9 +118,7 @@ func TestRun_WithRepositories(t *testing.T) {
 				To:       []string{"admin@example.com"},
 			},
 		},
-		Notification: struct {
-			IntervalHours int `yaml:"interval_hours"`
-		}{
+		Notification: config.NotificationConfig{
 			IntervalHours: 24,
 		},
 	}
@@ -323,14 +141,7 @@ func TestRun_WithRepositories(t *testing.T) {
 func TestRun_WithTeamsNotifier(t *testing.T) {
 	// Create a config with Teams notifier
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{
+		Bitbucket: config.BitbucketConfig{
 			Domain:       "bitbucket.org",
 			Port:         443,
 			Workspace:    "test-workspace",
@@ -338,34 +149,12 @@ func TestRun_WithTeamsNotifier(t *testing.T) {
 			AppPassword:  "test-password",
 			Repositories: []string{},
 		},
-		PRFilter: struct {
-			IgnoreKeywords []string `yaml:"ignore_keywords"`
-			StaleAfterDays int      `yaml:"stale_after_days"`
-		}{
+		PRFilter: config.PRFilterConfig{
 			IgnoreKeywords: []string{"WIP", "DRAFT"},
 			StaleAfterDays: 7,
 		},
-		Notifiers: struct {
-			SMTP struct {
-				Host     string   `yaml:"host"`
-				Port     int      `yaml:"port"`
-				User     string   `yaml:"user"`
-				Password string   `yaml:"password"`
-				From     string   `yaml:"from"`
-				To       []string `yaml:"to"`
-			} `yaml:"smtp"`
-			Teams struct {
-				WebhookURL string `yaml:"webhook_url"`
-			} `yaml:"teams"`
-		}{
-			SMTP: struct {
-				Host     string   `yaml:"host"`
-				Port     int      `yaml:"port"`
-				User     string   `yaml:"user"`
-				Password string   `yaml:"password"`
-				From     string   `yaml:"from"`
-				To       []string `yaml:"to"`
-			}{
+		Notifiers: config.NotifiersConfig{
+			SMTP: config.SMTPConfig{
 				Host:     "smtp.gmail.com",
 				Port:     587,
 				User:     "test@example.com",
@@ -373,15 +162,11 @@ func TestRun_WithTeamsNotifier(t *testing.T) {
 				From:     "test@example.com",
 				To:       []string{"admin@example.com"},
 			},
-			Teams: struct {
-				WebhookURL string `yaml:"webhook_url"`
-			}{
+			Teams: config.TeamsConfig{
 				WebhookURL: "https://webhook.url",
 			},
 		},
-		Notification: struct {
-			IntervalHours int `yaml:"interval_hours"`
-		}{
+		Notification: config.NotificationConfig{
 			IntervalHours: 24,
 		},
 	}
@@ -404,14 +189,7 @@ func TestRun_WithTeamsNotifier(t *testing.T) {
 func TestRun_ContextCancellation(t *testing.T) {
 	// Create a minimal config
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{
+		Bitbucket: config.BitbucketConfig{
 			Domain:       "bitbucket.org",
 			Port:         443,
 			Workspace:    "test-workspace",
@@ -419,34 +197,12 @@ func TestRun_ContextCancellation(t *testing.T) {
 			AppPassword:  "test-password",
 			Repositories: []string{},
 		},
-		PRFilter: struct {
-			IgnoreKeywords []string `yaml:"ignore_keywords"`
-			StaleAfterDays int      `yaml:"stale_after_days"`
-		}{
+		PRFilter: config.PRFilterConfig{
 			IgnoreKeywords: []string{"WIP", "DRAFT"},
 			StaleAfterDays: 7,
 		},
-		Notifiers: struct {
-			SMTP struct {
-				Host     string   `yaml:"host"`
-				Port     int      `yaml:"port"`
-				User     string   `yaml:"user"`
-				Password string   `yaml:"password"`
-				From     string   `yaml:"from"`
-				To       []string `yaml:"to"`
-			} `yaml:"smtp"`
-			Teams struct {
-				WebhookURL string `yaml:"webhook_url"`
-			} `yaml:"teams"`
-		}{
-			SMTP: struct {
-				Host     string   `yaml:"host"`
-				Port     int      `yaml:"port"`
-				User     string   `yaml:"user"`
-				Password string   `yaml:"password"`
-				From     string   `yaml:"from"`
-				To       []string `yaml:"to"`
-			}{
+		Notifiers: config.NotifiersConfig{
+			SMTP: config.SMTPConfig{
 				Host:     "smtp.gmail.com",
 				Port:     587,
 				User:     "test@example.com",
@@ -455,9 +211,7 @@ func TestRun_ContextCancellation(t *testing.T) {
 				To:       []string{"admin@example.com"},
 			},
 		},
-		Notification: struct {
-			IntervalHours int `yaml:"interval_hours"`
-		}{
+		Notification: config.NotificationConfig{
 			IntervalHours: 24,
 		},
 	}
@@ -510,3 +264,141 @@ func TestFileNotificationStateStore_Integration(t *testing.T) {
 		t.Errorf("Expected retrieved time to match set time, got %v vs %v", retrievedTime, now)
 	}
 }
+
+func TestCycleContext(t *testing.T) {
+	cfg := &config.Config{}
+	ctx, cancel := cycleContext(context.Background(), cfg)
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("Expected no deadline when cycle_timeout_minutes is not set")
+	}
+	cancel()
+
+	cfg.Notification.CycleTimeoutMinutes = 5
+	ctx, cancel = cycleContext(context.Background(), cfg)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		t.Fatal("Expected a deadline when cycle_timeout_minutes is set")
+	}
+	if remaining := time.Until(deadline); remaining <= 4*time.Minute || remaining > 5*time.Minute {
+		t.Errorf("Expected deadline about 5 minutes away, got %v", remaining)
+	}
+}
+
+// createStaleBitbucketServer serves one open PR, last updated 30 days ago, with an unapproved reviewer
+func createStaleBitbucketServer(status int) (*httptest.Server, *bitbucket.Client) {
+	updated := time.Now().AddDate(0, 0, -30).UnixMilli()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/participants"):
+			w.Write([]byte(`{"values":[{"role":"REVIEWER","approved":false,"status":"UNAPPROVED"}]}`))
+		case strings.HasSuffix(r.URL.Path, "/activities"):
+			w.Write([]byte(`{"values":[]}`))
+		default:
+			fmt.Fprintf(w, `{"values":[{"id":42,"title":"Forgotten PR","createdDate":%d,"updatedDate":%d,
+				"links":{"self":[{"href":"https://bitbucket.example.com/pr/42"}]}}]}`, updated, updated)
+		}
+	}))
+	cfg := &config.Config{Bitbucket: config.BitbucketConfig{Workspace: "test-workspace"}}
+	return server, &bitbucket.Client{Config: cfg, Client: server.Client(), BaseURL: server.URL}
+}
+
+func TestRunOnce(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		staleDays    int
+		expectedCode int
+	}{
+		{"stale PRs", 200, 7, exitStale},
+		{"nothing stale", 200, 60, exitOK},
+		{"API failure", 403, 7, exitPartial},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := createStaleBitbucketServer(tt.status)
+			defer server.Close()
+
+			cfg := &config.Config{
+				PRFilter: config.PRFilterConfig{StaleAfterDays: tt.staleDays},
+				Notifiers: config.NotifiersConfig{
+					SMTP:  config.SMTPConfig{From: "tracker@example.com", To: []string{"team@example.com"}},
+					Teams: config.TeamsConfig{WebhookURL: "https://webhook.invalid"},
+				},
+				Concurrency: config.ConcurrencyConfig{Workers: 2},
+			}
+			catalog := provider.NewStaticCatalog([]provider.Repository{{Name: "repo1", Provider: client}})
+
+			var out bytes.Buffer
+			code := runOnce(context.Background(), newService(cfg, catalog), options{dryRun: true, out: &out})
+			if code != tt.expectedCode {
+				t.Errorf("Expected exit code %d, got %d", tt.expectedCode, code)
+			}
+			if tt.expectedCode != exitStale {
+				if out.Len() != 0 {
+					t.Errorf("Expected no preview, got:\n%s", out.String())
+				}
+				return
+			}
+			for _, want := range []string{"===== email =====", "To: team@example.com", "Forgotten PR", "===== teams =====", `"@type": "MessageCard"`} {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("Expected dry-run output to contain %q, got:\n%s", want, out.String())
+				}
+			}
+			if _, err := os.Stat(stateFile); err == nil {
+				t.Error("Expected a dry run not to record the notification time")
+			}
+		})
+	}
+}
+
+func TestCycleOutcome_ExitCode(t *testing.T) {
+	tests := []struct {
+		outcome  cycleOutcome
+		expected int
+	}{
+		{cycleOutcome{}, exitOK},
+		{cycleOutcome{stale: 3}, exitStale},
+		{cycleOutcome{stale: 3, failures: 1}, exitPartial},
+		{cycleOutcome{notifyErrors: 1}, exitPartial},
+		{cycleOutcome{deadline: true}, exitPartial},
+	}
+	for _, tt := range tests {
+		if got := tt.outcome.exitCode(); got != tt.expected {
+			t.Errorf("Expected exit code %d for %+v, got %d", tt.expected, tt.outcome, got)
+		}
+	}
+}
+
+func TestService_FirstCheck(t *testing.T) {
+	// Wednesday, March 18th 2026
+	now := time.Date(2026, 3, 18, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		notification config.NotificationConfig
+		lastNotified time.Time
+		expected     time.Time
+	}{
+		{"never notified", config.NotificationConfig{IntervalHours: 24}, time.Time{}, now},
+		{"interval elapsed", config.NotificationConfig{IntervalHours: 24}, now.Add(-25 * time.Hour), now},
+		{"interval not reached", config.NotificationConfig{IntervalHours: 24}, now.Add(-2 * time.Hour), now.Add(22 * time.Hour)},
+		{"quiet hours", config.NotificationConfig{IntervalHours: 24, Timezone: "UTC",
+			QuietHours: config.QuietHoursConfig{Start: "09:00", End: "11:00"}}, time.Time{}, now.Add(30 * time.Minute)},
+		{"cron waits for its time", config.NotificationConfig{Schedule: "0 9 * * MON-FRI", Timezone: "UTC"},
+			time.Time{}, time.Date(2026, 3, 19, 9, 0, 0, 0, time.UTC)},
+		{"cron in another time zone", config.NotificationConfig{Schedule: "0 9 * * *", Timezone: "America/Sao_Paulo"},
+			time.Time{}, time.Date(2026, 3, 18, 12, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newService(&config.Config{Notification: tt.notification}, provider.NewStaticCatalog(nil))
+			if got := svc.firstCheck(now, tt.lastNotified); !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got.UTC())
+			}
+		})
+	}
+}
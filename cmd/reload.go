@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// reloadPollInterval is how often serve checks the configuration file for changes
+const reloadPollInterval = 5 * time.Second
+
+// configWatcher reloads the configuration when its file changes or on SIGHUP.
+// Changes are detected by content, so editors that replace the file and Kubernetes
+// ConfigMap updates, which swap a symlink, are both noticed.
+type configWatcher struct {
+	path     string
+	poll     time.Duration
+	load     func() (*service, error)
+	checksum [sha256.Size]byte
+	missing  bool // the file could not be read on the last poll
+}
+
+// newConfigWatcher remembers the current content of the file at path; load builds
+// the service of a changed file
+func newConfigWatcher(path string, poll time.Duration, load func() (*service, error)) (*configWatcher, error) {
+	w := &configWatcher{path: path, poll: poll, load: load}
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return nil, err
+	}
+	w.checksum = sum
+	return w, nil
+}
+
+// watch delivers the service of every valid reload until ctx is done. An invalid
+// configuration is logged and skipped, so the current one stays in use.
+func (w *configWatcher) watch(ctx context.Context, hup <-chan os.Signal) <-chan *service {
+	reloads := make(chan *service)
+	go func() {
+		ticker := time.NewTicker(w.poll)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				slog.Info("SIGHUP received, reloading configuration", "path", w.path)
+				// Record the content, so the next poll does not reload it again
+				if sum, err := fileChecksum(w.path); err == nil {
+					w.checksum = sum
+				}
+			case <-ticker.C:
+				if !w.changed() {
+					continue
+				}
+				slog.Info("Configuration file changed, reloading", "path", w.path)
+			}
+
+			svc, err := w.load()
+			if err != nil {
+				slog.Error("Configuration reload failed, keeping the current configuration", "path", w.path, "error", err)
+				continue
+			}
+			select {
+			case reloads <- svc:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return reloads
+}
+
+// changed reports whether the content of the file differs from the last one seen
+func (w *configWatcher) changed() bool {
+	sum, err := fileChecksum(w.path)
+	if err != nil {
+		// Editors may briefly remove the file while saving it
+		if !w.missing {
+			slog.Warn("Cannot read the configuration file, keeping the current configuration", "path", w.path, "error", err)
+		}
+		w.missing = true
+		return false
+	}
+	w.missing = false
+	if sum == w.checksum {
+		return false
+	}
+	w.checksum = sum
+	return true
+}
+
+func fileChecksum(path string) ([sha256.Size]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
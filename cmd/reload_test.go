@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"fc-pr-tracker/internal/config"
+	"fc-pr-tracker/internal/provider"
+)
+
+// lockedBuffer lets the test read the output written by the run loop
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// receive waits for a reload, failing the test if none arrives
+func receive(t *testing.T, reloads <-chan *service) *service {
+	t.Helper()
+	select {
+	case svc := <-reloads:
+		return svc
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a reload")
+		return nil
+	}
+}
+
+// expectNoReload fails the test if a reload arrives within a few polls
+func expectNoReload(t *testing.T, reloads <-chan *service) {
+	t.Helper()
+	select {
+	case <-reloads:
+		t.Fatal("Expected no reload")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// replaceFile swaps the content of path at once, as editors do, so a poll never sees it half written
+func replaceFile(t *testing.T, path, content string) {
+	t.Helper()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("Failed to replace config: %v", err)
+	}
+}
+
+func TestConfigWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	replaceFile(t, path, "version: 1\n")
+
+	var loads atomic.Int32
+	var invalid atomic.Bool
+	load := func() (*service, error) {
+		loads.Add(1)
+		if invalid.Load() {
+			return nil, errors.New("invalid configuration")
+		}
+		return newService(&config.Config{}, provider.NewStaticCatalog(nil)), nil
+	}
+	w, err := newConfigWatcher(path, 10*time.Millisecond, load)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hup := make(chan os.Signal, 1)
+	reloads := w.watch(ctx, hup)
+
+	// An unchanged file is not reloaded
+	expectNoReload(t, reloads)
+
+	// A changed file is reloaded once
+	replaceFile(t, path, "version: 2\n")
+	receive(t, reloads)
+	expectNoReload(t, reloads)
+
+	// An invalid file keeps the current configuration
+	invalid.Store(true)
+	replaceFile(t, path, "version: 3\n")
+	expectNoReload(t, reloads)
+
+	// SIGHUP reloads even when the file did not change
+	invalid.Store(false)
+	hup <- os.Interrupt
+	receive(t, reloads)
+
+	if n := loads.Load(); n != 3 {
+		t.Errorf("Expected 3 loads, got %d", n)
+	}
+}
+
+func TestNewConfigWatcher_MissingFile(t *testing.T) {
+	if _, err := newConfigWatcher(filepath.Join(t.TempDir(), "missing.yaml"), time.Second, nil); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestRun_Reload(t *testing.T) {
+	server, client := createStaleBitbucketServer(200)
+	defer server.Close()
+	cfg := testCycleConfig(7)
+	cfg.Notification.IntervalHours = 24
+	cfg.Notifiers.SMTP = config.SMTPConfig{From: "tracker@example.com", To: []string{"team@example.com"}}
+	catalog := provider.NewStaticCatalog([]provider.Repository{{Name: "repo1", Provider: client}})
+
+	reloaded := testCycleConfig(7)
+	reloaded.Notification.IntervalHours = 12
+	reloaded.Notifiers.SMTP = config.SMTPConfig{From: "tracker@example.com", To: []string{"new-team@example.com"}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reloads := make(chan *service)
+	out := &lockedBuffer{}
+	done := make(chan error)
+	go func() {
+		done <- run(ctx, newService(cfg, catalog), reloads, options{dryRun: true, out: out})
+	}()
+
+	// The first cycle runs right away
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(out.String(), "To: team@example.com") {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected a first cycle, got:\n%s", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The loop takes the new service while sleeping, without starting a cycle early
+	select {
+	case reloads <- newService(reloaded, catalog):
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the run loop to accept the reload")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if strings.Contains(out.String(), "new-team@example.com") {
+		t.Errorf("Expected the next cycle to stay due at the same time, got:\n%s", out.String())
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"fc-pr-tracker/internal/config"
+)
+
+// Supported values of the auth.type setting
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthOAuth2 = "oauth2"
+)
+
+// DefaultCloudTokenURL is the Bitbucket Cloud OAuth2 token endpoint used when none is configured
+const DefaultCloudTokenURL = "https://bitbucket.org/site/oauth2/access_token"
+
+// tokenExpiryMargin renews OAuth2 tokens slightly before they actually expire
+const tokenExpiryMargin = 30 * time.Second
+
+// Authenticator adds credentials to outgoing API requests
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// Invalidator is implemented by authenticators that cache credentials the server may revoke
+// before they expire
+type Invalidator interface {
+	// Invalidate drops the cached credentials that req was sent with
+	Invalidate(req *http.Request)
+}
+
+// NewAuthenticator builds the authenticator selected by auth.type.
+// An empty type keeps the historical user + app password Basic authentication.
+// Token requests made by the OAuth2 authenticator go through client.
+func NewAuthenticator(auth config.AuthConfig, user, password string, client *http.Client) Authenticator {
+	switch strings.ToLower(auth.Type) {
+	case "", AuthBasic:
+		return &BasicAuth{User: user, Password: password}
+	case AuthBearer:
+		return &BearerAuth{Token: auth.Token}
+	case AuthOAuth2:
+		return &OAuth2ClientCredentials{
+			TokenURL:     auth.TokenURL,
+			ClientID:     auth.ClientID,
+			ClientSecret: auth.ClientSecret,
+			Scopes:       auth.Scopes,
+			Client:       client,
+		}
+	default:
+		return unsupportedAuth(auth.Type)
+	}
+}
+
+// BasicAuth authenticates with a user name and an app password
+type BasicAuth struct {
+	User     string
+	Password string
+}
+
+// Authenticate sets the Basic Authorization header
+func (a *BasicAuth) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Basic "+basicCredentials(a.User, a.Password))
+	return nil
+}
+
+// BearerAuth authenticates with a personal, project, repository or HTTP access token
+type BearerAuth struct {
+	Token string
+}
+
+// Authenticate sets the Bearer Authorization header
+func (a *BearerAuth) Authenticate(req *http.Request) error {
+	if a.Token == "" {
+		return fmt.Errorf("bearer auth requires auth.token")
+	}
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+// OAuth2ClientCredentials authenticates with an access token obtained through the
+// OAuth2 client-credentials grant. The token is cached and renewed before it expires,
+// using the refresh token when the server handed one out, or as soon as it is rejected.
+type OAuth2ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	Client       *http.Client
+
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+	expiry       time.Time        // zero when the token does not expire
+	now          func() time.Time // overridden in tests
+}
+
+// tokenResponse is the body returned by the OAuth2 token endpoint
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Authenticate sets the Bearer Authorization header, fetching a new token first if needed
+func (a *OAuth2ClientCredentials) Authenticate(req *http.Request) error {
+	token, err := a.token(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// token returns a valid access token, fetching or refreshing it when the cached one is missing or expiring
+func (a *OAuth2ClientCredentials) token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.accessToken != "" && (a.expiry.IsZero() || a.clock().Before(a.expiry)) {
+		return a.accessToken, nil
+	}
+
+	if a.refreshToken != "" {
+		err := a.fetch(ctx, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {a.refreshToken},
+		})
+		if err == nil {
+			return a.accessToken, nil
+		}
+		slog.Warn("OAuth2 token refresh failed, requesting a new token", "error", err)
+		a.refreshToken = ""
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.Scopes, " "))
+	}
+	if err := a.fetch(ctx, form); err != nil {
+		return "", err
+	}
+	return a.accessToken, nil
+}
+
+// Invalidate drops the cached access token when it is the one req was sent with, so the
+// next request fetches a new one. Tokens fetched meanwhile by other requests are kept.
+func (a *OAuth2ClientCredentials) Invalidate(req *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.accessToken != "" && req.Header.Get("Authorization") == "Bearer "+a.accessToken {
+		slog.Info("OAuth2 access token rejected, requesting a new one", "token_url", a.TokenURL)
+		a.accessToken = ""
+		a.expiry = time.Time{}
+	}
+}
+
+// sendAuthenticated sends req through the retrier. When the server answers 401 and auth
+// caches credentials, they are dropped and the request is authenticated and sent once more.
+func (r *retrier) sendAuthenticated(client *http.Client, policy config.RetryConfig, auth Authenticator, req *http.Request) (*http.Response, error) {
+	resp, err := r.do(client, policy, req)
+	invalidator, ok := auth.(Invalidator)
+	if err != nil || !ok || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	invalidator.Invalidate(req)
+	retry := req.Clone(req.Context())
+	if err := auth.Authenticate(retry); err != nil {
+		return nil, err
+	}
+	return r.do(client, policy, retry)
+}
+
+// fetch posts form to the token endpoint and caches the returned token. Callers hold a.mu.
+func (a *OAuth2ClientCredentials) fetch(ctx context.Context, form url.Values) error {
+	if a.TokenURL == "" {
+		return fmt.Errorf("oauth2 auth requires auth.token_url")
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", a.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("error creating token request: %v", err)
+	}
+	req.SetBasicAuth(a.ClientID, a.ClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error requesting OAuth2 token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("OAuth2 token request failed: %s (URL: %s, Body: %s)", resp.Status, a.TokenURL, string(body))
+	}
+
+	var tok tokenResponse
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return fmt.Errorf("error decoding OAuth2 token: %v", err)
+	}
+	if tok.AccessToken == "" {
+		return fmt.Errorf("OAuth2 token response has no access_token (URL: %s)", a.TokenURL)
+	}
+
+	a.accessToken = tok.AccessToken
+	if tok.RefreshToken != "" {
+		a.refreshToken = tok.RefreshToken
+	}
+	a.expiry = time.Time{}
+	if tok.ExpiresIn > 0 {
+		a.expiry = a.clock().Add(time.Duration(tok.ExpiresIn)*time.Second - tokenExpiryMargin)
+	}
+	slog.Debug("Fetched OAuth2 access token", "token_url", a.TokenURL, "expires_in", tok.ExpiresIn)
+	return nil
+}
+
+func (a *OAuth2ClientCredentials) clock() time.Time {
+	if a.now != nil {
+		return a.now()
+	}
+	return time.Now()
+}
+
+// unsupportedAuth fails every request, so a typo in auth.type never silently falls back to another scheme
+type unsupportedAuth string
+
+// Authenticate always returns an error
+func (a unsupportedAuth) Authenticate(req *http.Request) error {
+	return fmt.Errorf("unsupported auth type %q (expected %s, %s or %s)", string(a), AuthBasic, AuthBearer, AuthOAuth2)
+}
+
+// basicCredentials encodes user and password for a Basic Authorization header
+func basicCredentials(user, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(user + ":" + password))
+}
@@ -0,0 +1,259 @@
+package bitbucket
+
+import (
+	"context"
+	"fc-pr-tracker/internal/config"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewAuthenticator(t *testing.T) {
+	tests := []struct {
+		name       string
+		auth       config.AuthConfig
+		wantHeader string
+		wantErr    bool
+	}{
+		{"default is basic", config.AuthConfig{}, "Basic dGVzdHVzZXI6dGVzdHBhc3M=", false},
+		{"explicit basic", config.AuthConfig{Type: "basic"}, "Basic dGVzdHVzZXI6dGVzdHBhc3M=", false},
+		{"bearer", config.AuthConfig{Type: "Bearer", Token: "pat-123"}, "Bearer pat-123", false},
+		{"bearer without token", config.AuthConfig{Type: "bearer"}, "", true},
+		{"unknown type", config.AuthConfig{Type: "kerberos"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := NewAuthenticator(tt.auth, "testuser", "testpass", nil)
+			req, _ := http.NewRequest("GET", "http://example.com", nil)
+			err := auth.Authenticate(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got := req.Header.Get("Authorization"); got != tt.wantHeader {
+				t.Errorf("Expected Authorization %q, got %q", tt.wantHeader, got)
+			}
+		})
+	}
+}
+
+func TestOAuth2ClientCredentials_FetchCacheAndRefresh(t *testing.T) {
+	var grants []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "key" || pass != "secret" {
+			t.Errorf("Expected client credentials key:secret, got %q:%q", user, pass)
+		}
+		r.ParseForm()
+		grant := r.PostForm.Get("grant_type")
+		grants = append(grants, grant)
+		switch grant {
+		case "client_credentials":
+			if got := r.PostForm.Get("scope"); got != "repository pullrequest" {
+				t.Errorf("Expected scopes to be sent, got %q", got)
+			}
+			w.Write([]byte(`{"access_token":"token-1","expires_in":3600,"refresh_token":"refresh-1"}`))
+		case "refresh_token":
+			if got := r.PostForm.Get("refresh_token"); got != "refresh-1" {
+				t.Errorf("Expected refresh token 'refresh-1', got %q", got)
+			}
+			w.Write([]byte(`{"access_token":"token-2","expires_in":3600}`))
+		}
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	auth := &OAuth2ClientCredentials{
+		TokenURL:     server.URL,
+		ClientID:     "key",
+		ClientSecret: "secret",
+		Scopes:       []string{"repository", "pullrequest"},
+		now:          func() time.Time { return now },
+	}
+	authorize := func() string {
+		req, _ := http.NewRequestWithContext(context.Background(), "GET", "http://example.com", nil)
+		if err := auth.Authenticate(req); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return req.Header.Get("Authorization")
+	}
+
+	if got := authorize(); got != "Bearer token-1" {
+		t.Errorf("Expected 'Bearer token-1', got %q", got)
+	}
+	if got := authorize(); got != "Bearer token-1" {
+		t.Errorf("Expected cached token, got %q", got)
+	}
+
+	now = now.Add(time.Hour)
+	if got := authorize(); got != "Bearer token-2" {
+		t.Errorf("Expected refreshed token, got %q", got)
+	}
+
+	want := []string{"client_credentials", "refresh_token"}
+	if strings.Join(grants, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected grants %v, got %v", want, grants)
+	}
+}
+
+func TestOAuth2ClientCredentials_RefreshFailureFallsBack(t *testing.T) {
+	var grants []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		grant := r.PostForm.Get("grant_type")
+		grants = append(grants, grant)
+		if grant == "refresh_token" {
+			w.WriteHeader(400)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":60,"refresh_token":"r"}`, len(grants))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	auth := &OAuth2ClientCredentials{TokenURL: server.URL, now: func() time.Time { return now }}
+	if _, err := auth.token(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	now = now.Add(time.Minute)
+	token, err := auth.token(context.Background())
+	if err != nil {
+		t.Fatalf("Expected fallback to client credentials, got %v", err)
+	}
+	if token != "token-3" {
+		t.Errorf("Expected 'token-3', got %q", token)
+	}
+	want := []string{"client_credentials", "refresh_token", "client_credentials"}
+	if strings.Join(grants, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected grants %v, got %v", want, grants)
+	}
+}
+
+func TestOAuth2ClientCredentials_TokenError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+		w.Write([]byte(`{"error":"invalid_client"}`))
+	}))
+	defer server.Close()
+
+	auth := &OAuth2ClientCredentials{TokenURL: server.URL}
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	err := auth.Authenticate(req)
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected token request error mentioning 401, got %v", err)
+	}
+	if req.Header.Get("Authorization") != "" {
+		t.Errorf("Expected no Authorization header on failure")
+	}
+}
+
+func TestClient_BearerAuth(t *testing.T) {
+	cfg := &config.Config{Bitbucket: config.BitbucketConfig{
+		Workspace: "test-workspace",
+		Auth:      config.AuthConfig{Type: "bearer", Token: "http-access-token"},
+	}}
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer http-access-token" {
+			t.Errorf("Expected bearer token, got %q", got)
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"values":[]}`))
+	}, cfg)
+
+	if err := client.TestConnection(context.Background()); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestCloudClient_OAuth2(t *testing.T) {
+	tokenCalls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/site/oauth2/access_token" {
+			tokenCalls++
+			w.Write([]byte(`{"access_token":"cloud-token","expires_in":7200}`))
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer cloud-token" {
+			t.Errorf("Expected OAuth2 bearer token, got %q", got)
+		}
+		w.Write([]byte(`{"values":[]}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{BitbucketCloud: config.BitbucketCloudConfig{
+		Workspace: "my-team",
+		Auth: config.AuthConfig{
+			Type:         "oauth2",
+			ClientID:     "key",
+			ClientSecret: "secret",
+			TokenURL:     server.URL + "/site/oauth2/access_token",
+		},
+	}}
+	client := &CloudClient{Config: cfg, Client: server.Client(), BaseURL: server.URL}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListOpenPRs(context.Background(), "repo"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if tokenCalls != 1 {
+		t.Errorf("Expected the token to be fetched once, got %d", tokenCalls)
+	}
+}
+
+func TestCloudClient_OAuth2_RevokedToken(t *testing.T) {
+	tokenCalls, apiCalls := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/site/oauth2/access_token" {
+			tokenCalls++
+			fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":7200}`, tokenCalls)
+			return
+		}
+		apiCalls++
+		if r.Header.Get("Authorization") == "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"values":[]}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{BitbucketCloud: config.BitbucketCloudConfig{
+		Workspace: "my-team",
+		Auth: config.AuthConfig{
+			Type:         "oauth2",
+			ClientID:     "key",
+			ClientSecret: "secret",
+			TokenURL:     server.URL + "/site/oauth2/access_token",
+		},
+	}}
+	client := &CloudClient{Config: cfg, Client: server.Client(), BaseURL: server.URL}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListOpenPRs(context.Background(), "repo"); err != nil {
+			t.Fatalf("Expected the request to succeed with a new token, got %v", err)
+		}
+	}
+	if tokenCalls != 2 || apiCalls != 3 {
+		t.Errorf("Expected 2 token fetches and 3 API calls, got %d and %d", tokenCalls, apiCalls)
+	}
+}
+
+func TestClient_BasicAuth_UnauthorizedNotRetried(t *testing.T) {
+	cfg := &config.Config{Bitbucket: config.BitbucketConfig{Workspace: "test-workspace"}}
+	calls := 0
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnauthorized)
+	}, cfg)
+
+	if err := client.TestConnection(context.Background()); err == nil {
+		t.Error("Expected error for 401, got nil")
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single attempt without cached credentials, got %d", calls)
+	}
+}
@@ -1,13 +1,15 @@
 package bitbucket
 
 import (
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"fc-pr-tracker/internal/config"
@@ -18,7 +20,11 @@ import (
 type Client struct {
 	Config  *config.Config
 	Client  *http.Client
-	BaseURL string // para testes
+	BaseURL string        // para testes
+	Auth    Authenticator // built from Config.Bitbucket on first use when nil
+
+	authOnce sync.Once
+	retrier
 }
 
 // NewClient creates a new Bitbucket client
@@ -29,27 +35,35 @@ func NewClient(cfg *config.Config) *Client {
 	}
 }
 
-// TestConnection checks if the Bitbucket API is reachable and credentials are valid
-func (c *Client) TestConnection() error {
-	var url string
+// Name identifies the provider in logs
+func (c *Client) Name() string {
+	return "bitbucket-server"
+}
+
+// Host returns the Bitbucket Server host (and port) the client talks to
+func (c *Client) Host() string {
 	if c.BaseURL != "" {
-		url = c.BaseURL + "/rest/api/1.0/projects/" + c.Config.Bitbucket.Workspace + "/repos?limit=1"
-	} else {
-		url = fmt.Sprintf("https://%s:%d/rest/api/1.0/projects/%s/repos?limit=1",
-			c.Config.Bitbucket.Domain,
-			c.Config.Bitbucket.Port,
-			c.Config.Bitbucket.Workspace)
+		if u, err := url.Parse(c.BaseURL); err == nil {
+			return u.Host
+		}
 	}
+	return fmt.Sprintf("%s:%d", c.Config.Bitbucket.Domain, c.Config.Bitbucket.Port)
+}
+
+// TestConnection checks if the Bitbucket API is reachable and credentials are valid
+func (c *Client) TestConnection(ctx context.Context) error {
+	url := c.apiURL("/rest/api/1.0/projects/" + c.defaultProject() + "/repos?limit=1")
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return fmt.Errorf("error creating test request: %v", err)
 	}
 
-	req.Header.Set("Authorization", "Basic "+c.basicAuth())
-	slog.Debug("Basic Auth header set for test connection")
+	if err := c.authenticate(req); err != nil {
+		return fmt.Errorf("error authenticating test request: %v", err)
+	}
 
-	resp, err := c.Client.Do(req)
+	resp, err := c.sendAuthenticated(c.Client, c.Config.Bitbucket.Retry, c.Auth, req)
 	if err != nil {
 		return fmt.Errorf("error connecting to Bitbucket: %v", err)
 	}
@@ -63,37 +77,27 @@ func (c *Client) TestConnection() error {
 }
 
 // ListOpenPRs fetches open PRs for a repository
-func (c *Client) ListOpenPRs(repo string) ([]models.PullRequest, error) {
+func (c *Client) ListOpenPRs(ctx context.Context, repo string) ([]models.PullRequest, error) {
 	var prs []models.PullRequest
-	var baseURL string
-	if c.BaseURL != "" {
-		baseURL = c.BaseURL + "/rest/api/1.0/projects/" + c.Config.Bitbucket.Workspace + "/repos/" + repo + "/pull-requests?state=OPEN"
-	} else {
-		baseURL = fmt.Sprintf(
-			"https://%s:%d/rest/api/1.0/projects/%s/repos/%s/pull-requests?state=OPEN",
-			c.Config.Bitbucket.Domain,
-			c.Config.Bitbucket.Port,
-			c.Config.Bitbucket.Workspace,
-			repo,
-		)
-	}
-	url := baseURL
+	url := c.repoURL(repo) + "/pull-requests?state=OPEN"
 
 	headers := map[string]string{
-		"Authorization": "Basic " + c.basicAuth(),
-		"Content-Type":  "application/json",
+		"Content-Type": "application/json",
 	}
 
 	for url != "" {
-		req, err := http.NewRequest("GET", url, nil)
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
 			return nil, err
 		}
 		for k, v := range headers {
 			req.Header.Set(k, v)
 		}
+		if err := c.authenticate(req); err != nil {
+			return nil, err
+		}
 
-		resp, err := c.Client.Do(req)
+		resp, err := c.sendAuthenticated(c.Client, c.Config.Bitbucket.Retry, c.Auth, req)
 		if err != nil {
 			return nil, err
 		}
@@ -111,16 +115,7 @@ func (c *Client) ListOpenPRs(repo string) ([]models.PullRequest, error) {
 		prs = append(prs, prResp.Values...)
 		// Corrigir paginação: se prResp.Next for relativo, concatenar com BaseURL
 		if prResp.Next != "" && strings.HasPrefix(prResp.Next, "/") {
-			if c.BaseURL != "" {
-				url = c.BaseURL + prResp.Next
-			} else {
-				url = fmt.Sprintf(
-					"https://%s:%d%s",
-					c.Config.Bitbucket.Domain,
-					c.Config.Bitbucket.Port,
-					prResp.Next,
-				)
-			}
+			url = c.apiURL(prResp.Next)
 		} else {
 			url = prResp.Next
 		}
@@ -129,30 +124,19 @@ func (c *Client) ListOpenPRs(repo string) ([]models.PullRequest, error) {
 }
 
 // GetParticipants fetches PR participants (reviewers)
-func (c *Client) GetParticipants(repo string, prID int) ([]models.Participant, error) {
-	var url string
-	if c.BaseURL != "" {
-		url = fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/pull-requests/%d/participants", c.BaseURL, c.Config.Bitbucket.Workspace, repo, prID)
-	} else {
-		url = fmt.Sprintf(
-			"https://%s:%d/rest/api/1.0/projects/%s/repos/%s/pull-requests/%d/participants",
-			c.Config.Bitbucket.Domain,
-			c.Config.Bitbucket.Port,
-			c.Config.Bitbucket.Workspace,
-			repo,
-			prID,
-		)
-	}
+func (c *Client) GetParticipants(ctx context.Context, repo string, prID int) ([]models.Participant, error) {
+	url := fmt.Sprintf("%s/pull-requests/%d/participants", c.repoURL(repo), prID)
 	slog.Info("Fetching participants for PR", "pr_id", prID, "repo", repo, "url", url)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", "Basic "+c.basicAuth())
-	slog.Debug("Basic Auth header set for participants fetch")
+	if err := c.authenticate(req); err != nil {
+		return nil, err
+	}
 
-	resp, err := c.Client.Do(req)
+	resp, err := c.sendAuthenticated(c.Client, c.Config.Bitbucket.Retry, c.Auth, req)
 	if err != nil {
 		return nil, err
 	}
@@ -173,32 +157,21 @@ func (c *Client) GetParticipants(repo string, prID int) ([]models.Participant, e
 }
 
 // GetComments fetches all comments/activities for a PR
-func (c *Client) GetComments(repo string, prID int) ([]models.Comment, error) {
-	var url string
+func (c *Client) GetComments(ctx context.Context, repo string, prID int) ([]models.Comment, error) {
 	var comments []models.Comment
-	if c.BaseURL != "" {
-		url = fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/pull-requests/%d/activities", c.BaseURL, c.Config.Bitbucket.Workspace, repo, prID)
-	} else {
-		url = fmt.Sprintf(
-			"https://%s:%d/rest/api/1.0/projects/%s/repos/%s/pull-requests/%d/activities",
-			c.Config.Bitbucket.Domain,
-			c.Config.Bitbucket.Port,
-			c.Config.Bitbucket.Workspace,
-			repo,
-			prID,
-		)
-	}
+	url := fmt.Sprintf("%s/pull-requests/%d/activities", c.repoURL(repo), prID)
 	slog.Info("Fetching comments/activities for PR", "pr_id", prID, "repo", repo, "url", url)
 
 	for url != "" {
-		req, err := http.NewRequest("GET", url, nil)
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
 			return nil, err
 		}
-		req.Header.Set("Authorization", "Basic "+c.basicAuth())
-		slog.Debug("Basic Auth header set for comments/activities fetch")
+		if err := c.authenticate(req); err != nil {
+			return nil, err
+		}
 
-		resp, err := c.Client.Do(req)
+		resp, err := c.sendAuthenticated(c.Client, c.Config.Bitbucket.Retry, c.Auth, req)
 		if err != nil {
 			return nil, err
 		}
@@ -222,16 +195,53 @@ func (c *Client) GetComments(repo string, prID int) ([]models.Comment, error) {
 }
 
 // Helper methods
+
+// apiURL returns the absolute URL of an API path on the configured server
+func (c *Client) apiURL(path string) string {
+	if c.BaseURL != "" {
+		return c.BaseURL + path
+	}
+	return fmt.Sprintf("https://%s:%d%s", c.Config.Bitbucket.Domain, c.Config.Bitbucket.Port, path)
+}
+
+// repoURL returns the REST URL of repo, given either as a slug of the configured
+// workspace or as "PROJECT/slug"
+func (c *Client) repoURL(repo string) string {
+	project := c.Config.Bitbucket.Workspace
+	if i := strings.Index(repo, "/"); i >= 0 {
+		project, repo = repo[:i], repo[i+1:]
+	}
+	return c.apiURL(fmt.Sprintf("/rest/api/1.0/projects/%s/repos/%s", project, repo))
+}
+
+// defaultProject is the project used for connection tests: the workspace, or the first discovered project
+func (c *Client) defaultProject() string {
+	if c.Config.Bitbucket.Workspace == "" && len(c.Config.Bitbucket.Discovery.Projects) > 0 {
+		return c.Config.Bitbucket.Discovery.Projects[0]
+	}
+	return c.Config.Bitbucket.Workspace
+}
+
 func (c *Client) basicAuth() string {
-	auth := c.Config.Bitbucket.User + ":" + c.Config.Bitbucket.AppPassword
-	return base64.StdEncoding.EncodeToString([]byte(auth))
+	return basicCredentials(c.Config.Bitbucket.User, c.Config.Bitbucket.AppPassword)
+}
+
+// authenticate adds the configured credentials to req
+func (c *Client) authenticate(req *http.Request) error {
+	c.authOnce.Do(func() {
+		if c.Auth == nil {
+			b := c.Config.Bitbucket
+			c.Auth = NewAuthenticator(b.Auth, b.User, b.AppPassword, c.Client)
+		}
+	})
+	return c.Auth.Authenticate(req)
 }
 
 // FilterPRs filters PRs by ignored keywords
 func FilterPRs(prs []models.PullRequest, ignoreKeywords []string) []models.PullRequest {
 	var filtered []models.PullRequest
 	for _, pr := range prs {
-		if containsIgnoreKeyword(pr.Title, ignoreKeywords) {
+		if ContainsIgnoreKeyword(pr.Title, ignoreKeywords) {
 			continue // Ignore PRs with forbidden keywords
 		}
 		filtered = append(filtered, pr)
@@ -239,8 +249,8 @@ func FilterPRs(prs []models.PullRequest, ignoreKeywords []string) []models.PullR
 	return filtered
 }
 
-// containsIgnoreKeyword checks if the title contains any forbidden keyword
-func containsIgnoreKeyword(title string, keywords []string) bool {
+// ContainsIgnoreKeyword checks if the title contains any forbidden keyword
+func ContainsIgnoreKeyword(title string, keywords []string) bool {
 	titleLower := strings.ToLower(title)
 	for _, kw := range keywords {
 		if strings.Contains(titleLower, strings.ToLower(kw)) {
@@ -260,6 +270,16 @@ func IsPRApproved(participants []models.Participant) bool {
 	return true
 }
 
+// HasRequiredApprovals checks if the PR has minApprovals approvals or, when minApprovals is 0,
+// is approved by all reviewers
+func HasRequiredApprovals(participants []models.Participant, minApprovals int) bool {
+	if minApprovals == 0 {
+		return IsPRApproved(participants)
+	}
+	approved, _ := CountApprovals(participants)
+	return approved >= minApprovals
+}
+
 // CountApprovals counts the number of approved reviewers
 func CountApprovals(participants []models.Participant) (approved, total int) {
 	for _, p := range participants {
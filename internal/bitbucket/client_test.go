@@ -1,6 +1,7 @@
 package bitbucket
 
 import (
+	"context"
 	"encoding/json"
 	"fc-pr-tracker/internal/config"
 	"fc-pr-tracker/pkg/models"
@@ -27,14 +28,7 @@ func TestNewClient(t *testing.T) {
 
 func TestClient_basicAuth(t *testing.T) {
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{
+		Bitbucket: config.BitbucketConfig{
 			User:        "testuser",
 			AppPassword: "testpass",
 		},
@@ -70,7 +64,7 @@ func TestFilterPRs(t *testing.T) {
 
 	// Check that filtered PRs don't contain ignored keywords
 	for _, pr := range filtered {
-		if containsIgnoreKeyword(pr.Title, ignoreKeywords) {
+		if ContainsIgnoreKeyword(pr.Title, ignoreKeywords) {
 			t.Errorf("Filtered PR should not contain ignored keywords: %s", pr.Title)
 		}
 	}
@@ -151,7 +145,7 @@ func TestContainsIgnoreKeyword(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := containsIgnoreKeyword(tt.title, tt.keywords)
+			result := ContainsIgnoreKeyword(tt.title, tt.keywords)
 			if result != tt.expected {
 				t.Errorf("Expected %v, got %v for title '%s' with keywords %v", tt.expected, result, tt.title, tt.keywords)
 			}
@@ -206,6 +200,27 @@ func TestIsPRApproved(t *testing.T) {
 	}
 }
 
+func TestHasRequiredApprovals(t *testing.T) {
+	participants := []models.Participant{
+		{Approved: true, Role: "REVIEWER"},
+		{Approved: false, Role: "REVIEWER"},
+		{Approved: false, Role: "AUTHOR"},
+	}
+	tests := []struct {
+		minApprovals int
+		expected     bool
+	}{
+		{0, false}, // every reviewer must approve
+		{1, true},
+		{2, false},
+	}
+	for _, tt := range tests {
+		if got := HasRequiredApprovals(participants, tt.minApprovals); got != tt.expected {
+			t.Errorf("With min approvals %d: expected %v, got %v", tt.minApprovals, tt.expected, got)
+		}
+	}
+}
+
 func TestCountApprovals(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -312,14 +327,7 @@ func newTestClient(handler http.HandlerFunc, cfg *config.Config) *Client {
 
 func TestClient_TestConnection_Success(t *testing.T) {
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{
+		Bitbucket: config.BitbucketConfig{
 			Workspace: "test-workspace",
 		},
 	}
@@ -327,21 +335,14 @@ func TestClient_TestConnection_Success(t *testing.T) {
 		w.WriteHeader(200)
 		w.Write([]byte(`{"values":[]}`))
 	}, cfg)
-	if err := client.TestConnection(); err != nil {
+	if err := client.TestConnection(context.Background()); err != nil {
 		t.Errorf("Expected success, got error: %v", err)
 	}
 }
 
 func TestClient_TestConnection_FailStatus(t *testing.T) {
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{
+		Bitbucket: config.BitbucketConfig{
 			Workspace: "test-workspace",
 		},
 	}
@@ -349,7 +350,7 @@ func TestClient_TestConnection_FailStatus(t *testing.T) {
 		w.WriteHeader(401)
 		w.Write([]byte(`{"errors":[{"message":"Unauthorized"}]}`))
 	}, cfg)
-	err := client.TestConnection()
+	err := client.TestConnection(context.Background())
 	if err == nil {
 		t.Error("Expected error for unauthorized, got nil")
 	}
@@ -357,14 +358,7 @@ func TestClient_TestConnection_FailStatus(t *testing.T) {
 
 func TestClient_TestConnection_BadRequest(t *testing.T) {
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{
+		Bitbucket: config.BitbucketConfig{
 			Workspace: "test-workspace",
 		},
 	}
@@ -372,7 +366,7 @@ func TestClient_TestConnection_BadRequest(t *testing.T) {
 		w.WriteHeader(400)
 		w.Write([]byte(`{"errors":[{"message":"Bad Request"}]}`))
 	}, cfg)
-	err := client.TestConnection()
+	err := client.TestConnection(context.Background())
 	if err == nil {
 		t.Error("Expected error for bad request, got nil")
 	}
@@ -380,14 +374,7 @@ func TestClient_TestConnection_BadRequest(t *testing.T) {
 
 func TestClient_ListOpenPRs_Success(t *testing.T) {
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{},
+		Bitbucket: config.BitbucketConfig{},
 	}
 	pr := models.PullRequest{ID: 1, Title: "Test PR"}
 	resp := map[string]interface{}{"values": []models.PullRequest{pr}}
@@ -396,7 +383,7 @@ func TestClient_ListOpenPRs_Success(t *testing.T) {
 		w.WriteHeader(200)
 		w.Write(body)
 	}, cfg)
-	prs, err := client.ListOpenPRs("repo1")
+	prs, err := client.ListOpenPRs(context.Background(), "repo1")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -407,14 +394,7 @@ func TestClient_ListOpenPRs_Success(t *testing.T) {
 
 func TestClient_ListOpenPRs_Pagination(t *testing.T) {
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{},
+		Bitbucket: config.BitbucketConfig{},
 	}
 	pr1 := models.PullRequest{ID: 1, Title: "PR1"}
 	pr2 := models.PullRequest{ID: 2, Title: "PR2"}
@@ -433,7 +413,7 @@ func TestClient_ListOpenPRs_Pagination(t *testing.T) {
 			w.Write(body2)
 		}
 	}, cfg)
-	prs, err := client.ListOpenPRs("repo1")
+	prs, err := client.ListOpenPRs(context.Background(), "repo1")
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -444,20 +424,13 @@ func TestClient_ListOpenPRs_Pagination(t *testing.T) {
 
 func TestClient_ListOpenPRs_HTTPError(t *testing.T) {
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{},
+		Bitbucket: config.BitbucketConfig{},
 	}
 	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(500)
 		w.Write([]byte(`{"error":"server error"}`))
 	}, cfg)
-	_, err := client.ListOpenPRs("repo1")
+	_, err := client.ListOpenPRs(context.Background(), "repo1")
 	if err == nil {
 		t.Error("Expected error for HTTP 500, got nil")
 	}
@@ -465,20 +438,13 @@ func TestClient_ListOpenPRs_HTTPError(t *testing.T) {
 
 func TestClient_ListOpenPRs_BadJSON(t *testing.T) {
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{},
+		Bitbucket: config.BitbucketConfig{},
 	}
 	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(200)
 		w.Write([]byte("not json"))
 	}, cfg)
-	_, err := client.ListOpenPRs("repo1")
+	_, err := client.ListOpenPRs(context.Background(), "repo1")
 	if err == nil {
 		t.Error("Expected error for bad JSON, got nil")
 	}
@@ -486,14 +452,7 @@ func TestClient_ListOpenPRs_BadJSON(t *testing.T) {
 
 func TestClient_GetParticipants_Success(t *testing.T) {
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{},
+		Bitbucket: config.BitbucketConfig{},
 	}
 	p := models.Participant{Role: "REVIEWER", Approved: true}
 	resp := map[string]interface{}{"values": []models.Participant{p}}
@@ -502,7 +461,7 @@ func TestClient_GetParticipants_Success(t *testing.T) {
 		w.WriteHeader(200)
 		w.Write(body)
 	}, cfg)
-	ps, err := client.GetParticipants("repo1", 1)
+	ps, err := client.GetParticipants(context.Background(), "repo1", 1)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -513,20 +472,13 @@ func TestClient_GetParticipants_Success(t *testing.T) {
 
 func TestClient_GetParticipants_HTTPError(t *testing.T) {
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{},
+		Bitbucket: config.BitbucketConfig{},
 	}
 	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(404)
 		w.Write([]byte(`{"error":"not found"}`))
 	}, cfg)
-	_, err := client.GetParticipants("repo1", 1)
+	_, err := client.GetParticipants(context.Background(), "repo1", 1)
 	if err == nil {
 		t.Error("Expected error for HTTP 404, got nil")
 	}
@@ -534,20 +486,13 @@ func TestClient_GetParticipants_HTTPError(t *testing.T) {
 
 func TestClient_GetParticipants_BadJSON(t *testing.T) {
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{},
+		Bitbucket: config.BitbucketConfig{},
 	}
 	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(200)
 		w.Write([]byte("not json"))
 	}, cfg)
-	_, err := client.GetParticipants("repo1", 1)
+	_, err := client.GetParticipants(context.Background(), "repo1", 1)
 	if err == nil {
 		t.Error("Expected error for bad JSON, got nil")
 	}
@@ -555,14 +500,7 @@ func TestClient_GetParticipants_BadJSON(t *testing.T) {
 
 func TestClient_GetComments_Success(t *testing.T) {
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{},
+		Bitbucket: config.BitbucketConfig{},
 	}
 	c := models.Comment{ID: 1, Content: "Test comment"}
 	resp := map[string]interface{}{"values": []models.Comment{c}}
@@ -571,7 +509,7 @@ func TestClient_GetComments_Success(t *testing.T) {
 		w.WriteHeader(200)
 		w.Write(body)
 	}, cfg)
-	cs, err := client.GetComments("repo1", 1)
+	cs, err := client.GetComments(context.Background(), "repo1", 1)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -582,20 +520,13 @@ func TestClient_GetComments_Success(t *testing.T) {
 
 func TestClient_GetComments_HTTPError(t *testing.T) {
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{},
+		Bitbucket: config.BitbucketConfig{},
 	}
 	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(500)
 		w.Write([]byte(`{"error":"server error"}`))
 	}, cfg)
-	_, err := client.GetComments("repo1", 1)
+	_, err := client.GetComments(context.Background(), "repo1", 1)
 	if err == nil {
 		t.Error("Expected error for HTTP 500, got nil")
 	}
@@ -603,20 +534,13 @@ func TestClient_GetComments_HTTPError(t *testing.T) {
 
 func TestClient_GetComments_BadJSON(t *testing.T) {
 	cfg := &config.Config{
-		Bitbucket: struct {
-			Domain       string   `yaml:"domain"`
-			Port         int      `yaml:"port"`
-			Workspace    string   `yaml:"workspace"`
-			User         string   `yaml:"user"`
-			AppPassword  string   `yaml:"app_password"`
-			Repositories []string `yaml:"repositories"`
-		}{},
+		Bitbucket: config.BitbucketConfig{},
 	}
 	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(200)
 		w.Write([]byte("not json"))
 	}, cfg)
-	_, err := client.GetComments("repo1", 1)
+	_, err := client.GetComments(context.Background(), "repo1", 1)
 	if err == nil {
 		t.Error("Expected error for bad JSON, got nil")
 	}
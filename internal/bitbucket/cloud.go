@@ -0,0 +1,328 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"fc-pr-tracker/internal/config"
+	"fc-pr-tracker/pkg/models"
+)
+
+// DefaultCloudBaseURL is the Bitbucket Cloud API root used when none is configured
+const DefaultCloudBaseURL = "https://api.bitbucket.org"
+
+// CloudClient represents a Bitbucket Cloud (2.0 API) client
+type CloudClient struct {
+	Config  *config.Config
+	Client  *http.Client
+	BaseURL string
+	Auth    Authenticator // built from Config.BitbucketCloud on first use when nil
+
+	authOnce sync.Once
+	retrier
+}
+
+// NewCloudClient creates a new Bitbucket Cloud client
+func NewCloudClient(cfg *config.Config) *CloudClient {
+	baseURL := strings.TrimRight(cfg.BitbucketCloud.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultCloudBaseURL
+	}
+	return &CloudClient{
+		Config:  cfg,
+		Client:  &http.Client{Timeout: 15 * time.Second},
+		BaseURL: baseURL,
+	}
+}
+
+// Name identifies the provider in logs
+func (c *CloudClient) Name() string {
+	return "bitbucket-cloud"
+}
+
+// Host returns the Bitbucket Cloud API host the client talks to
+func (c *CloudClient) Host() string {
+	if u, err := url.Parse(c.BaseURL); err == nil {
+		return u.Host
+	}
+	return c.BaseURL
+}
+
+// TestConnection checks if the Bitbucket Cloud API is reachable and credentials are valid
+func (c *CloudClient) TestConnection(ctx context.Context) error {
+	url := fmt.Sprintf("%s/2.0/repositories/%s?pagelen=1", c.BaseURL, c.Config.BitbucketCloud.Workspace)
+	if err := c.get(ctx, url, nil); err != nil {
+		return fmt.Errorf("Bitbucket Cloud connection test failed: %v", err)
+	}
+	return nil
+}
+
+// ListOpenPRs fetches open PRs for a repository
+func (c *CloudClient) ListOpenPRs(ctx context.Context, repo string) ([]models.PullRequest, error) {
+	var prs []models.PullRequest
+	url := fmt.Sprintf("%s/pullrequests?state=OPEN", c.repoURL(repo))
+
+	for url != "" {
+		var page cloudPage[cloudPullRequest]
+		if err := c.get(ctx, url, &page); err != nil {
+			return nil, fmt.Errorf("error fetching PRs: %v", err)
+		}
+		for _, pr := range page.Values {
+			prs = append(prs, pr.toModel())
+		}
+		url = page.Next
+	}
+	return prs, nil
+}
+
+// GetParticipants fetches PR participants (reviewers) from the PR detail resource
+func (c *CloudClient) GetParticipants(ctx context.Context, repo string, prID int) ([]models.Participant, error) {
+	url := fmt.Sprintf("%s/pullrequests/%d", c.repoURL(repo), prID)
+	slog.Info("Fetching participants for PR", "pr_id", prID, "repo", repo, "url", url)
+
+	var pr cloudPullRequest
+	if err := c.get(ctx, url, &pr); err != nil {
+		return nil, fmt.Errorf("error fetching participants: %v", err)
+	}
+
+	participants := make([]models.Participant, 0, len(pr.Participants))
+	for _, p := range pr.Participants {
+		participants = append(participants, p.toModel())
+	}
+	return participants, nil
+}
+
+// GetComments fetches the activity log (comments, approvals and updates) for a PR
+func (c *CloudClient) GetComments(ctx context.Context, repo string, prID int) ([]models.Comment, error) {
+	var comments []models.Comment
+	url := fmt.Sprintf("%s/pullrequests/%d/activity", c.repoURL(repo), prID)
+	slog.Info("Fetching comments/activities for PR", "pr_id", prID, "repo", repo, "url", url)
+
+	for url != "" {
+		var page cloudPage[cloudActivity]
+		if err := c.get(ctx, url, &page); err != nil {
+			return nil, fmt.Errorf("error fetching comments/activities: %v", err)
+		}
+		for _, a := range page.Values {
+			if comment, ok := a.toModel(); ok {
+				comments = append(comments, comment)
+			}
+		}
+		url = page.Next
+	}
+	return comments, nil
+}
+
+// repoURL returns the REST URL of repo, given either as a slug of the configured
+// workspace or as "workspace/slug"
+func (c *CloudClient) repoURL(repo string) string {
+	workspace := c.Config.BitbucketCloud.Workspace
+	if i := strings.Index(repo, "/"); i >= 0 {
+		workspace, repo = repo[:i], repo[i+1:]
+	}
+	return fmt.Sprintf("%s/2.0/repositories/%s/%s", c.BaseURL, workspace, repo)
+}
+
+// authenticate adds the configured credentials to req
+func (c *CloudClient) authenticate(req *http.Request) error {
+	c.authOnce.Do(func() {
+		if c.Auth == nil {
+			b := c.Config.BitbucketCloud
+			if b.Auth.TokenURL == "" {
+				b.Auth.TokenURL = DefaultCloudTokenURL
+			}
+			c.Auth = NewAuthenticator(b.Auth, b.User, b.AppPassword, c.Client)
+		}
+	})
+	return c.Auth.Authenticate(req)
+}
+
+// get performs an authenticated GET request and decodes the JSON body into out (if not nil)
+func (c *CloudClient) get(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	if err := c.authenticate(req); err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.sendAuthenticated(c.Client, c.Config.BitbucketCloud.Retry, c.Auth, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		slog.Error("Bitbucket Cloud request failed", "status", resp.Status, "url", url, "body", string(body))
+		return fmt.Errorf("%s (URL: %s, Body: %s)", resp.Status, url, string(body))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
+
+// Response types for the Bitbucket Cloud 2.0 API
+type cloudPage[T any] struct {
+	Values []T    `json:"values"`
+	Next   string `json:"next"`
+}
+
+type cloudUser struct {
+	DisplayName string `json:"display_name"`
+	Nickname    string `json:"nickname"`
+	AccountID   string `json:"account_id"`
+	UUID        string `json:"uuid"`
+	Type        string `json:"type"`
+}
+
+type cloudPullRequest struct {
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	State       string    `json:"state"`
+	CreatedOn   string    `json:"created_on"`
+	UpdatedOn   string    `json:"updated_on"`
+	Author      cloudUser `json:"author"`
+	Destination struct {
+		Branch struct {
+			Name string `json:"name"`
+		} `json:"branch"`
+	} `json:"destination"`
+	Links struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+	Participants []cloudParticipant `json:"participants"`
+}
+
+type cloudParticipant struct {
+	User     cloudUser `json:"user"`
+	Role     string    `json:"role"`
+	Approved bool      `json:"approved"`
+	State    string    `json:"state"`
+}
+
+type cloudActivity struct {
+	Comment *struct {
+		ID      int `json:"id"`
+		Content struct {
+			Raw string `json:"raw"`
+		} `json:"content"`
+		CreatedOn string    `json:"created_on"`
+		UpdatedOn string    `json:"updated_on"`
+		User      cloudUser `json:"user"`
+	} `json:"comment"`
+	Approval *struct {
+		Date string    `json:"date"`
+		User cloudUser `json:"user"`
+	} `json:"approval"`
+	Update *struct {
+		Date   string    `json:"date"`
+		Author cloudUser `json:"author"`
+	} `json:"update"`
+}
+
+// toModel maps a Cloud pull request into the shared model
+func (p cloudPullRequest) toModel() models.PullRequest {
+	pr := models.PullRequest{
+		ID:          p.ID,
+		Title:       p.Title,
+		Description: p.Description,
+		State:       p.State,
+		Open:        p.State == "OPEN",
+		Closed:      p.State != "OPEN",
+		CreatedDate: parseCloudTime(p.CreatedOn),
+		UpdatedDate: parseCloudTime(p.UpdatedOn),
+	}
+	pr.Author.User.DisplayName = p.Author.DisplayName
+	pr.Author.User.Username = p.Author.Nickname
+	pr.ToRef.DisplayID = p.Destination.Branch.Name
+	pr.Author.Role = "AUTHOR"
+	if p.Links.HTML.Href != "" {
+		pr.Links.Self = append(pr.Links.Self, struct {
+			Href string `json:"href"`
+		}{Href: p.Links.HTML.Href})
+	}
+	for _, participant := range p.Participants {
+		pr.Participants = append(pr.Participants, participant.toModel())
+	}
+	return pr
+}
+
+// toModel maps a Cloud participant into the shared model, using the Server role and status names
+func (p cloudParticipant) toModel() models.Participant {
+	var participant models.Participant
+	participant.User.DisplayName = p.User.DisplayName
+	participant.User.Username = p.User.Nickname
+	participant.User.Slug = p.User.Nickname
+	participant.User.Type = p.User.Type
+	participant.User.Active = true
+	participant.Role = strings.ToUpper(p.Role)
+	participant.Approved = p.Approved
+
+	switch {
+	case p.Approved:
+		participant.Status = "APPROVED"
+	case p.State == "changes_requested":
+		participant.Status = "NEEDS_WORK"
+	default:
+		participant.Status = "UNAPPROVED"
+	}
+	return participant
+}
+
+// toModel maps an activity entry into a Comment; ok is false for entries without a date
+func (a cloudActivity) toModel() (models.Comment, bool) {
+	var comment models.Comment
+	switch {
+	case a.Comment != nil:
+		comment.ID = a.Comment.ID
+		comment.Content = a.Comment.Content.Raw
+		comment.CreatedDate = parseCloudTime(a.Comment.CreatedOn)
+		comment.UpdatedDate = parseCloudTime(a.Comment.UpdatedOn)
+		comment.User.DisplayName = a.Comment.User.DisplayName
+		comment.User.Username = a.Comment.User.Nickname
+	case a.Approval != nil:
+		comment.CreatedDate = parseCloudTime(a.Approval.Date)
+		comment.UpdatedDate = comment.CreatedDate
+		comment.User.DisplayName = a.Approval.User.DisplayName
+		comment.User.Username = a.Approval.User.Nickname
+	case a.Update != nil:
+		comment.CreatedDate = parseCloudTime(a.Update.Date)
+		comment.UpdatedDate = comment.CreatedDate
+		comment.User.DisplayName = a.Update.Author.DisplayName
+		comment.User.Username = a.Update.Author.Nickname
+	default:
+		return comment, false
+	}
+	if comment.UpdatedDate == 0 {
+		comment.UpdatedDate = comment.CreatedDate
+	}
+	return comment, comment.UpdatedDate != 0
+}
+
+// parseCloudTime converts a Cloud ISO-8601 timestamp into Unix milliseconds (0 if invalid)
+func parseCloudTime(value string) int64 {
+	if value == "" {
+		return 0
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		slog.Debug("Could not parse Bitbucket Cloud timestamp", "value", value, "error", err)
+		return 0
+	}
+	return t.UnixMilli()
+}
@@ -0,0 +1,186 @@
+package bitbucket
+
+import (
+	"context"
+	"fc-pr-tracker/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCloudClient(handler http.HandlerFunc) *CloudClient {
+	ts := httptest.NewServer(handler)
+	cfg := &config.Config{
+		BitbucketCloud: config.BitbucketCloudConfig{
+			Workspace:   "test-workspace",
+			User:        "testuser",
+			AppPassword: "testpass",
+		},
+	}
+	return &CloudClient{Config: cfg, Client: ts.Client(), BaseURL: ts.URL}
+}
+
+func TestNewCloudClient_DefaultBaseURL(t *testing.T) {
+	client := NewCloudClient(&config.Config{})
+	if client.BaseURL != DefaultCloudBaseURL {
+		t.Errorf("Expected base URL '%s', got '%s'", DefaultCloudBaseURL, client.BaseURL)
+	}
+
+	cfg := &config.Config{BitbucketCloud: config.BitbucketCloudConfig{BaseURL: "https://bb.example.com/"}}
+	client = NewCloudClient(cfg)
+	if client.BaseURL != "https://bb.example.com" {
+		t.Errorf("Expected configured base URL without trailing slash, got '%s'", client.BaseURL)
+	}
+}
+
+func TestCloudClient_RepoURL(t *testing.T) {
+	client := newTestCloudClient(func(w http.ResponseWriter, r *http.Request) {})
+	if got := client.repoURL("api"); got != client.BaseURL+"/2.0/repositories/test-workspace/api" {
+		t.Errorf("Expected the configured workspace, got %s", got)
+	}
+	if got := client.repoURL("other-workspace/api"); got != client.BaseURL+"/2.0/repositories/other-workspace/api" {
+		t.Errorf("Expected the workspace of the repository, got %s", got)
+	}
+}
+
+func TestCloudClient_TestConnection(t *testing.T) {
+	client := newTestCloudClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2.0/repositories/test-workspace" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "testuser" || pass != "testpass" {
+			t.Errorf("Expected basic auth testuser/testpass, got %s/%s", user, pass)
+		}
+		w.Write([]byte(`{"values":[]}`))
+	})
+	if err := client.TestConnection(context.Background()); err != nil {
+		t.Errorf("Expected success, got error: %v", err)
+	}
+
+	failing := newTestCloudClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+	})
+	if err := failing.TestConnection(context.Background()); err == nil {
+		t.Error("Expected error for unauthorized, got nil")
+	}
+}
+
+func TestCloudClient_ListOpenPRs_Pagination(t *testing.T) {
+	var serverURL string
+	client := newTestCloudClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2.0/repositories/test-workspace/repo1/pullrequests" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`{"values":[{"id":2,"title":"PR2","state":"OPEN","created_on":"2024-01-02T10:00:00.000000+00:00","updated_on":"2024-01-03T10:00:00+00:00"}]}`))
+			return
+		}
+		w.Write([]byte(`{"values":[{"id":1,"title":"PR1","state":"OPEN",
+			"created_on":"2024-01-01T10:00:00.123456+00:00","updated_on":"2024-01-01T12:00:00+00:00",
+			"author":{"display_name":"Jane Doe","nickname":"jdoe"},"destination":{"branch":{"name":"develop"}},
+			"links":{"html":{"href":"https://bitbucket.org/test-workspace/repo1/pull-requests/1"}}}],
+			"next":"` + serverURL + `/2.0/repositories/test-workspace/repo1/pullrequests?state=OPEN&page=2"}`))
+	})
+	serverURL = client.BaseURL
+
+	prs, err := client.ListOpenPRs(context.Background(), "repo1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(prs) != 2 {
+		t.Fatalf("Expected 2 PRs, got %+v", prs)
+	}
+	pr := prs[0]
+	if pr.ID != 1 || pr.Title != "PR1" || !pr.Open {
+		t.Errorf("Unexpected PR mapping: %+v", pr)
+	}
+	if pr.Author.User.DisplayName != "Jane Doe" || pr.Author.User.Username != "jdoe" {
+		t.Errorf("Unexpected author mapping: %+v", pr.Author)
+	}
+	if pr.TargetBranch() != "develop" {
+		t.Errorf("Expected target branch develop, got %q", pr.TargetBranch())
+	}
+	if len(pr.Links.Self) != 1 || pr.Links.Self[0].Href != "https://bitbucket.org/test-workspace/repo1/pull-requests/1" {
+		t.Errorf("Unexpected link mapping: %+v", pr.Links)
+	}
+	expectedUpdated := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).UnixMilli()
+	if pr.UpdatedDate != expectedUpdated {
+		t.Errorf("Expected updated date %d, got %d", expectedUpdated, pr.UpdatedDate)
+	}
+}
+
+func TestCloudClient_ListOpenPRs_HTTPError(t *testing.T) {
+	client := newTestCloudClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	})
+	if _, err := client.ListOpenPRs(context.Background(), "repo1"); err == nil {
+		t.Error("Expected error for HTTP 500, got nil")
+	}
+}
+
+func TestCloudClient_GetParticipants(t *testing.T) {
+	client := newTestCloudClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2.0/repositories/test-workspace/repo1/pullrequests/7" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"id":7,"participants":[
+			{"user":{"display_name":"Rev One","nickname":"rev1"},"role":"REVIEWER","approved":true,"state":"approved"},
+			{"user":{"display_name":"Rev Two","nickname":"rev2"},"role":"REVIEWER","approved":false,"state":"changes_requested"},
+			{"user":{"display_name":"Someone","nickname":"someone"},"role":"PARTICIPANT","approved":false,"state":null}]}`))
+	})
+
+	participants, err := client.GetParticipants(context.Background(), "repo1", 7)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(participants) != 3 {
+		t.Fatalf("Expected 3 participants, got %d", len(participants))
+	}
+	if participants[0].Status != "APPROVED" || !participants[0].Approved {
+		t.Errorf("Expected first participant approved, got %+v", participants[0])
+	}
+	if participants[1].Status != "NEEDS_WORK" {
+		t.Errorf("Expected NEEDS_WORK status, got %s", participants[1].Status)
+	}
+	if participants[2].Role != "PARTICIPANT" {
+		t.Errorf("Expected PARTICIPANT role, got %s", participants[2].Role)
+	}
+
+	approved, total := CountApprovals(participants)
+	if approved != 1 || total != 2 {
+		t.Errorf("Expected 1/2 approvals, got %d/%d", approved, total)
+	}
+}
+
+func TestCloudClient_GetComments(t *testing.T) {
+	client := newTestCloudClient(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/pullrequests/7/activity") {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"values":[
+			{"comment":{"id":10,"content":{"raw":"LGTM"},"created_on":"2024-01-05T10:00:00+00:00","updated_on":"2024-01-05T11:00:00+00:00","user":{"display_name":"Rev One"}}},
+			{"approval":{"date":"2024-01-06T10:00:00+00:00","user":{"display_name":"Rev One"}}},
+			{"update":{"date":"2024-01-04T10:00:00+00:00","author":{"display_name":"Jane Doe"}}},
+			{"unknown":{}}]}`))
+	})
+
+	comments, err := client.GetComments(context.Background(), "repo1", 7)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(comments) != 3 {
+		t.Fatalf("Expected 3 activities, got %d", len(comments))
+	}
+	if comments[0].ID != 10 || comments[0].Content != "LGTM" {
+		t.Errorf("Unexpected comment mapping: %+v", comments[0])
+	}
+
+	lastActivity := GetLastActivity(cloudPullRequest{CreatedOn: "2024-01-01T10:00:00+00:00", UpdatedOn: "2024-01-01T10:00:00+00:00"}.toModel(), comments)
+	expected := time.Date(2024, 1, 6, 10, 0, 0, 0, time.UTC).Local().Format(time.RFC3339)
+	if lastActivity != expected {
+		t.Errorf("Expected last activity %s, got %s", expected, lastActivity)
+	}
+}
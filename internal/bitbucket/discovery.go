@@ -0,0 +1,164 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// discoveryPageSize is the number of repositories requested per page
+const discoveryPageSize = 100
+
+// RepoListResponse is a page of /rest/api/1.0/projects/{key}/repos
+type RepoListResponse struct {
+	Values        []ProjectRepo `json:"values"`
+	IsLastPage    bool          `json:"isLastPage"`
+	NextPageStart int           `json:"nextPageStart"`
+}
+
+// ProjectRepo is a repository as listed by the projects API
+type ProjectRepo struct {
+	Slug     string `json:"slug"`
+	Archived bool   `json:"archived"`
+	Project  struct {
+		Key string `json:"key"`
+	} `json:"project"`
+}
+
+// ListProjectRepos fetches every repository of a Bitbucket Server project, following pagination
+func (c *Client) ListProjectRepos(ctx context.Context, project string) ([]ProjectRepo, error) {
+	var repos []ProjectRepo
+	start := 0
+	for {
+		url := c.apiURL(fmt.Sprintf("/rest/api/1.0/projects/%s/repos?start=%d&limit=%d", project, start, discoveryPageSize))
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.authenticate(req); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.sendAuthenticated(c.Client, c.Config.Bitbucket.Retry, c.Auth, req)
+		if err != nil {
+			return nil, err
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != 200 {
+			return nil, fmt.Errorf("error listing repositories: %s (URL: %s, Body: %s)", resp.Status, url, string(body))
+		}
+
+		var page RepoListResponse
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		repos = append(repos, page.Values...)
+		if page.IsLastPage || len(page.Values) == 0 || page.NextPageStart <= start {
+			return repos, nil
+		}
+		start = page.NextPageStart
+	}
+}
+
+// DiscoverRepositories enumerates the repositories of the configured discovery projects,
+// applying the include/exclude patterns and skipping archived repositories.
+// Repositories of the default workspace are named by slug, the others "PROJECT/slug".
+func (c *Client) DiscoverRepositories(ctx context.Context) ([]string, error) {
+	d := c.Config.Bitbucket.Discovery
+	if len(d.Projects) == 0 {
+		return nil, nil
+	}
+	include, err := compilePatterns(d.Include)
+	if err != nil {
+		return nil, fmt.Errorf("invalid discovery include pattern: %v", err)
+	}
+	exclude, err := compilePatterns(d.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid discovery exclude pattern: %v", err)
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, project := range d.Projects {
+		repos, err := c.ListProjectRepos(ctx, project)
+		if err != nil {
+			return nil, fmt.Errorf("error discovering repositories of project %s: %v", project, err)
+		}
+		for _, r := range repos {
+			key := r.Project.Key
+			if key == "" {
+				key = project
+			}
+			full := key + "/" + r.Slug
+			switch {
+			case r.Archived && !d.IncludeArchived:
+				slog.Debug("Skipping archived repository", "repo", full)
+				continue
+			case len(include) > 0 && !matchAny(include, r.Slug, full):
+				continue
+			case matchAny(exclude, r.Slug, full):
+				continue
+			}
+
+			name := full
+			if strings.EqualFold(key, c.Config.Bitbucket.Workspace) {
+				name = r.Slug
+			}
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	slog.Info("Discovered repositories", "projects", d.Projects, "count", len(names))
+	return names, nil
+}
+
+// repoPattern is a compiled include/exclude pattern: a glob, or a regular expression when prefixed with "re:"
+type repoPattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+func compilePatterns(patterns []string) ([]repoPattern, error) {
+	var compiled []repoPattern
+	for _, p := range patterns {
+		if expr, ok := strings.CutPrefix(p, "re:"); ok {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, err
+			}
+			compiled = append(compiled, repoPattern{re: re})
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("%q: %v", p, err)
+		}
+		compiled = append(compiled, repoPattern{glob: p})
+	}
+	return compiled, nil
+}
+
+// matchAny reports whether any pattern matches any of the names
+func matchAny(patterns []repoPattern, names ...string) bool {
+	for _, p := range patterns {
+		for _, name := range names {
+			if p.re != nil && p.re.MatchString(name) {
+				return true
+			}
+			if p.re == nil {
+				if ok, _ := path.Match(p.glob, name); ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
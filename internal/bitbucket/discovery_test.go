@@ -0,0 +1,111 @@
+package bitbucket
+
+import (
+	"context"
+	"fc-pr-tracker/internal/config"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestClient_DiscoverRepositories(t *testing.T) {
+	cfg := &config.Config{Bitbucket: config.BitbucketConfig{
+		Workspace: "CORE",
+		Discovery: config.DiscoveryConfig{
+			Projects: []string{"CORE", "WEB"},
+			Include:  []string{"*-service", "WEB/*"},
+			Exclude:  []string{"re:^legacy-"},
+		},
+	}}
+	var starts []string
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/projects/CORE/repos"):
+			starts = append(starts, r.URL.Query().Get("start"))
+			if r.URL.Query().Get("start") == "0" {
+				w.Write([]byte(`{"values":[
+					{"slug":"billing-service","project":{"key":"CORE"}},
+					{"slug":"legacy-service","project":{"key":"CORE"}},
+					{"slug":"tools","project":{"key":"CORE"}}
+				],"isLastPage":false,"nextPageStart":3}`))
+				return
+			}
+			w.Write([]byte(`{"values":[
+				{"slug":"old-service","archived":true,"project":{"key":"CORE"}},
+				{"slug":"auth-service","project":{"key":"CORE"}}
+			],"isLastPage":true}`))
+		case strings.HasSuffix(r.URL.Path, "/projects/WEB/repos"):
+			w.Write([]byte(`{"values":[{"slug":"frontend","project":{"key":"WEB"}}],"isLastPage":true}`))
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+			w.WriteHeader(404)
+		}
+	}, cfg)
+
+	names, err := client.DiscoverRepositories(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"billing-service", "auth-service", "WEB/frontend"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+	if strings.Join(starts, ",") != "0,3" {
+		t.Errorf("Expected pages starting at 0 and 3, got %v", starts)
+	}
+}
+
+func TestClient_DiscoverRepositories_IncludeArchived(t *testing.T) {
+	cfg := &config.Config{Bitbucket: config.BitbucketConfig{
+		Discovery: config.DiscoveryConfig{Projects: []string{"CORE"}, IncludeArchived: true},
+	}}
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"values":[{"slug":"old","archived":true,"project":{"key":"CORE"}}],"isLastPage":true}`))
+	}, cfg)
+
+	names, err := client.DiscoverRepositories(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(names) != 1 || names[0] != "CORE/old" {
+		t.Errorf("Expected [CORE/old], got %v", names)
+	}
+}
+
+func TestClient_DiscoverRepositories_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		discovery config.DiscoveryConfig
+		status    int
+	}{
+		{"invalid regex", config.DiscoveryConfig{Projects: []string{"CORE"}, Include: []string{"re:("}}, 200},
+		{"invalid glob", config.DiscoveryConfig{Projects: []string{"CORE"}, Exclude: []string{"["}}, 200},
+		{"API error", config.DiscoveryConfig{Projects: []string{"CORE"}}, 403},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Bitbucket: config.BitbucketConfig{Discovery: tt.discovery}}
+			client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"values":[],"isLastPage":true}`))
+			}, cfg)
+			if _, err := client.DiscoverRepositories(context.Background()); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestClient_RepoURL(t *testing.T) {
+	cfg := &config.Config{Bitbucket: config.BitbucketConfig{Domain: "git.example.com", Port: 443, Workspace: "CORE"}}
+	client := &Client{Config: cfg}
+
+	if got := client.repoURL("api"); got != "https://git.example.com:443/rest/api/1.0/projects/CORE/repos/api" {
+		t.Errorf("Unexpected URL for workspace repo: %s", got)
+	}
+	if got := client.repoURL("WEB/frontend"); got != "https://git.example.com:443/rest/api/1.0/projects/WEB/repos/frontend" {
+		t.Errorf("Unexpected URL for project repo: %s", got)
+	}
+}
@@ -0,0 +1,170 @@
+package bitbucket
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"fc-pr-tracker/internal/config"
+)
+
+// Default retry policy, used for every retry setting left at zero
+const (
+	DefaultMaxAttempts    = 3
+	DefaultInitialBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff     = 30 * time.Second
+)
+
+// RetryStats summarizes the retry activity of a client
+type RetryStats struct {
+	Retried   int64 // attempts repeated after a transient failure
+	Abandoned int64 // calls that still failed after the last attempt
+}
+
+// retrier adds retries with exponential backoff and jitter to HTTP calls.
+// Its zero value is ready to use.
+type retrier struct {
+	retried   atomic.Int64
+	abandoned atomic.Int64
+	sleep     func(time.Duration) // overridden in tests
+}
+
+// TakeRetryStats returns the retry activity since the previous call and resets the counters
+func (r *retrier) TakeRetryStats() RetryStats {
+	return RetryStats{
+		Retried:   r.retried.Swap(0),
+		Abandoned: r.abandoned.Swap(0),
+	}
+}
+
+// do sends req, retrying network errors, 429 and 502/503/504 responses according to policy.
+// The last response is returned as is, so callers keep handling non-200 statuses themselves.
+// Retries stop as soon as the request context is done.
+func (r *retrier) do(client *http.Client, policy config.RetryConfig, req *http.Request) (*http.Response, error) {
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := client.Do(req)
+		if !isTransient(resp, err) || req.Context().Err() != nil {
+			return resp, err
+		}
+		if attempt >= maxAttempts {
+			r.abandoned.Add(1)
+			slog.Warn("Giving up on Bitbucket request", "url", req.URL.String(), "attempts", attempt, "error", err, "status", statusOf(resp))
+			return resp, err
+		}
+
+		wait := backoff(policy, attempt)
+		if resp != nil {
+			if hinted, ok := rateLimitWait(resp.Header, time.Now()); ok {
+				// A hint of zero or in the past means the server accepts a retry right away
+				wait = 0
+				if hinted > 0 {
+					wait = capBackoff(policy, hinted)
+				}
+			}
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		r.retried.Add(1)
+		slog.Info("Retrying Bitbucket request", "url", req.URL.String(), "attempt", attempt+1, "wait", wait, "error", err, "status", statusOf(resp))
+		if err := r.wait(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// wait pauses for d, returning early with the context error if ctx is done first
+func (r *retrier) wait(ctx context.Context, d time.Duration) error {
+	if r.sleep != nil {
+		r.sleep(d)
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isTransient reports whether a call failed in a way worth retrying
+func isTransient(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the exponential delay before the given retry, with up to 50% jitter
+func backoff(policy config.RetryConfig, attempt int) time.Duration {
+	initial := time.Duration(policy.InitialBackoffMS) * time.Millisecond
+	if initial <= 0 {
+		initial = DefaultInitialBackoff
+	}
+	delay := capBackoff(policy, initial<<uint(attempt-1))
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// capBackoff limits a delay to the configured maximum backoff. Non-positive delays, which
+// come from overflowing shifts, are capped too.
+func capBackoff(policy config.RetryConfig, delay time.Duration) time.Duration {
+	max := time.Duration(policy.MaxBackoffMS) * time.Millisecond
+	if max <= 0 {
+		max = DefaultMaxBackoff
+	}
+	if delay <= 0 || delay > max {
+		return max
+	}
+	return delay
+}
+
+// rateLimitWait derives the wait requested by the server from Retry-After (seconds or
+// HTTP date), X-RateLimit-Reset (Unix seconds) or Bitbucket Data Center's token bucket
+// headers (X-RateLimit-Interval-Seconds / X-RateLimit-Fill-Rate)
+func rateLimitWait(h http.Header, now time.Time) (time.Duration, bool) {
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return t.Sub(now), true
+		}
+	}
+	if v := h.Get("X-RateLimit-Reset"); v != "" {
+		if epoch, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(epoch, 0).Sub(now), true
+		}
+	}
+	interval, errInterval := strconv.ParseFloat(h.Get("X-RateLimit-Interval-Seconds"), 64)
+	fillRate, errFill := strconv.ParseFloat(h.Get("X-RateLimit-Fill-Rate"), 64)
+	if errInterval == nil && errFill == nil && fillRate > 0 {
+		// One token is refilled every interval/fillRate seconds
+		return time.Duration(interval / fillRate * float64(time.Second)), true
+	}
+	return 0, false
+}
+
+func statusOf(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
@@ -0,0 +1,202 @@
+package bitbucket
+
+import (
+	"context"
+	"fc-pr-tracker/internal/config"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClient_Retry_TransientThenSuccess(t *testing.T) {
+	cfg := &config.Config{Bitbucket: config.BitbucketConfig{Workspace: "test-workspace"}}
+	calls := 0
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(503)
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"values":[{"id":1,"title":"PR"}]}`))
+	}, cfg)
+	var waits []time.Duration
+	client.sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	prs, err := client.ListOpenPRs(context.Background(), "repo1")
+	if err != nil {
+		t.Fatalf("Expected success after retry, got %v", err)
+	}
+	if len(prs) != 1 {
+		t.Errorf("Expected 1 PR, got %d", len(prs))
+	}
+	if calls != 2 || len(waits) != 1 {
+		t.Errorf("Expected 2 calls and 1 backoff, got %d calls and %d waits", calls, len(waits))
+	}
+	if waits[0] < DefaultInitialBackoff/2 || waits[0] > DefaultInitialBackoff {
+		t.Errorf("Expected first backoff within [%v, %v], got %v", DefaultInitialBackoff/2, DefaultInitialBackoff, waits[0])
+	}
+
+	stats := client.TakeRetryStats()
+	if stats.Retried != 1 || stats.Abandoned != 0 {
+		t.Errorf("Expected 1 retried and 0 abandoned, got %+v", stats)
+	}
+	if stats = client.TakeRetryStats(); stats.Retried != 0 {
+		t.Errorf("Expected counters to be reset, got %+v", stats)
+	}
+}
+
+func TestClient_Retry_HonorsRetryAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		expected time.Duration
+	}{
+		{"Retry-After seconds", "Retry-After", "7", 7 * time.Second},
+		{"Retry-After zero", "Retry-After", "0", 0},
+		{"Retry-After date in the past", "Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0},
+		{"Reset in the past", "X-RateLimit-Reset", "1704110405", 0},
+		{"Retry-After beyond the maximum backoff", "Retry-After", "3600", DefaultMaxBackoff},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Bitbucket: config.BitbucketConfig{Workspace: "test-workspace"}}
+			calls := 0
+			client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls == 1 {
+					w.Header().Set(tt.header, tt.value)
+					w.WriteHeader(429)
+					return
+				}
+				w.Write([]byte(`{"values":[]}`))
+			}, cfg)
+			var waits []time.Duration
+			client.sleep = func(d time.Duration) { waits = append(waits, d) }
+
+			if _, err := client.GetParticipants(context.Background(), "repo1", 1); err != nil {
+				t.Fatalf("Expected success after rate limit, got %v", err)
+			}
+			if len(waits) != 1 || waits[0] != tt.expected {
+				t.Errorf("Expected a single %v wait, got %v", tt.expected, waits)
+			}
+		})
+	}
+}
+
+func TestClient_Retry_Abandoned(t *testing.T) {
+	cfg := &config.Config{Bitbucket: config.BitbucketConfig{
+		Workspace: "test-workspace",
+		Retry:     config.RetryConfig{MaxAttempts: 4, InitialBackoffMS: 10, MaxBackoffMS: 20},
+	}}
+	calls := 0
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(502)
+	}, cfg)
+	var waits []time.Duration
+	client.sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	if _, err := client.GetComments(context.Background(), "repo1", 1); err == nil {
+		t.Fatal("Expected error after exhausting retries, got nil")
+	}
+	if calls != 4 {
+		t.Errorf("Expected 4 attempts, got %d", calls)
+	}
+	for _, w := range waits {
+		if w > 20*time.Millisecond {
+			t.Errorf("Expected backoff capped at 20ms, got %v", w)
+		}
+	}
+
+	stats := client.TakeRetryStats()
+	if stats.Retried != 3 || stats.Abandoned != 1 {
+		t.Errorf("Expected 3 retried and 1 abandoned, got %+v", stats)
+	}
+}
+
+func TestClient_Retry_NotForClientErrors(t *testing.T) {
+	cfg := &config.Config{Bitbucket: config.BitbucketConfig{Workspace: "test-workspace"}}
+	calls := 0
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(404)
+	}, cfg)
+	client.sleep = func(time.Duration) { t.Error("Did not expect a retry for 404") }
+
+	if _, err := client.GetParticipants(context.Background(), "repo1", 1); err == nil {
+		t.Error("Expected error for 404, got nil")
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single attempt, got %d", calls)
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected time.Duration
+		ok       bool
+	}{
+		{"Retry-After seconds", map[string]string{"Retry-After": "3"}, 3 * time.Second, true},
+		{"Retry-After date", map[string]string{"Retry-After": now.Add(10 * time.Second).Format(http.TimeFormat)}, 10 * time.Second, true},
+		{"Reset epoch", map[string]string{"X-RateLimit-Reset": "1704110405"}, 5 * time.Second, true},
+		{"Data Center token bucket", map[string]string{"X-RateLimit-Interval-Seconds": "1", "X-RateLimit-Fill-Rate": "4"}, 250 * time.Millisecond, true},
+		{"No hints", map[string]string{}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tt.headers {
+				h.Set(k, v)
+			}
+			wait, ok := rateLimitWait(h, now)
+			if ok != tt.ok || wait != tt.expected {
+				t.Errorf("Expected (%v, %v), got (%v, %v)", tt.expected, tt.ok, wait, ok)
+			}
+		})
+	}
+}
+
+func TestBackoff_Exponential(t *testing.T) {
+	policy := config.RetryConfig{InitialBackoffMS: 100, MaxBackoffMS: 1000}
+	for attempt, max := range map[int]time.Duration{1: 100, 2: 200, 3: 400, 4: 800, 5: 1000, 10: 1000} {
+		max *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			d := backoff(policy, attempt)
+			if d < max/2 || d > max {
+				t.Fatalf("Attempt %d: expected backoff within [%v, %v], got %v", attempt, max/2, max, d)
+			}
+		}
+	}
+}
+
+func TestClient_Retry_StopsWhenContextCancelled(t *testing.T) {
+	cfg := &config.Config{Bitbucket: config.BitbucketConfig{
+		Workspace: "test-workspace",
+		Retry:     config.RetryConfig{MaxAttempts: 5, InitialBackoffMS: 60000, MaxBackoffMS: 60000},
+	}}
+	calls := 0
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(503)
+	}, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.ListOpenPRs(ctx, "repo1")
+	if err == nil {
+		t.Fatal("Expected error when context is cancelled, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected cancellation to interrupt the backoff, took %v", elapsed)
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single attempt before cancellation, got %d", calls)
+	}
+}
@@ -0,0 +1,151 @@
+// Package calendar counts the time a pull request waited in business days, skipping
+// the days outside the workweek and holidays.
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dateLayout is the format of holiday dates in the configuration
+const dateLayout = "2006-01-02"
+
+// Date is a calendar day, independent of any time zone
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// DateOf returns the day of t in its own location
+func DateOf(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date{y, m, d}
+}
+
+// ParseDate parses a "YYYY-MM-DD" date
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(dateLayout, strings.TrimSpace(s))
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", s)
+	}
+	return DateOf(t), nil
+}
+
+// Holiday is a day off, or with Yearly the same day of every year from Date on,
+// up to and including the year of Until when it is set
+type Holiday struct {
+	Date   Date
+	Yearly bool
+	Until  Date
+}
+
+// weekdays maps the names accepted in a workweek to their day
+var weekdays = map[string]time.Weekday{
+	"SUN": time.Sunday, "MON": time.Monday, "TUE": time.Tuesday, "WED": time.Wednesday,
+	"THU": time.Thursday, "FRI": time.Friday, "SAT": time.Saturday,
+}
+
+// Workweek holds the working days, indexed by time.Weekday
+type Workweek [7]bool
+
+// MondayToFriday is the default workweek
+var MondayToFriday = Workweek{time.Monday: true, time.Tuesday: true, time.Wednesday: true, time.Thursday: true, time.Friday: true}
+
+// ParseWorkweek parses day names such as "MON" or "Monday"
+func ParseWorkweek(days []string) (Workweek, error) {
+	var w Workweek
+	for _, name := range days {
+		upper := strings.ToUpper(strings.TrimSpace(name))
+		day, ok := weekdays[upper]
+		if !ok && len(upper) > 3 {
+			day, ok = weekdays[upper[:3]]
+			ok = ok && upper == strings.ToUpper(day.String())
+		}
+		if !ok {
+			return Workweek{}, fmt.Errorf("invalid day %q, expected MON, TUE, WED, THU, FRI, SAT or SUN", name)
+		}
+		w[day] = true
+	}
+	if w == (Workweek{}) {
+		return Workweek{}, fmt.Errorf("the workweek has no working day")
+	}
+	return w, nil
+}
+
+// Calendar tells business days from weekends and holidays in a time zone.
+// A nil *Calendar counts every day.
+type Calendar struct {
+	location *time.Location
+	workweek Workweek
+	holidays map[Date]bool
+	yearly   []Holiday
+}
+
+// New returns a calendar of the working days of workweek, except holidays, evaluated in loc
+func New(loc *time.Location, workweek Workweek, holidays []Holiday) *Calendar {
+	if loc == nil {
+		loc = time.Local
+	}
+	c := &Calendar{location: loc, workweek: workweek, holidays: make(map[Date]bool)}
+	for _, h := range holidays {
+		if h.Yearly {
+			c.yearly = append(c.yearly, h)
+		} else {
+			c.holidays[h.Date] = true
+		}
+	}
+	return c
+}
+
+// isBusinessDay reports whether people work on the day starting at midnight t
+func (c *Calendar) isBusinessDay(t time.Time) bool {
+	if !c.workweek[t.Weekday()] {
+		return false
+	}
+	d := DateOf(t)
+	if c.holidays[d] {
+		return false
+	}
+	for _, h := range c.yearly {
+		if h.Date.Month == d.Month && h.Date.Day == d.Day && d.Year >= h.Date.Year &&
+			(h.Until == (Date{}) || d.Year <= h.Until.Year) {
+			return false
+		}
+	}
+	return true
+}
+
+// Days returns the whole days elapsed from from to to. A calendar only counts the time
+// spent on business days, so a PR updated on Friday evening is not a day old before
+// Monday evening; without a calendar every day counts.
+func (c *Calendar) Days(from, to time.Time) int {
+	if c == nil {
+		return int(to.Sub(from).Hours() / 24)
+	}
+	if !to.After(from) {
+		return 0
+	}
+
+	const daySeconds = 24 * 60 * 60
+	var seconds int64
+	y, m, d := from.In(c.location).Date()
+	for day := time.Date(y, m, d, 0, 0, 0, 0, c.location); day.Before(to); {
+		y, m, d = day.Date()
+		next := time.Date(y, m, d+1, 0, 0, 0, 0, c.location)
+		if c.isBusinessDay(day) {
+			start, end := day, next
+			if from.After(start) {
+				start = from
+			}
+			if to.Before(end) {
+				end = to
+			}
+			// Days made shorter or longer by daylight saving time still count as one day
+			seconds += int64(end.Sub(start)/time.Second) * daySeconds / int64(next.Sub(day)/time.Second)
+		}
+		day = next
+	}
+	return int(seconds / daySeconds)
+}
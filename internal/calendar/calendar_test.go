@@ -0,0 +1,157 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWorkweek(t *testing.T) {
+	w, err := ParseWorkweek([]string{"sun", "Monday", "TUE", "wed", "Thursday"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := Workweek{time.Sunday: true, time.Monday: true, time.Tuesday: true, time.Wednesday: true, time.Thursday: true}
+	if w != expected {
+		t.Errorf("Expected %v, got %v", expected, w)
+	}
+
+	for _, days := range [][]string{{"MON", "FUN"}, {"Mondays"}, {}} {
+		if _, err := ParseWorkweek(days); err == nil {
+			t.Errorf("Expected an error for %v", days)
+		}
+	}
+}
+
+func TestCalendar_Days(t *testing.T) {
+	// March 2026: the 20th is a Friday, the 21st and 22nd a weekend
+	at := func(day, hour int) time.Time {
+		return time.Date(2026, 3, day, hour, 0, 0, 0, time.UTC)
+	}
+	weekdays := New(time.UTC, MondayToFriday, nil)
+	holidays := New(time.UTC, MondayToFriday, []Holiday{
+		{Date: Date{2026, time.March, 23}},
+		{Date: Date{2020, time.March, 24}, Yearly: true},
+		{Date: Date{2020, time.March, 25}, Yearly: true, Until: Date{2025, time.March, 25}},
+	})
+
+	tests := []struct {
+		name     string
+		calendar *Calendar
+		from, to time.Time
+		expected int
+	}{
+		{"every day without a calendar", nil, at(20, 18), at(23, 9), 2},
+		{"weekend skipped", weekdays, at(20, 18), at(23, 9), 0},
+		{"Friday evening to Monday evening", weekdays, at(20, 18), at(23, 18), 1},
+		{"Friday evening to Wednesday evening", weekdays, at(20, 18), at(25, 18), 3},
+		{"within a day", weekdays, at(18, 9), at(18, 17), 0},
+		{"full week", weekdays, at(16, 0), at(23, 0), 5},
+		{"started on a weekend", weekdays, at(21, 10), at(24, 0), 1},
+		{"holidays", holidays, at(20, 18), at(26, 18), 2},
+		{"to before from", weekdays, at(23, 9), at(20, 18), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.calendar.Days(tt.from, tt.to); got != tt.expected {
+				t.Errorf("Expected %d days, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCalendar_DaysInTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skipf("Time zone database unavailable: %v", err)
+	}
+	c := New(loc, MondayToFriday, nil)
+
+	// From Thursday 23:00 to Sunday in São Paulo, Friday evening there is already Saturday in UTC
+	from := time.Date(2026, 3, 19, 23, 0, 0, 0, loc)
+	to := time.Date(2026, 3, 22, 12, 0, 0, 0, loc)
+	if got := c.Days(from, to); got != 1 {
+		t.Errorf("Expected 1 day, got %d", got)
+	}
+	if got := New(time.UTC, MondayToFriday, nil).Days(from, to); got != 0 {
+		t.Errorf("Expected 0 days in UTC, got %d", got)
+	}
+}
+
+func TestCalendar_DaysAcrossDaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Lisbon")
+	if err != nil {
+		t.Skipf("Time zone database unavailable: %v", err)
+	}
+	every := New(loc, Workweek{true, true, true, true, true, true, true}, nil)
+
+	// Sunday March 29th 2026 has 23 hours in Lisbon and still counts as one day
+	from := time.Date(2026, 3, 28, 12, 0, 0, 0, loc)
+	if got := every.Days(from, time.Date(2026, 3, 30, 12, 0, 0, 0, loc)); got != 2 {
+		t.Errorf("Expected 2 days, got %d", got)
+	}
+}
+
+const testICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20261224\r\n" +
+	"DTEND;VALUE=DATE:20261226\r\n" +
+	"SUMMARY:Christmas\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20200501\r\n" +
+	"RRULE:FREQ=YEARLY;\r\n" +
+	" COUNT=10\r\n" +
+	"SUMMARY:Labour Day\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;TZID=Europe/Lisbon:20260610T090000\r\n" +
+	"DTEND;TZID=Europe/Lisbon:20260610T180000\r\n" +
+	"SUMMARY:Portugal Day\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	holidays, err := ParseICS(strings.NewReader(testICS))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []Holiday{
+		{Date: Date{2026, time.December, 24}},
+		{Date: Date{2026, time.December, 25}},
+		{Date: Date{2020, time.May, 1}, Yearly: true, Until: Date{2029, time.May, 1}},
+		{Date: Date{2026, time.June, 10}},
+	}
+	if len(holidays) != len(expected) {
+		t.Fatalf("Expected %d holidays, got %v", len(expected), holidays)
+	}
+	for i := range expected {
+		if holidays[i] != expected[i] {
+			t.Errorf("Holiday %d: expected %v, got %v", i, expected[i], holidays[i])
+		}
+	}
+}
+
+func TestParseICS_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		ics     string
+		message string
+	}{
+		{"missing start", "BEGIN:VEVENT\nSUMMARY:x\nEND:VEVENT\n", "DTSTART"},
+		{"invalid end", "BEGIN:VEVENT\nDTSTART:20260101\nDTEND:2026\nEND:VEVENT\n", "DTEND"},
+		{"weekly rule", "BEGIN:VEVENT\nDTSTART:20260101\nRRULE:FREQ=WEEKLY\nEND:VEVENT\n", "unsupported RRULE"},
+		{"unbalanced", "END:VEVENT\n", "without BEGIN:VEVENT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseICS(strings.NewReader(tt.ics))
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected an error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
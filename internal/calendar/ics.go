@@ -0,0 +1,156 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadICS reads the holidays of an iCalendar (.ics) file
+func LoadICS(path string) ([]Holiday, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading holiday calendar: %v", err)
+	}
+	defer f.Close()
+
+	holidays, err := ParseICS(f)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing holiday calendar %s: %v", path, err)
+	}
+	return holidays, nil
+}
+
+// ParseICS turns every event of an iCalendar stream into holidays: all the days from
+// DTSTART up to DTEND, which is exclusive for all-day events. Events repeated with
+// RRULE:FREQ=YEARLY, optionally bounded by UNTIL or COUNT, are holidays every year.
+func ParseICS(r io.Reader) ([]Holiday, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var holidays []Holiday
+	var event map[string]string
+	for i, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// Parameters such as ";VALUE=DATE" or ";TZID=..." do not matter for whole days
+		name, _, _ = strings.Cut(strings.ToUpper(name), ";")
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			event = make(map[string]string)
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if event == nil {
+				return nil, fmt.Errorf("line %d: END:VEVENT without BEGIN:VEVENT", i+1)
+			}
+			days, err := eventHolidays(event)
+			if err != nil {
+				return nil, fmt.Errorf("event ending on line %d: %v", i+1, err)
+			}
+			holidays = append(holidays, days...)
+			event = nil
+		case event != nil:
+			event[name] = strings.TrimSpace(value)
+		}
+	}
+	return holidays, nil
+}
+
+// unfoldLines splits the content lines, joining the continuation lines that start with a space or tab
+func unfoldLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// eventHolidays returns the days covered by one event
+func eventHolidays(event map[string]string) ([]Holiday, error) {
+	start, err := parseICSDate(event["DTSTART"])
+	if err != nil {
+		return nil, fmt.Errorf("DTSTART: %v", err)
+	}
+	end := start.AddDate(0, 0, 1)
+	if raw, ok := event["DTEND"]; ok {
+		if end, err = parseICSDate(raw); err != nil {
+			return nil, fmt.Errorf("DTEND: %v", err)
+		}
+		// Timed events end on the day they end, all-day events the day before DTEND
+		if len(raw) > 8 {
+			end = end.AddDate(0, 0, 1)
+		}
+	}
+
+	yearly, until, err := parseRecurrence(event["RRULE"], start)
+	if err != nil {
+		return nil, err
+	}
+	var holidays []Holiday
+	for day := start; day.Before(end) || day.Equal(start); day = day.AddDate(0, 0, 1) {
+		holidays = append(holidays, Holiday{Date: DateOf(day), Yearly: yearly, Until: until})
+	}
+	return holidays, nil
+}
+
+// parseICSDate parses the day of a DATE (20261225) or DATE-TIME (20261225T090000Z) value
+func parseICSDate(s string) (time.Time, error) {
+	if len(s) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	t, err := time.Parse("20060102", s[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return t, nil
+}
+
+// parseRecurrence accepts no rule or a yearly one, returning the last year it applies to
+func parseRecurrence(rule string, start time.Time) (bool, Date, error) {
+	if rule == "" {
+		return false, Date{}, nil
+	}
+	parts := make(map[string]string)
+	for _, part := range strings.Split(rule, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		parts[strings.ToUpper(key)] = value
+	}
+	if !strings.EqualFold(parts["FREQ"], "YEARLY") {
+		return false, Date{}, fmt.Errorf("unsupported RRULE %q, only FREQ=YEARLY is", rule)
+	}
+	for key := range parts {
+		if key != "FREQ" && key != "UNTIL" && key != "COUNT" {
+			return false, Date{}, fmt.Errorf("unsupported RRULE %q, only FREQ=YEARLY with UNTIL or COUNT is", rule)
+		}
+	}
+
+	var until Date
+	if raw, ok := parts["UNTIL"]; ok {
+		t, err := parseICSDate(raw)
+		if err != nil {
+			return false, Date{}, fmt.Errorf("RRULE UNTIL: %v", err)
+		}
+		until = DateOf(t)
+	}
+	if raw, ok := parts["COUNT"]; ok {
+		count, err := strconv.Atoi(raw)
+		if err != nil || count < 1 {
+			return false, Date{}, fmt.Errorf("RRULE COUNT: invalid count %q", raw)
+		}
+		until = DateOf(start.AddDate(count-1, 0, 0))
+	}
+	return true, until, nil
+}
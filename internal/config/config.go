@@ -1,79 +1,440 @@
 package config
 
 import (
-	"io/ioutil"
-	"log"
-	"log/slog"
-	"strings"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"fc-pr-tracker/internal/calendar"
+	"fc-pr-tracker/internal/schedule"
 
 	"gopkg.in/yaml.v3"
 )
 
+// DefaultWorkers is the number of parallel API calls used when concurrency.workers is not set
+const DefaultWorkers = 4
+
 // Config represents the application configuration
 type Config struct {
-	Bitbucket struct {
-		Domain       string   `yaml:"domain"`
-		Port         int      `yaml:"port"`
-		Workspace    string   `yaml:"workspace"`
-		User         string   `yaml:"user"`
-		AppPassword  string   `yaml:"app_password"`
-		Repositories []string `yaml:"repositories"`
-	} `yaml:"bitbucket"`
-	PRFilter struct {
-		IgnoreKeywords []string `yaml:"ignore_keywords"`
-		StaleAfterDays int      `yaml:"stale_after_days"`
-	} `yaml:"pr_filter"`
-	Notifiers struct {
-		SMTP struct {
-			Host     string   `yaml:"host"`
-			Port     int      `yaml:"port"`
-			User     string   `yaml:"user"`
-			Password string   `yaml:"password"`
-			From     string   `yaml:"from"`
-			To       []string `yaml:"to"`
-		} `yaml:"smtp"`
-		Teams struct {
-			WebhookURL string `yaml:"webhook_url"`
-		} `yaml:"teams"`
-	} `yaml:"notifiers"`
-	Log struct {
-		File       string `yaml:"file"`
-		Level      string `yaml:"level"`
-		Format     string `yaml:"format"`
-		MaxSizeMB  int    `yaml:"max_size_mb"`
-		MaxBackups int    `yaml:"max_backups"`
-		MaxAgeDays int    `yaml:"max_age_days"`
-		Compress   bool   `yaml:"compress"`
-		Stdout     bool   `yaml:"stdout"`
-	} `yaml:"log"`
-	Notification struct {
-		IntervalHours int `yaml:"interval_hours"`
-	} `yaml:"notification"`
-}
-
-// Load reads and parses the configuration file
-func Load(path string) *Config {
-	var config Config
-	data, err := ioutil.ReadFile(path)
+	Bitbucket      BitbucketConfig      `yaml:"bitbucket"`
+	BitbucketCloud BitbucketCloudConfig `yaml:"bitbucket_cloud"`
+	GitHub         GitHubConfig         `yaml:"github"`
+	GitLab         GitLabConfig         `yaml:"gitlab"`
+	PRFilter       PRFilterConfig       `yaml:"pr_filter"`
+	Notifiers      NotifiersConfig      `yaml:"notifiers"`
+	Escalation     EscalationConfig     `yaml:"escalation"`
+	Log            LogConfig            `yaml:"log"`
+	Notification   NotificationConfig   `yaml:"notification"`
+	Concurrency    ConcurrencyConfig    `yaml:"concurrency"`
+}
+
+// BitbucketConfig holds the Bitbucket Server/Data Center settings
+type BitbucketConfig struct {
+	Domain       string          `yaml:"domain"`
+	Port         int             `yaml:"port"`
+	Workspace    string          `yaml:"workspace"`
+	User         string          `yaml:"user"`
+	AppPassword  string          `yaml:"app_password"`
+	Repositories []string        `yaml:"repositories"`
+	Discovery    DiscoveryConfig `yaml:"discovery"`
+	Auth         AuthConfig      `yaml:"auth"`
+	Retry        RetryConfig     `yaml:"retry"`
+	// Targets adds further projects, each optionally on its own server and with its own credentials
+	Targets []BitbucketTarget `yaml:"targets"`
+}
+
+// BitbucketTarget is a Bitbucket Server project to track. Server and credential settings
+// left empty are inherited from the bitbucket section.
+type BitbucketTarget struct {
+	Project      string     `yaml:"project"`
+	Repositories []string   `yaml:"repositories"` // empty tracks every repository of the project
+	Domain       string     `yaml:"domain"`
+	Port         int        `yaml:"port"`
+	User         string     `yaml:"user"`
+	AppPassword  string     `yaml:"app_password"`
+	Auth         AuthConfig `yaml:"auth"`
+}
+
+// DiscoveryConfig enumerates the repositories of Bitbucket projects instead of listing them by hand.
+// Patterns are globs, or regular expressions when prefixed with "re:", matched against both the
+// repository slug and "PROJECT/slug".
+type DiscoveryConfig struct {
+	Projects        []string `yaml:"projects"`         // project keys whose repositories are tracked
+	Include         []string `yaml:"include"`          // repositories to keep; empty keeps all
+	Exclude         []string `yaml:"exclude"`          // repositories to drop, applied after include
+	IncludeArchived bool     `yaml:"include_archived"` // archived repositories are skipped by default
+}
+
+// AuthConfig selects how Bitbucket API requests are authenticated
+type AuthConfig struct {
+	Type         string   `yaml:"type"`          // basic (default, user + app_password), bearer or oauth2
+	Token        string   `yaml:"token"`         // bearer: personal / HTTP / repository access token
+	ClientID     string   `yaml:"client_id"`     // oauth2: consumer key
+	ClientSecret string   `yaml:"client_secret"` // oauth2: consumer secret
+	TokenURL     string   `yaml:"token_url"`     // oauth2: token endpoint, defaults to bitbucket.org's on Cloud
+	Scopes       []string `yaml:"scopes"`        // oauth2: optional scopes to request
+}
+
+// RetryConfig controls how transient API failures (network errors, 429, 502-504) are retried
+type RetryConfig struct {
+	MaxAttempts      int `yaml:"max_attempts"`       // total attempts per call, default 3
+	InitialBackoffMS int `yaml:"initial_backoff_ms"` // first backoff, doubled on every retry, default 500
+	MaxBackoffMS     int `yaml:"max_backoff_ms"`     // upper bound for backoff and Retry-After waits, default 30000
+}
+
+// BitbucketCloudConfig holds the Bitbucket Cloud (bitbucket.org) settings
+type BitbucketCloudConfig struct {
+	BaseURL      string      `yaml:"base_url"` // defaults to https://api.bitbucket.org
+	Workspace    string      `yaml:"workspace"`
+	User         string      `yaml:"user"`
+	AppPassword  string      `yaml:"app_password"`
+	Repositories []string    `yaml:"repositories"`
+	Auth         AuthConfig  `yaml:"auth"`
+	Retry        RetryConfig `yaml:"retry"`
+}
+
+// GitHubConfig holds the GitHub / GitHub Enterprise settings
+type GitHubConfig struct {
+	BaseURL      string   `yaml:"base_url"` // defaults to https://api.github.com
+	Token        string   `yaml:"token"`
+	Owner        string   `yaml:"owner"` // used for repositories listed without "owner/"
+	Repositories []string `yaml:"repositories"`
+}
+
+// GitLabConfig holds the GitLab (gitlab.com or self-hosted) settings
+type GitLabConfig struct {
+	BaseURL      string   `yaml:"base_url"` // defaults to https://gitlab.com
+	Token        string   `yaml:"token"`
+	Group        string   `yaml:"group"` // used for projects listed without "group/"
+	Repositories []string `yaml:"repositories"`
+}
+
+// PRFilterConfig holds the rules used to select stale PRs
+type PRFilterConfig struct {
+	IgnoreKeywords []string           `yaml:"ignore_keywords"`
+	StaleAfterDays int                `yaml:"stale_after_days"`
+	BusinessDays   BusinessDaysConfig `yaml:"business_days"`
+	// MinApprovals makes PRs with that many approvals never stale; 0 requires every reviewer's
+	MinApprovals int `yaml:"min_approvals"`
+	// Overrides change the settings above for some projects, repositories or target branches
+	Overrides []PRFilterOverride `yaml:"overrides"`
+}
+
+// BusinessDaysConfig makes stale_after_days count business days only, skipping the days
+// outside the workweek and holidays
+type BusinessDaysConfig struct {
+	Enabled  bool     `yaml:"enabled"`
+	Workweek []string `yaml:"workweek"` // working days, MON to FRI by default
+	// Timezone is the IANA zone the days are counted in; notification.timezone by default
+	Timezone string   `yaml:"timezone"`
+	Holidays []string `yaml:"holidays"` // YYYY-MM-DD dates
+	// HolidayCalendar is the path of an iCalendar (.ics) file whose events are holidays
+	HolidayCalendar string `yaml:"holiday_calendar"`
+}
+
+// NotifiersConfig holds the settings of every notification channel
+type NotifiersConfig struct {
+	SMTP    SMTPConfig    `yaml:"smtp"`
+	Teams   TeamsConfig   `yaml:"teams"`
+	Slack   SlackConfig   `yaml:"slack"`
+	Digests DigestsConfig `yaml:"digests"`
+}
+
+// configured reports whether at least one notification channel is set
+func (n NotifiersConfig) configured() bool {
+	return n.sendsEmail() || n.Teams.WebhookURL != "" || n.Slack.Enabled()
+}
+
+// sendsEmail reports whether the broadcast email or the digests go through SMTP
+func (n NotifiersConfig) sendsEmail() bool {
+	return len(n.SMTP.To) > 0 || n.Digests.Enabled()
+}
+
+// SMTPConfig holds the email notifier settings
+type SMTPConfig struct {
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port"`
+	User     string   `yaml:"user"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+	// Templates replace the built-in subject and bodies of the email
+	Templates EmailTemplatesConfig `yaml:"templates"`
+}
+
+// EmailTemplatesConfig points the email notifier at user templates, executed with the data
+// and helpers documented in internal/templates. A text body without an HTML body is sent
+// as plain text only; an HTML body without a text body keeps the built-in text alternative.
+type EmailTemplatesConfig struct {
+	Subject string `yaml:"subject"` // inline text/template, e.g. "{{.TotalPRs}} stale PRs"
+	Text    string `yaml:"text"`    // text/template file of the plain-text body
+	HTML    string `yaml:"html"`    // html/template file of the HTML body
+}
+
+// DigestsConfig sends personal emails through the SMTP server: every reviewer who has not
+// approved gets the stale PRs waiting on them, every author their own stale PRs.
+// Recipients are read from the participant and author email addresses the provider returns.
+type DigestsConfig struct {
+	Reviewers bool     `yaml:"reviewers"`
+	Authors   bool     `yaml:"authors"`
+	OptOut    []string `yaml:"opt_out"` // emails or usernames that receive no digest
+	// MaxRecipients caps the digests of a tier; in the notifiers section it also caps those of
+	// the whole cycle, escalation tiers included. 0 for no limit.
+	MaxRecipients int `yaml:"max_recipients"`
+}
+
+// Enabled reports whether reviewers or authors receive digests
+func (d DigestsConfig) Enabled() bool {
+	return d.Reviewers || d.Authors
+}
+
+// TeamsConfig holds the Microsoft Teams notifier settings
+type TeamsConfig struct {
+	WebhookURL string `yaml:"webhook_url"`
+	// Format is the payload posted to the webhook: message_card (default) for Office 365
+	// connectors, adaptive_card for Power Automate Workflows webhooks
+	Format string `yaml:"format"`
+	// Template is a text/template file rendering the JSON payload posted to the webhook
+	Template string `yaml:"template"`
+}
+
+// Teams payload formats
+const (
+	TeamsMessageCard  = "message_card"
+	TeamsAdaptiveCard = "adaptive_card"
+)
+
+// SlackConfig holds the Slack notifier settings: an incoming webhook, or a bot token
+// posting to a channel with chat.postMessage
+type SlackConfig struct {
+	WebhookURL string `yaml:"webhook_url"`
+	Token      string `yaml:"token"`    // bot token (xoxb-...) with the chat:write scope
+	Channel    string `yaml:"channel"`  // channel ID or name the bot posts to
+	BaseURL    string `yaml:"base_url"` // Web API URL, defaults to https://slack.com/api
+}
+
+// Enabled reports whether a webhook or a bot token is configured
+func (s SlackConfig) Enabled() bool {
+	return s.WebhookURL != "" || s.Token != ""
+}
+
+// EscalationConfig sends the PRs that stay stale longer to further recipients
+type EscalationConfig struct {
+	Tiers []EscalationTier `yaml:"tiers"`
+}
+
+// EscalationTier receives the stale PRs inactive for AfterDays or more, unless a later tier does
+type EscalationTier struct {
+	Name      string `yaml:"name"`
+	AfterDays int    `yaml:"after_days"`
+	// Notifiers are the only channels the tier notifies; the SMTP server settings, templates and
+	// Teams format it leaves empty are those of the notifiers section
+	Notifiers NotifiersConfig `yaml:"notifiers"`
+}
+
+// LogConfig holds the logging settings
+type LogConfig struct {
+	File       string `yaml:"file"`
+	Level      string `yaml:"level"`
+	Format     string `yaml:"format"`
+	MaxSizeMB  int    `yaml:"max_size_mb"`
+	MaxBackups int    `yaml:"max_backups"`
+	MaxAgeDays int    `yaml:"max_age_days"`
+	Compress   bool   `yaml:"compress"`
+	Stdout     bool   `yaml:"stdout"`
+}
+
+// NotificationConfig holds the notification scheduling settings
+type NotificationConfig struct {
+	// IntervalHours is the time between two cycles, used when Schedule is empty
+	IntervalHours int `yaml:"interval_hours"`
+	// Schedule is a cron expression, such as "0 9 * * MON-FRI", replacing interval_hours
+	Schedule string `yaml:"schedule"`
+	// Timezone is the IANA zone of the schedule and quiet hours; the server's zone by default
+	Timezone   string           `yaml:"timezone"`
+	QuietHours QuietHoursConfig `yaml:"quiet_hours"`
+	// SkipWeekends postpones the cycles due on Saturday and Sunday to Monday
+	SkipWeekends bool `yaml:"skip_weekends"`
+	// CycleTimeoutMinutes bounds the time spent fetching PRs in one cycle (0 = no deadline)
+	CycleTimeoutMinutes int `yaml:"cycle_timeout_minutes"`
+}
+
+// QuietHoursConfig is a daily period, in "HH:MM" local time, during which cycles are postponed.
+// It may span midnight, e.g. 20:00 to 08:00.
+type QuietHoursConfig struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// Location returns the time zone of the notification schedule
+func (n NotificationConfig) Location() (*time.Location, error) {
+	if n.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(n.Timezone)
+}
+
+// NewSchedule builds the schedule of the monitoring cycles: the cron expression, or else
+// every interval_hours, postponed out of quiet hours and weekends
+func (n NotificationConfig) NewSchedule() (*schedule.Schedule, error) {
+	loc, err := n.Location()
+	if err != nil {
+		return nil, err
+	}
+	s := &schedule.Schedule{
+		Trigger:      schedule.Every(time.Duration(n.IntervalHours) * time.Hour),
+		Location:     loc,
+		SkipWeekends: n.SkipWeekends,
+	}
+	if n.Schedule != "" {
+		if s.Trigger, err = schedule.ParseCron(n.Schedule, loc); err != nil {
+			return nil, err
+		}
+	}
+	if n.QuietHours.Start != "" || n.QuietHours.End != "" {
+		if s.Quiet, err = schedule.ParseQuietHours(n.QuietHours.Start, n.QuietHours.End); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// ForTier returns a copy of the configuration whose notifiers section is that of tier t
+func (c *Config) ForTier(t EscalationTier) *Config {
+	derived := *c
+	smtp := t.Notifiers.SMTP
+	if smtp.Host == "" {
+		smtp.Host, smtp.Port = c.Notifiers.SMTP.Host, c.Notifiers.SMTP.Port
+	}
+	if smtp.Port == 0 {
+		smtp.Port = c.Notifiers.SMTP.Port
+	}
+	if smtp.User == "" && smtp.Password == "" {
+		smtp.User, smtp.Password = c.Notifiers.SMTP.User, c.Notifiers.SMTP.Password
+	}
+	if smtp.From == "" {
+		smtp.From = c.Notifiers.SMTP.From
+	}
+	if smtp.Templates == (EmailTemplatesConfig{}) {
+		smtp.Templates = c.Notifiers.SMTP.Templates
+	}
+	teams := t.Notifiers.Teams
+	if teams.Template == "" {
+		teams.Template = c.Notifiers.Teams.Template
+	}
+	if teams.Format == "" {
+		teams.Format = c.Notifiers.Teams.Format
+	}
+	derived.Notifiers = NotifiersConfig{SMTP: smtp, Teams: teams, Slack: t.Notifiers.Slack, Digests: t.Notifiers.Digests}
+	return &derived
+}
+
+// NewCalendar builds the calendar stale PRs are counted with, or returns nil when
+// business days are disabled and every day counts
+func (c *Config) NewCalendar() (*calendar.Calendar, error) {
+	b := c.PRFilter.BusinessDays
+	if !b.Enabled {
+		return nil, nil
+	}
+	loc, err := c.Notification.Location()
+	if b.Timezone != "" {
+		loc, err = time.LoadLocation(b.Timezone)
+	}
 	if err != nil {
-		log.Fatalf("Error reading configuration file: %v", err)
+		return nil, err
+	}
+	workweek := calendar.MondayToFriday
+	if len(b.Workweek) > 0 {
+		if workweek, err = calendar.ParseWorkweek(b.Workweek); err != nil {
+			return nil, err
+		}
+	}
+	var holidays []calendar.Holiday
+	for _, h := range b.Holidays {
+		d, err := calendar.ParseDate(h)
+		if err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, calendar.Holiday{Date: d})
+	}
+	if b.HolidayCalendar != "" {
+		loaded, err := calendar.LoadICS(b.HolidayCalendar)
+		if err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, loaded...)
+	}
+	return calendar.New(loc, workweek, holidays), nil
+}
+
+// ConcurrencyConfig limits how many API calls run in parallel during a cycle
+type ConcurrencyConfig struct {
+	Workers int `yaml:"workers"`  // parallel API calls across all repositories
+	PerHost int `yaml:"per_host"` // parallel API calls against a single host
+}
+
+// ForTarget returns a copy of the configuration whose bitbucket section describes target t,
+// so a Bitbucket client can serve it unchanged
+func (c *Config) ForTarget(t BitbucketTarget) *Config {
+	derived := *c
+	b := c.Bitbucket
+	b.Workspace = t.Project
+	b.Repositories = t.Repositories
+	b.Discovery.Projects = nil
+	if len(t.Repositories) == 0 {
+		b.Discovery.Projects = []string{t.Project}
+	}
+	if t.Domain != "" {
+		b.Domain = t.Domain
 	}
+	if t.Port != 0 {
+		b.Port = t.Port
+	}
+	if t.User != "" || t.AppPassword != "" || t.Auth.Type != "" {
+		b.User, b.AppPassword, b.Auth = t.User, t.AppPassword, t.Auth
+	}
+	b.Targets = nil
+	derived.Bitbucket = b
+	return &derived
+}
 
-	err = yaml.Unmarshal(data, &config)
+// Load reads the configuration file, expands ${VAR} references, reads *_file settings,
+// applies the PRTRACKER_* environment overrides and the defaults, and validates the result.
+// Unknown keys and invalid settings are all reported at once in a *ValidationError.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		log.Fatalf("Error parsing YAML: %v", err)
+		return nil, fmt.Errorf("error reading configuration file: %v", err)
+	}
+
+	var config Config
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing YAML in %s: %v", path, err)
+	}
+	// Expand ${VAR} references and read *_file settings before decoding
+	var ps problems
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		resolveNode(doc.Content[0], reflect.TypeOf(config), "", &ps)
+	}
+	ps = append(ps, unknownKeys(&doc)...)
+
+	// An empty file decodes to nothing, and is then reported by the validation
+	if doc.Kind != 0 {
+		if err := doc.Decode(&config); err != nil {
+			return nil, fmt.Errorf("error parsing YAML in %s: %v", path, err)
+		}
 	}
 
-	// Trim spaces from Bitbucket user and app_password if not empty
-	originalUser := config.Bitbucket.User
-	originalPass := config.Bitbucket.AppPassword
-	config.Bitbucket.User = strings.TrimSpace(config.Bitbucket.User)
-	config.Bitbucket.AppPassword = strings.TrimSpace(config.Bitbucket.AppPassword)
-	if config.Bitbucket.User != originalUser {
-		slog.Debug("Trimmed spaces from Bitbucket user in config.")
+	ps = append(ps, config.applyEnv()...)
+	config.applyDefaults()
+	config.PRFilter.compileSelectors()
+	if err := config.Validate(); err != nil {
+		ps = append(ps, err.(*ValidationError).Problems...)
 	}
-	if config.Bitbucket.AppPassword != originalPass {
-		slog.Debug("Trimmed spaces from Bitbucket app_password in config.")
+	if err := ps.err(); err != nil {
+		return nil, err
 	}
-	return &config
+	return &config, nil
 }
@@ -2,9 +2,25 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
+// requiredSettings completes test configurations that only exercise a provider section
+const requiredSettings = `
+pr_filter:
+  stale_after_days: 7
+notification:
+  interval_hours: 24
+notifiers:
+  smtp:
+    host: "smtp.example.com"
+    from: "tracker@example.com"
+    to: ["team@example.com"]
+`
+
 func TestLoad_ValidConfig(t *testing.T) {
 	// Create a temporary config file
 	configContent := `
@@ -58,7 +74,10 @@ notification:
 	defer os.Remove(tempFile)
 
 	// Test loading the config
-	config := Load(tempFile)
+	config, err := Load(tempFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 
 	// Verify Bitbucket configuration
 	if config.Bitbucket.Domain != "bitbucket.org" {
@@ -132,7 +151,7 @@ bitbucket:
   app_password: "  test-password  "
   repositories:
     - "repo1"
-`
+` + requiredSettings
 
 	tempFile := "test_config_trim.yaml"
 	err := os.WriteFile(tempFile, []byte(configContent), 0644)
@@ -141,7 +160,10 @@ bitbucket:
 	}
 	defer os.Remove(tempFile)
 
-	config := Load(tempFile)
+	config, err := Load(tempFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
 
 	// Verify that spaces are trimmed
 	if config.Bitbucket.User != "test-user" {
@@ -152,16 +174,171 @@ bitbucket:
 	}
 }
 
+func TestLoad_BitbucketCloud(t *testing.T) {
+	configContent := `
+bitbucket_cloud:
+  workspace: "my-team"
+  user: " cloud-user "
+  app_password: "cloud-password"
+  repositories:
+    - "cloud-repo1"
+    - "cloud-repo2"
+` + requiredSettings
+
+	tempFile := "test_config_cloud.yaml"
+	err := os.WriteFile(tempFile, []byte(configContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer os.Remove(tempFile)
+
+	config, err := Load(tempFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if config.BitbucketCloud.Workspace != "my-team" {
+		t.Errorf("Expected cloud workspace 'my-team', got '%s'", config.BitbucketCloud.Workspace)
+	}
+	if config.BitbucketCloud.User != "cloud-user" {
+		t.Errorf("Expected trimmed cloud user 'cloud-user', got '%s'", config.BitbucketCloud.User)
+	}
+	if len(config.BitbucketCloud.Repositories) != 2 {
+		t.Errorf("Expected 2 cloud repositories, got %d", len(config.BitbucketCloud.Repositories))
+	}
+	if len(config.Bitbucket.Repositories) != 0 {
+		t.Errorf("Expected no Bitbucket Server repositories, got %d", len(config.Bitbucket.Repositories))
+	}
+}
+
 func TestLoad_InvalidFile(t *testing.T) {
-	// Test loading a non-existent file
-	// This test is skipped because Load() uses log.Fatalf() which calls os.Exit(1)
-	// and cannot be tested in a unit test environment
-	t.Skip("Skipping test because Load() uses log.Fatalf() which calls os.Exit(1)")
+	_, err := Load("does_not_exist.yaml")
+	if err == nil || !strings.Contains(err.Error(), "error reading configuration file") {
+		t.Errorf("Expected a read error, got %v", err)
+	}
 }
 
 func TestLoad_InvalidYAML(t *testing.T) {
-	// Create a config file with invalid YAML
-	// This test is skipped because Load() uses log.Fatalf() which calls os.Exit(1)
-	// and cannot be tested in a unit test environment
-	t.Skip("Skipping test because Load() uses log.Fatalf() which calls os.Exit(1)")
+	tempFile := "test_config_invalid.yaml"
+	if err := os.WriteFile(tempFile, []byte("bitbucket:\n  domain: [unclosed\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer os.Remove(tempFile)
+
+	_, err := Load(tempFile)
+	if err == nil || !strings.Contains(err.Error(), "error parsing YAML") {
+		t.Errorf("Expected a parse error, got %v", err)
+	}
+}
+
+func TestConfig_ForTarget(t *testing.T) {
+	cfg := &Config{
+		Bitbucket: BitbucketConfig{
+			Domain:       "git.example.com",
+			Port:         443,
+			Workspace:    "CORE",
+			User:         "shared-user",
+			AppPassword:  "shared-password",
+			Repositories: []string{"api"},
+			Targets: []BitbucketTarget{
+				{Project: "WEB", Repositories: []string{"frontend"}},
+				{Project: "OPS", Domain: "ops.example.com", Auth: AuthConfig{Type: "bearer", Token: "ops-token"}},
+			},
+		},
+		PRFilter: PRFilterConfig{StaleAfterDays: 3},
+	}
+
+	web := cfg.ForTarget(cfg.Bitbucket.Targets[0])
+	if web.Bitbucket.Workspace != "WEB" || web.Bitbucket.Domain != "git.example.com" || web.Bitbucket.User != "shared-user" {
+		t.Errorf("Expected WEB target to inherit server and credentials, got %+v", web.Bitbucket)
+	}
+	if len(web.Bitbucket.Repositories) != 1 || len(web.Bitbucket.Discovery.Projects) != 0 || len(web.Bitbucket.Targets) != 0 {
+		t.Errorf("Expected only the WEB repositories, got %+v", web.Bitbucket)
+	}
+	if web.PRFilter.StaleAfterDays != 3 {
+		t.Errorf("Expected the other sections to be kept")
+	}
+
+	ops := cfg.ForTarget(cfg.Bitbucket.Targets[1])
+	if ops.Bitbucket.Domain != "ops.example.com" || ops.Bitbucket.Port != 443 {
+		t.Errorf("Expected OPS target on its own server, got %s:%d", ops.Bitbucket.Domain, ops.Bitbucket.Port)
+	}
+	if ops.Bitbucket.User != "" || ops.Bitbucket.Auth.Token != "ops-token" {
+		t.Errorf("Expected OPS target to use its own credentials, got %+v", ops.Bitbucket)
+	}
+	if len(ops.Bitbucket.Discovery.Projects) != 1 || ops.Bitbucket.Discovery.Projects[0] != "OPS" {
+		t.Errorf("Expected a target without repositories to discover its project, got %v", ops.Bitbucket.Discovery.Projects)
+	}
+
+	if cfg.Bitbucket.Workspace != "CORE" {
+		t.Errorf("Expected the original configuration to be left untouched")
+	}
+}
+
+func TestConfig_NewCalendar(t *testing.T) {
+	cfg := &Config{}
+	if cal, err := cfg.NewCalendar(); cal != nil || err != nil {
+		t.Errorf("Expected no calendar when business days are disabled, got %v, %v", cal, err)
+	}
+
+	ics := filepath.Join(t.TempDir(), "holidays.ics")
+	content := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART;VALUE=DATE:20260323\nEND:VEVENT\nEND:VCALENDAR\n"
+	if err := os.WriteFile(ics, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write calendar: %v", err)
+	}
+	cfg.Notification.Timezone = "UTC"
+	cfg.PRFilter.BusinessDays = BusinessDaysConfig{Enabled: true, Holidays: []string{"2026-03-24"}, HolidayCalendar: ics}
+	cal, err := cfg.NewCalendar()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// From Friday evening to Wednesday evening, Monday and Tuesday being holidays
+	from := time.Date(2026, 3, 20, 18, 0, 0, 0, time.UTC)
+	if got := cal.Days(from, time.Date(2026, 3, 25, 18, 0, 0, 0, time.UTC)); got != 1 {
+		t.Errorf("Expected 1 business day, got %d", got)
+	}
+
+	cfg.PRFilter.BusinessDays.HolidayCalendar = filepath.Join(t.TempDir(), "missing.ics")
+	if _, err := cfg.NewCalendar(); err == nil {
+		t.Error("Expected an error for a missing holiday calendar")
+	}
+}
+
+func TestConfig_ForTier(t *testing.T) {
+	cfg := &Config{
+		Notifiers: NotifiersConfig{
+			SMTP:  SMTPConfig{Host: "smtp.example.com", Port: 587, User: "tracker", Password: "secret", From: "tracker@example.com", To: []string{"team@example.com"}},
+			Teams: TeamsConfig{WebhookURL: "https://teams.example.com/team"},
+		},
+	}
+
+	managers := cfg.ForTier(EscalationTier{Name: "Managers", AfterDays: 14, Notifiers: NotifiersConfig{
+		SMTP: SMTPConfig{To: []string{"managers@example.com"}},
+	}})
+	smtp := managers.Notifiers.SMTP
+	if smtp.Host != "smtp.example.com" || smtp.Port != 587 || smtp.User != "tracker" || smtp.From != "tracker@example.com" {
+		t.Errorf("Expected the tier to inherit the SMTP server, got %+v", smtp)
+	}
+	if len(smtp.To) != 1 || smtp.To[0] != "managers@example.com" || managers.Notifiers.Teams.WebhookURL != "" {
+		t.Errorf("Expected only the tier's channels, got %+v", managers.Notifiers)
+	}
+
+	relay := cfg.ForTier(EscalationTier{Notifiers: NotifiersConfig{SMTP: SMTPConfig{Host: "relay.example.com", To: []string{"x@example.com"}}}})
+	if relay.Notifiers.SMTP.Host != "relay.example.com" || relay.Notifiers.SMTP.Port != 587 {
+		t.Errorf("Expected the tier's own host with the inherited port, got %+v", relay.Notifiers.SMTP)
+	}
+	if cfg.Notifiers.SMTP.To[0] != "team@example.com" {
+		t.Errorf("Expected the original configuration to be left untouched")
+	}
+
+	cfg.Notifiers.SMTP.Templates = EmailTemplatesConfig{Text: "team.txt"}
+	cfg.Notifiers.Teams.Template = "team.json"
+	own := cfg.ForTier(EscalationTier{Notifiers: NotifiersConfig{
+		SMTP:  SMTPConfig{To: []string{"x@example.com"}, Templates: EmailTemplatesConfig{HTML: "leads.html"}},
+		Teams: TeamsConfig{WebhookURL: "https://teams.example.com/leads"},
+	}})
+	if own.Notifiers.SMTP.Templates != (EmailTemplatesConfig{HTML: "leads.html"}) || own.Notifiers.Teams.Template != "team.json" {
+		t.Errorf("Expected the tier's email templates and the inherited Teams template, got %+v", own.Notifiers)
+	}
 }
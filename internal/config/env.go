@@ -0,0 +1,225 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variables that override configuration settings,
+// e.g. PRTRACKER_BITBUCKET_APP_PASSWORD for bitbucket.app_password
+const EnvPrefix = "PRTRACKER_"
+
+// fileSuffix marks settings read from a file, e.g. app_password_file or PRTRACKER_NOTIFIERS_SMTP_PASSWORD_FILE
+const fileSuffix = "_file"
+
+// envReference matches ${VAR} and ${VAR:-default}; $${ escapes a literal ${
+var envReference = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// interpolate replaces the environment references in s. Variables that are not set
+// and have no default are reported in missing.
+func interpolate(s string) (result string, missing []string) {
+	result = envReference.ReplaceAllStringFunc(s, func(ref string) string {
+		if strings.HasPrefix(ref, "$$") {
+			return ref[1:]
+		}
+		m := envReference.FindStringSubmatch(ref)
+		if v, ok := os.LookupEnv(m[1]); ok {
+			return v
+		}
+		if m[2] != "" {
+			return m[3]
+		}
+		missing = append(missing, m[1])
+		return ""
+	})
+	return result, missing
+}
+
+// readSecret reads a setting from a file, as mounted by Kubernetes or Docker secrets.
+// The trailing newline most editors add is dropped.
+func readSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// resolveNode expands the environment references of every scalar and replaces every
+// "<key>_file: path" entry by "<key>: <file contents>"
+func resolveNode(node *yaml.Node, t reflect.Type, prefix string, ps *problems) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "${") {
+			return
+		}
+		value, missing := interpolate(node.Value)
+		for _, name := range missing {
+			ps.add(prefix, "environment variable %s is not set (line %d)", name, node.Line)
+		}
+		node.Value = value
+		// Keep string settings literal, so a "null" or "~" value is not dropped, but let the
+		// others resolve again, so "${PORT}" still decodes as an int
+		node.Tag = ""
+		if t.Kind() == reflect.String {
+			node.Tag = "!!str"
+		}
+	case yaml.MappingNode:
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fields[key.Value]
+			if !ok {
+				field, ok = fileField(fields, key.Value)
+				if ok {
+					resolveFileEntry(key, value, joinPath(prefix, key.Value), ps)
+				}
+			}
+			if ok {
+				resolveNode(value, field, joinPath(prefix, key.Value), ps)
+			}
+		}
+	case yaml.SequenceNode:
+		if t.Kind() != reflect.Slice {
+			return
+		}
+		for i, item := range node.Content {
+			resolveNode(item, t.Elem(), fmt.Sprintf("%s[%d]", prefix, i), ps)
+		}
+	}
+}
+
+// fileField returns the string setting that key reads from a file, if key is "<setting>_file"
+func fileField(fields map[string]reflect.Type, key string) (reflect.Type, bool) {
+	name, ok := strings.CutSuffix(key, fileSuffix)
+	if !ok {
+		return nil, false
+	}
+	field, ok := fields[name]
+	if !ok || field.Kind() != reflect.String {
+		return nil, false
+	}
+	return field, true
+}
+
+// resolveFileEntry turns "<key>_file: path" into "<key>: <file contents>"
+func resolveFileEntry(key, value *yaml.Node, p string, ps *problems) {
+	path, missing := interpolate(value.Value)
+	for _, name := range missing {
+		ps.add(p, "environment variable %s is not set (line %d)", name, value.Line)
+	}
+	secret, err := readSecret(path)
+	if err != nil {
+		ps.add(p, "%v (line %d)", err, value.Line)
+	}
+	key.Value = strings.TrimSuffix(key.Value, fileSuffix)
+	value.Kind, value.Tag, value.Style, value.Value = yaml.ScalarNode, "!!str", yaml.DoubleQuotedStyle, secret
+}
+
+// applyEnv overrides settings from PRTRACKER_* environment variables. Every setting has a
+// variable named after its path, e.g. PRTRACKER_NOTIFIERS_SMTP_PASSWORD; list elements are
+// addressed by index (PRTRACKER_BITBUCKET_TARGETS_0_AUTH_TOKEN) and lists of strings are
+// comma-separated. String settings can also be read from the file named by <variable>_FILE.
+func (c *Config) applyEnv() problems {
+	var ps problems
+	applyEnvValue(reflect.ValueOf(c).Elem(), "", &ps)
+	return ps
+}
+
+func applyEnvValue(v reflect.Value, path string, ps *problems) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if name == "" || name == "-" || !f.IsExported() {
+				continue
+			}
+			applyEnvValue(v.Field(i), joinPath(path, name), ps)
+		}
+		return
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			for i := 0; i < v.Len(); i++ {
+				applyEnvValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), ps)
+			}
+			return
+		}
+	}
+
+	name := envName(path)
+	raw, ok := os.LookupEnv(name)
+	if !ok && v.Kind() == reflect.String {
+		var file string
+		if file, ok = os.LookupEnv(name + "_FILE"); ok {
+			var err error
+			if raw, err = readSecret(file); err != nil {
+				ps.add(path, "%s_FILE: %v", name, err)
+				return
+			}
+		}
+	}
+	if !ok {
+		return
+	}
+	if err := setFromString(v, raw); err != nil {
+		ps.add(path, "%s: %v", name, err)
+	}
+}
+
+// envName maps a setting path to its environment variable,
+// e.g. bitbucket.targets[0].app_password to PRTRACKER_BITBUCKET_TARGETS_0_APP_PASSWORD
+func envName(path string) string {
+	r := strings.NewReplacer(".", "_", "[", "_", "]", "")
+	return EnvPrefix + strings.ToUpper(r.Replace(path))
+}
+
+// setFromString parses raw into a string, int, bool or string list setting, or a pointer to one
+func setFromString(v reflect.Value, raw string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", raw)
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := setFromString(elem.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(elem)
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a configuration file to a temporary directory and returns its path
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	return path
+}
+
+// writeSecret writes a secret file, with the trailing newline editors usually add
+func writeSecret(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte(content+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to create secret file: %v", err)
+	}
+	return path
+}
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("PRT_TEST_HOST", "git.example.com")
+	t.Setenv("PRT_TEST_EMPTY", "")
+
+	tests := []struct {
+		input    string
+		expected string
+		missing  string
+	}{
+		{"plain", "plain", ""},
+		{"https://${PRT_TEST_HOST}/api", "https://git.example.com/api", ""},
+		{"${PRT_TEST_UNSET:-fallback}", "fallback", ""},
+		{"${PRT_TEST_EMPTY:-fallback}", "", ""},
+		{"${PRT_TEST_UNSET}", "", "PRT_TEST_UNSET"},
+		{"pa$$word", "pa$$word", ""},
+		{"$${PRT_TEST_HOST}", "${PRT_TEST_HOST}", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, missing := interpolate(tt.input)
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+			if strings.Join(missing, ",") != tt.missing {
+				t.Errorf("Expected missing %q, got %v", tt.missing, missing)
+			}
+		})
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"bitbucket.app_password":             "PRTRACKER_BITBUCKET_APP_PASSWORD",
+		"bitbucket_cloud.auth.client_secret": "PRTRACKER_BITBUCKET_CLOUD_AUTH_CLIENT_SECRET",
+		"bitbucket.targets[1].auth.token":    "PRTRACKER_BITBUCKET_TARGETS_1_AUTH_TOKEN",
+	}
+	for path, expected := range tests {
+		if got := envName(path); got != expected {
+			t.Errorf("envName(%q) = %q, expected %q", path, got, expected)
+		}
+	}
+}
+
+func TestLoad_Interpolation(t *testing.T) {
+	t.Setenv("PRT_TEST_DOMAIN", "git.example.com")
+	t.Setenv("PRT_TEST_PORT", "8443")
+	t.Setenv("PRT_TEST_PASSWORD", "s3cret")
+	path := writeConfig(t, `
+bitbucket:
+  domain: "${PRT_TEST_DOMAIN}"
+  port: ${PRT_TEST_PORT}
+  user: "svc"
+  app_password: "${PRT_TEST_PASSWORD}"
+  repositories: ["api"]
+`+requiredSettings)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.Bitbucket.Domain != "git.example.com" || cfg.Bitbucket.Port != 8443 || cfg.Bitbucket.AppPassword != "s3cret" {
+		t.Errorf("Expected interpolated settings, got %+v", cfg.Bitbucket)
+	}
+}
+
+func TestLoad_InterpolationKeepsStrings(t *testing.T) {
+	for _, value := range []string{"null", "~", "true", "0123"} {
+		t.Run(value, func(t *testing.T) {
+			t.Setenv("PRT_TEST_PASSWORD", value)
+			path := writeConfig(t, `
+bitbucket:
+  domain: "git.example.com"
+  user: "svc"
+  app_password: ${PRT_TEST_PASSWORD}
+  repositories: ["api"]
+`+requiredSettings)
+
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if cfg.Bitbucket.AppPassword != value {
+				t.Errorf("Expected app_password %q, got %q", value, cfg.Bitbucket.AppPassword)
+			}
+		})
+	}
+}
+
+func TestLoad_SecretFiles(t *testing.T) {
+	bitbucketSecret := writeSecret(t, "bb-secret")
+	t.Setenv("PRT_TEST_SECRET_FILE", writeSecret(t, "smtp-secret"))
+	path := writeConfig(t, `
+bitbucket:
+  domain: "git.example.com"
+  user: "svc"
+  app_password_file: "`+bitbucketSecret+`"
+  repositories: ["api"]
+pr_filter:
+  stale_after_days: 7
+notification:
+  interval_hours: 24
+notifiers:
+  smtp:
+    host: "smtp.example.com"
+    user: "tracker"
+    password_file: "${PRT_TEST_SECRET_FILE}"
+    from: "tracker@example.com"
+    to: ["team@example.com"]
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.Bitbucket.AppPassword != "bb-secret" {
+		t.Errorf("Expected app_password from file, got %q", cfg.Bitbucket.AppPassword)
+	}
+	if cfg.Notifiers.SMTP.Password != "smtp-secret" {
+		t.Errorf("Expected SMTP password from file, got %q", cfg.Notifiers.SMTP.Password)
+	}
+}
+
+func TestLoad_EnvOverrides(t *testing.T) {
+	t.Setenv("PRTRACKER_BITBUCKET_APP_PASSWORD", "from-env")
+	t.Setenv("PRTRACKER_BITBUCKET_REPOSITORIES", "api, web ,")
+	t.Setenv("PRTRACKER_BITBUCKET_TARGETS_0_AUTH_TOKEN", "ops-token")
+	t.Setenv("PRTRACKER_PR_FILTER_STALE_AFTER_DAYS", "10")
+	t.Setenv("PRTRACKER_LOG_STDOUT", "true")
+	t.Setenv("PRTRACKER_NOTIFIERS_SMTP_USER", "tracker")
+	t.Setenv("PRTRACKER_NOTIFIERS_SMTP_PASSWORD_FILE", writeSecret(t, "smtp-secret"))
+	path := writeConfig(t, `
+bitbucket:
+  domain: "git.example.com"
+  user: "svc"
+  app_password: "from-yaml"
+  repositories: ["legacy"]
+  targets:
+    - project: "OPS"
+      auth:
+        type: "bearer"
+`+requiredSettings)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.Bitbucket.AppPassword != "from-env" {
+		t.Errorf("Expected app_password from the environment, got %q", cfg.Bitbucket.AppPassword)
+	}
+	if strings.Join(cfg.Bitbucket.Repositories, ",") != "api,web" {
+		t.Errorf("Expected repositories [api web], got %v", cfg.Bitbucket.Repositories)
+	}
+	if cfg.Bitbucket.Targets[0].Auth.Token != "ops-token" {
+		t.Errorf("Expected the target token from the environment, got %q", cfg.Bitbucket.Targets[0].Auth.Token)
+	}
+	if cfg.PRFilter.StaleAfterDays != 10 || !cfg.Log.Stdout {
+		t.Errorf("Expected stale_after_days 10 and stdout, got %d and %v", cfg.PRFilter.StaleAfterDays, cfg.Log.Stdout)
+	}
+	if cfg.Notifiers.SMTP.Password != "smtp-secret" {
+		t.Errorf("Expected the SMTP password from the secret file, got %q", cfg.Notifiers.SMTP.Password)
+	}
+}
+
+func TestLoad_EnvProblems(t *testing.T) {
+	t.Setenv("PRTRACKER_NOTIFICATION_INTERVAL_HOURS", "daily")
+	path := writeConfig(t, `
+bitbucket:
+  domain: "${PRT_TEST_UNSET_DOMAIN}"
+  app_password_file: "/does/not/exist"
+  repositories: ["api"]
+`+requiredSettings)
+
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, want := range []string{
+		"bitbucket.domain: environment variable PRT_TEST_UNSET_DOMAIN is not set (line 3)",
+		"bitbucket.app_password_file: open /does/not/exist",
+		`notification.interval_hours: PRTRACKER_NOTIFICATION_INTERVAL_HOURS: "daily" is not an integer`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in:\n%v", want, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// PRFilterOverride changes the pr_filter settings of the PRs it matches. Selectors are globs,
+// or regular expressions when prefixed with "re:"; a PR matches when every selector set
+// matches it. Settings left empty are inherited.
+type PRFilterOverride struct {
+	Projects     []string `yaml:"projects"`     // project keys, workspaces, owners or groups
+	Repositories []string `yaml:"repositories"` // matched against the slug and "PROJECT/slug"
+	Branches     []string `yaml:"branches"`     // target branches, e.g. "release/*"
+
+	StaleAfterDays *int     `yaml:"stale_after_days"`
+	IgnoreKeywords []string `yaml:"ignore_keywords"` // replaces the inherited keywords
+	MinApprovals   *int     `yaml:"min_approvals"`
+
+	// regexps holds the compiled "re:" selectors, nil for invalid ones; filled by Load
+	regexps map[string]*regexp.Regexp
+}
+
+// PRRule holds the pr_filter settings that apply to one PR
+type PRRule struct {
+	StaleAfterDays int
+	IgnoreKeywords []string
+	MinApprovals   int
+}
+
+// Rule returns the settings of the PRs of repository slug in project merging into branch:
+// the global ones, changed by every matching override in order, so later overrides win
+func (f PRFilterConfig) Rule(project, slug, branch string) PRRule {
+	rule := PRRule{StaleAfterDays: f.StaleAfterDays, IgnoreKeywords: f.IgnoreKeywords, MinApprovals: f.MinApprovals}
+	for _, o := range f.Overrides {
+		if !o.matches(project, slug, branch) {
+			continue
+		}
+		if o.StaleAfterDays != nil {
+			rule.StaleAfterDays = *o.StaleAfterDays
+		}
+		if o.IgnoreKeywords != nil {
+			rule.IgnoreKeywords = o.IgnoreKeywords
+		}
+		if o.MinApprovals != nil {
+			rule.MinApprovals = *o.MinApprovals
+		}
+	}
+	return rule
+}
+
+// compileSelectors compiles the "re:" selectors of every override once, so matching PRs
+// does not compile them again every cycle
+func (f *PRFilterConfig) compileSelectors() {
+	for i := range f.Overrides {
+		o := &f.Overrides[i]
+		o.regexps = make(map[string]*regexp.Regexp)
+		for _, patterns := range [][]string{o.Projects, o.Repositories, o.Branches} {
+			for _, p := range patterns {
+				if expr, ok := strings.CutPrefix(p, "re:"); ok {
+					// Invalid patterns are reported by Validate and stored as nil
+					o.regexps[p], _ = regexp.Compile(expr)
+				}
+			}
+		}
+	}
+}
+
+// matches reports whether every selector of the override matches the PR
+func (o PRFilterOverride) matches(project, slug, branch string) bool {
+	key := slug
+	if project != "" {
+		key = project + "/" + slug
+	}
+	return o.matchSelector(o.Projects, project) &&
+		o.matchSelector(o.Repositories, slug, key) &&
+		o.matchSelector(o.Branches, branch)
+}
+
+// matchSelector reports whether patterns is empty or one of them matches one of the names
+func (o PRFilterOverride) matchSelector(patterns []string, names ...string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if expr, ok := strings.CutPrefix(p, "re:"); ok {
+			re, compiled := o.regexps[p]
+			if !compiled {
+				// Overrides built without Load have no compiled selectors
+				re, _ = regexp.Compile(expr)
+			}
+			// An invalid pattern never matches
+			for _, name := range names {
+				if re != nil && re.MatchString(name) {
+					return true
+				}
+			}
+			continue
+		}
+		for _, name := range names {
+			if ok, _ := path.Match(p, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
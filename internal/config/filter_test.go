@@ -0,0 +1,85 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPRFilterConfig_Rule(t *testing.T) {
+	one, seven, two := 1, 7, 2
+	filter := PRFilterConfig{
+		StaleAfterDays: 3,
+		IgnoreKeywords: []string{"WIP"},
+		Overrides: []PRFilterOverride{
+			{Repositories: []string{"hotfix-*"}, StaleAfterDays: &one},
+			{Projects: []string{"DOCS"}, StaleAfterDays: &seven, IgnoreKeywords: []string{}},
+			{Branches: []string{"release/*"}, MinApprovals: &two},
+			{Projects: []string{"DOCS"}, Repositories: []string{"re:^handbook$"}, StaleAfterDays: &two},
+			{Repositories: []string{"CORE/api"}, IgnoreKeywords: []string{"DRAFT"}},
+		},
+	}
+
+	tests := []struct {
+		name                  string
+		project, slug, branch string
+		expected              PRRule
+	}{
+		{"global", "CORE", "web", "main", PRRule{StaleAfterDays: 3, IgnoreKeywords: []string{"WIP"}}},
+		{"repository", "CORE", "hotfix-payments", "main", PRRule{StaleAfterDays: 1, IgnoreKeywords: []string{"WIP"}}},
+		{"project replaces keywords", "DOCS", "guides", "main", PRRule{StaleAfterDays: 7, IgnoreKeywords: []string{}}},
+		{"branch", "CORE", "web", "release/1.0", PRRule{StaleAfterDays: 3, IgnoreKeywords: []string{"WIP"}, MinApprovals: 2}},
+		{"branch glob stops at slashes", "CORE", "web", "release/1.0/fix", PRRule{StaleAfterDays: 3, IgnoreKeywords: []string{"WIP"}}},
+		{"later overrides win", "DOCS", "handbook", "release/2.0", PRRule{StaleAfterDays: 2, IgnoreKeywords: []string{}, MinApprovals: 2}},
+		{"project and slug", "CORE", "api", "main", PRRule{StaleAfterDays: 3, IgnoreKeywords: []string{"DRAFT"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.Rule(tt.project, tt.slug, tt.branch); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoad_Overrides(t *testing.T) {
+	t.Setenv("PRTRACKER_PR_FILTER_OVERRIDES_1_MIN_APPROVALS", "1")
+	path := writeConfig(t, `
+bitbucket_cloud:
+  workspace: "acme"
+  repositories: ["docs", "hotfix-api"]
+pr_filter:
+  stale_after_days: 3
+  overrides:
+    - repositories: ["hotfix-*"]
+      stale_after_days: 1
+    - repositories: ["docs"]
+      stale_after_days: 7
+    - branches: ["re:^release/"]
+      min_approvals: 2
+notification:
+  interval_hours: 24
+notifiers:
+  smtp:
+    host: "smtp.example.com"
+    from: "tracker@example.com"
+    to: ["team@example.com"]
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rule := cfg.PRFilter.Rule("acme", "hotfix-api", "main"); rule.StaleAfterDays != 1 || rule.MinApprovals != 0 {
+		t.Errorf("Expected 1 day and every reviewer's approval for hotfix-api, got %+v", rule)
+	}
+	if rule := cfg.PRFilter.Rule("acme", "docs", "main"); rule.StaleAfterDays != 7 || rule.MinApprovals != 1 {
+		t.Errorf("Expected 7 days and 1 approval from the environment for docs, got %+v", rule)
+	}
+	if rule := cfg.PRFilter.Rule("acme", "docs", "release/1.0"); rule.MinApprovals != 2 {
+		t.Errorf("Expected 2 approvals on release branches, got %+v", rule)
+	}
+	if re := cfg.PRFilter.Overrides[2].regexps["re:^release/"]; re == nil {
+		t.Error("Expected the regular expression selector to be compiled by Load")
+	}
+}
@@ -0,0 +1,546 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"fc-pr-tracker/internal/calendar"
+	"fc-pr-tracker/internal/schedule"
+	"fc-pr-tracker/internal/templates"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults applied by Load to settings left empty
+const (
+	DefaultLogFile       = "./logs/pr-tracker.log"
+	DefaultLogLevel      = "info"
+	DefaultLogFormat     = "text"
+	DefaultLogMaxSizeMB  = 10
+	DefaultLogMaxBackups = 5
+	DefaultLogMaxAgeDays = 30
+	DefaultBitbucketPort = 443
+	DefaultSMTPPort      = 587
+)
+
+// Problem is one invalid setting, identified by its path in the YAML file
+type Problem struct {
+	Path    string // e.g. "notifiers.smtp.host" or "bitbucket.targets[1].project"
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Path + ": " + p.Message
+}
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%d problems):", len(e.Problems))
+	for _, p := range e.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(p.String())
+	}
+	return b.String()
+}
+
+// problems collects the problems found while validating
+type problems []Problem
+
+func (ps *problems) add(path, format string, args ...any) {
+	*ps = append(*ps, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns the collected problems as a *ValidationError, or nil if there are none
+func (ps problems) err() error {
+	if len(ps) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: ps}
+}
+
+// unknownKeys walks the YAML document against the Config type and reports every key
+// that does not map to a setting, which is usually a typo
+func unknownKeys(node *yaml.Node) problems {
+	var ps problems
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		walkKeys(node.Content[0], reflect.TypeOf(Config{}), "", &ps)
+	}
+	return ps
+}
+
+func walkKeys(node *yaml.Node, t reflect.Type, prefix string, ps *problems) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			p := joinPath(prefix, key.Value)
+			field, ok := fields[key.Value]
+			if !ok {
+				ps.add(p, "unknown key (line %d)", key.Line)
+				continue
+			}
+			walkKeys(value, field, p, ps)
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for i, item := range node.Content {
+			walkKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", prefix, i), ps)
+		}
+	}
+}
+
+// yamlFields maps the YAML keys of a struct to the types of their fields
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// applyDefaults trims credentials and fills in the settings left empty
+func (c *Config) applyDefaults() {
+	c.Bitbucket.User = strings.TrimSpace(c.Bitbucket.User)
+	c.Bitbucket.AppPassword = strings.TrimSpace(c.Bitbucket.AppPassword)
+	c.BitbucketCloud.User = strings.TrimSpace(c.BitbucketCloud.User)
+	c.BitbucketCloud.AppPassword = strings.TrimSpace(c.BitbucketCloud.AppPassword)
+	trimAuth(&c.Bitbucket.Auth)
+	trimAuth(&c.BitbucketCloud.Auth)
+	for i := range c.Bitbucket.Targets {
+		t := &c.Bitbucket.Targets[i]
+		t.User = strings.TrimSpace(t.User)
+		t.AppPassword = strings.TrimSpace(t.AppPassword)
+		trimAuth(&t.Auth)
+	}
+	c.GitHub.Token = strings.TrimSpace(c.GitHub.Token)
+	c.GitLab.Token = strings.TrimSpace(c.GitLab.Token)
+	c.Notifiers.SMTP.Password = strings.TrimSpace(c.Notifiers.SMTP.Password)
+	c.Notifiers.Slack.Token = strings.TrimSpace(c.Notifiers.Slack.Token)
+	for i := range c.Escalation.Tiers {
+		n := &c.Escalation.Tiers[i].Notifiers
+		n.SMTP.Password = strings.TrimSpace(n.SMTP.Password)
+		n.Slack.Token = strings.TrimSpace(n.Slack.Token)
+	}
+
+	if c.Bitbucket.Domain != "" && c.Bitbucket.Port == 0 {
+		c.Bitbucket.Port = DefaultBitbucketPort
+	}
+	if c.Notifiers.SMTP.Port == 0 {
+		c.Notifiers.SMTP.Port = DefaultSMTPPort
+	}
+	if c.Concurrency.Workers <= 0 {
+		c.Concurrency.Workers = DefaultWorkers
+	}
+	if c.Log.File == "" {
+		c.Log.File = DefaultLogFile
+	}
+	if c.Log.Level == "" {
+		c.Log.Level = DefaultLogLevel
+	}
+	if c.Log.Format == "" {
+		c.Log.Format = DefaultLogFormat
+	}
+	if c.Log.MaxSizeMB == 0 {
+		c.Log.MaxSizeMB = DefaultLogMaxSizeMB
+	}
+	if c.Log.MaxBackups == 0 {
+		c.Log.MaxBackups = DefaultLogMaxBackups
+	}
+	if c.Log.MaxAgeDays == 0 {
+		c.Log.MaxAgeDays = DefaultLogMaxAgeDays
+	}
+}
+
+func trimAuth(a *AuthConfig) {
+	a.Token = strings.TrimSpace(a.Token)
+	a.ClientSecret = strings.TrimSpace(a.ClientSecret)
+}
+
+// Validate checks the configuration and returns a *ValidationError listing every problem
+func (c *Config) Validate() error {
+	var ps problems
+
+	if !c.hasRepositories() {
+		ps.add("repositories", "no repository to track; set bitbucket.repositories, bitbucket.discovery.projects, "+
+			"bitbucket.targets, bitbucket_cloud.repositories, github.repositories or gitlab.repositories")
+	}
+
+	b := c.Bitbucket
+	if len(b.Repositories) > 0 || len(b.Discovery.Projects) > 0 {
+		if b.Domain == "" {
+			ps.add("bitbucket.domain", "required when bitbucket repositories or discovery projects are set")
+		}
+		validateAuth(&ps, "bitbucket", b.User, b.AppPassword, b.Auth)
+	}
+	validatePort(&ps, "bitbucket.port", b.Port)
+	validatePatterns(&ps, "bitbucket.discovery.include", b.Discovery.Include)
+	validatePatterns(&ps, "bitbucket.discovery.exclude", b.Discovery.Exclude)
+	validateRetry(&ps, "bitbucket.retry", b.Retry)
+	for i, t := range b.Targets {
+		p := fmt.Sprintf("bitbucket.targets[%d]", i)
+		if t.Project == "" {
+			ps.add(p+".project", "required")
+		}
+		if t.Domain == "" && b.Domain == "" {
+			ps.add(p+".domain", "required when bitbucket.domain is not set")
+		}
+		validatePort(&ps, p+".port", t.Port)
+		target := c.ForTarget(t).Bitbucket
+		validateAuth(&ps, p, target.User, target.AppPassword, target.Auth)
+	}
+
+	cloud := c.BitbucketCloud
+	if len(cloud.Repositories) > 0 {
+		if cloud.Workspace == "" {
+			ps.add("bitbucket_cloud.workspace", "required when bitbucket_cloud.repositories is set")
+		}
+		validateAuth(&ps, "bitbucket_cloud", cloud.User, cloud.AppPassword, cloud.Auth)
+	}
+	validateURL(&ps, "bitbucket_cloud.base_url", cloud.BaseURL)
+	validateRetry(&ps, "bitbucket_cloud.retry", cloud.Retry)
+
+	validateURL(&ps, "github.base_url", c.GitHub.BaseURL)
+	if c.GitHub.Owner == "" {
+		for i, r := range c.GitHub.Repositories {
+			if !strings.Contains(r, "/") {
+				ps.add(fmt.Sprintf("github.repositories[%d]", i), "%q has no owner; write \"owner/%s\" or set github.owner", r, r)
+			}
+		}
+	}
+	validateURL(&ps, "gitlab.base_url", c.GitLab.BaseURL)
+	if c.GitLab.Group == "" {
+		for i, r := range c.GitLab.Repositories {
+			if !strings.Contains(r, "/") {
+				ps.add(fmt.Sprintf("gitlab.repositories[%d]", i), "%q has no group; write \"group/%s\" or set gitlab.group", r, r)
+			}
+		}
+	}
+
+	if c.PRFilter.StaleAfterDays < 1 {
+		ps.add("pr_filter.stale_after_days", "must be at least 1, got %d", c.PRFilter.StaleAfterDays)
+	}
+	if c.PRFilter.MinApprovals < 0 {
+		ps.add("pr_filter.min_approvals", "must not be negative, got %d", c.PRFilter.MinApprovals)
+	}
+	for i, o := range c.PRFilter.Overrides {
+		validateOverride(&ps, fmt.Sprintf("pr_filter.overrides[%d]", i), o)
+	}
+	validateBusinessDays(&ps, c.PRFilter.BusinessDays)
+	validateSchedule(&ps, c.Notification)
+	if c.Notification.CycleTimeoutMinutes < 0 {
+		ps.add("notification.cycle_timeout_minutes", "must not be negative, got %d", c.Notification.CycleTimeoutMinutes)
+	}
+
+	smtp := c.Notifiers.SMTP
+	if !c.Notifiers.configured() {
+		ps.add("notifiers", "set smtp.to, teams.webhook_url, slack or digests")
+	}
+	if c.Notifiers.sendsEmail() {
+		if smtp.Host == "" {
+			ps.add("notifiers.smtp.host", "required to send emails")
+		}
+		if smtp.From == "" {
+			ps.add("notifiers.smtp.from", "required to send emails")
+		}
+	}
+	validatePort(&ps, "notifiers.smtp.port", smtp.Port)
+	if (smtp.User == "") != (smtp.Password == "") {
+		ps.add("notifiers.smtp", "user and password must be set together")
+	}
+	validateTeams(&ps, "notifiers.teams", c.Notifiers.Teams)
+	validateSlack(&ps, "notifiers.slack", c.Notifiers.Slack)
+	validateDigests(&ps, "notifiers.digests", c.Notifiers.Digests)
+	validateTemplates(&ps, "notifiers", c.Notifiers)
+	validateEscalation(&ps, c.Escalation, smtp)
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		ps.add("log.level", "must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "text", "json":
+	default:
+		ps.add("log.format", "must be text or json, got %q", c.Log.Format)
+	}
+
+	if c.Concurrency.Workers < 0 {
+		ps.add("concurrency.workers", "must not be negative, got %d", c.Concurrency.Workers)
+	}
+	if c.Concurrency.PerHost < 0 {
+		ps.add("concurrency.per_host", "must not be negative, got %d", c.Concurrency.PerHost)
+	}
+	return ps.err()
+}
+
+// hasRepositories reports whether any section lists or discovers repositories
+func (c *Config) hasRepositories() bool {
+	return len(c.Bitbucket.Repositories) > 0 || len(c.Bitbucket.Discovery.Projects) > 0 || len(c.Bitbucket.Targets) > 0 ||
+		len(c.BitbucketCloud.Repositories) > 0 || len(c.GitHub.Repositories) > 0 || len(c.GitLab.Repositories) > 0
+}
+
+// validateAuth checks the credentials of a Bitbucket section for the selected auth type
+func validateAuth(ps *problems, prefix, user, password string, auth AuthConfig) {
+	switch strings.ToLower(auth.Type) {
+	case "", "basic":
+		if (user == "") != (password == "") {
+			ps.add(prefix, "user and app_password must be set together")
+		}
+	case "bearer":
+		if auth.Token == "" {
+			ps.add(prefix+".auth.token", "required for bearer authentication")
+		}
+	case "oauth2":
+		if auth.ClientID == "" {
+			ps.add(prefix+".auth.client_id", "required for oauth2 authentication")
+		}
+		if auth.ClientSecret == "" {
+			ps.add(prefix+".auth.client_secret", "required for oauth2 authentication")
+		}
+		validateURL(ps, prefix+".auth.token_url", auth.TokenURL)
+	default:
+		ps.add(prefix+".auth.type", "must be basic, bearer or oauth2, got %q", auth.Type)
+	}
+}
+
+// validateEscalation checks that every tier is named, has its own threshold and notifies someone
+func validateEscalation(ps *problems, e EscalationConfig, smtp SMTPConfig) {
+	names := make(map[string]bool)
+	days := make(map[int]bool)
+	for i, t := range e.Tiers {
+		p := fmt.Sprintf("escalation.tiers[%d]", i)
+		switch {
+		case t.Name == "":
+			ps.add(p+".name", "required")
+		case names[t.Name]:
+			ps.add(p+".name", "duplicate tier %q", t.Name)
+		}
+		names[t.Name] = true
+		switch {
+		case t.AfterDays < 1:
+			ps.add(p+".after_days", "must be at least 1, got %d", t.AfterDays)
+		case days[t.AfterDays]:
+			ps.add(p+".after_days", "another tier already starts after %d days", t.AfterDays)
+		}
+		days[t.AfterDays] = true
+		if !t.Notifiers.configured() {
+			ps.add(p+".notifiers", "set smtp.to, teams.webhook_url, slack or digests")
+		}
+		if t.Notifiers.sendsEmail() {
+			if t.Notifiers.SMTP.Host == "" && smtp.Host == "" {
+				ps.add(p+".notifiers.smtp.host", "required to send emails, here or in notifiers.smtp")
+			}
+			if t.Notifiers.SMTP.From == "" && smtp.From == "" {
+				ps.add(p+".notifiers.smtp.from", "required to send emails, here or in notifiers.smtp")
+			}
+		}
+		validateSlack(ps, p+".notifiers.slack", t.Notifiers.Slack)
+		validateDigests(ps, p+".notifiers.digests", t.Notifiers.Digests)
+		validateTemplates(ps, p+".notifiers", t.Notifiers)
+		if (t.Notifiers.SMTP.User == "") != (t.Notifiers.SMTP.Password == "") {
+			ps.add(p+".notifiers.smtp", "user and password must be set together")
+		}
+		validatePort(ps, p+".notifiers.smtp.port", t.Notifiers.SMTP.Port)
+		validateTeams(ps, p+".notifiers.teams", t.Notifiers.Teams)
+	}
+}
+
+// validateTeams checks the Teams webhook and payload format
+func validateTeams(ps *problems, p string, t TeamsConfig) {
+	validateURL(ps, p+".webhook_url", t.WebhookURL)
+	switch t.Format {
+	case "", TeamsMessageCard, TeamsAdaptiveCard:
+	default:
+		ps.add(p+".format", "must be %s or %s, got %q", TeamsMessageCard, TeamsAdaptiveCard, t.Format)
+	}
+}
+
+// validateSlack checks that Slack is reached through either a webhook or a bot token
+func validateSlack(ps *problems, p string, s SlackConfig) {
+	validateURL(ps, p+".webhook_url", s.WebhookURL)
+	validateURL(ps, p+".base_url", s.BaseURL)
+	switch {
+	case s.WebhookURL != "" && s.Token != "":
+		ps.add(p, "set webhook_url or token, not both")
+	case s.Token != "" && s.Channel == "":
+		ps.add(p+".channel", "required with a bot token")
+	case s.Token == "" && s.Channel != "":
+		ps.add(p+".token", "required to post to a channel; webhooks post to the channel they were created for")
+	}
+}
+
+// validateDigests checks the recipient settings of personal digests
+func validateDigests(ps *problems, p string, d DigestsConfig) {
+	if d.MaxRecipients < 0 {
+		ps.add(p+".max_recipients", "must not be negative, got %d", d.MaxRecipients)
+	}
+}
+
+// validateTemplates parses the notification templates and executes them with a sample report
+func validateTemplates(ps *problems, p string, n NotifiersConfig) {
+	check := func(path string, t templates.Executor, err error, verify func(templates.Executor) error) {
+		if err == nil {
+			err = verify(t)
+		}
+		if err != nil {
+			ps.add(path, "%v", err)
+		}
+	}
+	if tt := n.SMTP.Templates; tt.Subject != "" {
+		t, err := templates.Parse("subject", tt.Subject, nil, nil)
+		check(p+".smtp.templates.subject", t, err, templates.Check)
+	}
+	if tt := n.SMTP.Templates; tt.Text != "" {
+		t, err := templates.ParseText(tt.Text, nil, nil)
+		check(p+".smtp.templates.text", t, err, templates.Check)
+	}
+	if tt := n.SMTP.Templates; tt.HTML != "" {
+		t, err := templates.ParseHTML(tt.HTML, nil, nil)
+		check(p+".smtp.templates.html", t, err, templates.Check)
+	}
+	if n.Teams.Template != "" {
+		t, err := templates.ParseText(n.Teams.Template, nil, nil)
+		check(p+".teams.template", t, err, templates.CheckJSON)
+	}
+}
+
+// validateOverride checks the selectors and settings of a pr_filter override
+func validateOverride(ps *problems, p string, o PRFilterOverride) {
+	if len(o.Projects) == 0 && len(o.Repositories) == 0 && len(o.Branches) == 0 {
+		ps.add(p, "needs projects, repositories or branches to select the PRs it applies to")
+	}
+	validatePatterns(ps, p+".projects", o.Projects)
+	validatePatterns(ps, p+".repositories", o.Repositories)
+	validatePatterns(ps, p+".branches", o.Branches)
+	if o.StaleAfterDays != nil && *o.StaleAfterDays < 1 {
+		ps.add(p+".stale_after_days", "must be at least 1, got %d", *o.StaleAfterDays)
+	}
+	if o.MinApprovals != nil && *o.MinApprovals < 0 {
+		ps.add(p+".min_approvals", "must not be negative, got %d", *o.MinApprovals)
+	}
+}
+
+// validateBusinessDays checks the workweek, time zone and holidays, reading the holiday calendar
+func validateBusinessDays(ps *problems, b BusinessDaysConfig) {
+	if len(b.Workweek) > 0 {
+		if _, err := calendar.ParseWorkweek(b.Workweek); err != nil {
+			ps.add("pr_filter.business_days.workweek", "%v", err)
+		}
+	}
+	if b.Timezone != "" {
+		if _, err := time.LoadLocation(b.Timezone); err != nil {
+			ps.add("pr_filter.business_days.timezone", "unknown time zone %q", b.Timezone)
+		}
+	}
+	for i, h := range b.Holidays {
+		if _, err := calendar.ParseDate(h); err != nil {
+			ps.add(fmt.Sprintf("pr_filter.business_days.holidays[%d]", i), "%v", err)
+		}
+	}
+	if b.HolidayCalendar != "" {
+		if _, err := calendar.LoadICS(b.HolidayCalendar); err != nil {
+			ps.add("pr_filter.business_days.holiday_calendar", "%v", err)
+		}
+	}
+}
+
+// validateSchedule checks the interval or cron expression, the time zone and the quiet hours
+func validateSchedule(ps *problems, n NotificationConfig) {
+	if n.Schedule == "" && n.IntervalHours < 1 {
+		ps.add("notification.interval_hours", "must be at least 1 when notification.schedule is not set, got %d", n.IntervalHours)
+	}
+	if n.Schedule != "" && n.IntervalHours < 0 {
+		ps.add("notification.interval_hours", "must not be negative, got %d", n.IntervalHours)
+	}
+	loc, err := n.Location()
+	if err != nil {
+		ps.add("notification.timezone", "unknown time zone %q", n.Timezone)
+		loc = time.UTC
+	}
+	if n.Schedule != "" {
+		cron, err := schedule.ParseCron(n.Schedule, loc)
+		if err != nil {
+			ps.add("notification.schedule", "%v", err)
+		} else if cron.Next(time.Now()).IsZero() {
+			ps.add("notification.schedule", "cron expression %q never fires", n.Schedule)
+		}
+	}
+	q := n.QuietHours
+	if q.Start != "" || q.End != "" {
+		if _, err := schedule.ParseQuietHours(q.Start, q.End); err != nil {
+			ps.add("notification.quiet_hours", "%v", err)
+		}
+	}
+}
+
+func validatePort(ps *problems, p string, port int) {
+	if port < 0 || port > 65535 {
+		ps.add(p, "must be between 1 and 65535, got %d", port)
+	}
+}
+
+// validateURL accepts an empty value or an absolute http(s) URL
+func validateURL(ps *problems, p, raw string) {
+	if raw == "" {
+		return
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		ps.add(p, "must be an absolute http or https URL, got %q", raw)
+	}
+}
+
+// validatePatterns checks discovery globs and "re:" regular expressions
+func validatePatterns(ps *problems, p string, patterns []string) {
+	for i, pattern := range patterns {
+		var err error
+		if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+			_, err = regexp.Compile(expr)
+		} else {
+			_, err = path.Match(pattern, "")
+		}
+		if err != nil {
+			ps.add(fmt.Sprintf("%s[%d]", p, i), "invalid pattern %q: %v", pattern, err)
+		}
+	}
+}
+
+func validateRetry(ps *problems, p string, r RetryConfig) {
+	if r.MaxAttempts < 0 {
+		ps.add(p+".max_attempts", "must not be negative, got %d", r.MaxAttempts)
+	}
+	if r.InitialBackoffMS < 0 {
+		ps.add(p+".initial_backoff_ms", "must not be negative, got %d", r.InitialBackoffMS)
+	}
+	if r.MaxBackoffMS < 0 {
+		ps.add(p+".max_backoff_ms", "must not be negative, got %d", r.MaxBackoffMS)
+	}
+}
@@ -0,0 +1,265 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// validConfig returns a configuration that passes validation
+func validConfig() *Config {
+	cfg := &Config{
+		Bitbucket: BitbucketConfig{
+			Domain:       "git.example.com",
+			User:         "svc",
+			AppPassword:  "secret",
+			Repositories: []string{"api"},
+		},
+		PRFilter:     PRFilterConfig{StaleAfterDays: 7},
+		Notification: NotificationConfig{IntervalHours: 24},
+		Notifiers: NotifiersConfig{
+			SMTP: SMTPConfig{Host: "smtp.example.com", From: "tracker@example.com", To: []string{"team@example.com"}},
+		},
+	}
+	cfg.applyDefaults()
+	return cfg
+}
+
+// problemPaths returns the paths reported by a *ValidationError
+func problemPaths(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected a *ValidationError, got %T: %v", err, err)
+	}
+	var paths []string
+	for _, p := range verr.Problems {
+		paths = append(paths, p.Path)
+	}
+	return paths
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(c *Config)
+		expected []string
+	}{
+		{"valid", func(c *Config) {}, nil},
+		{"missing stale_after_days", func(c *Config) { c.PRFilter.StaleAfterDays = 0 }, []string{"pr_filter.stale_after_days"}},
+		{"zero interval_hours", func(c *Config) { c.Notification.IntervalHours = 0 }, []string{"notification.interval_hours"}},
+		{"cron schedule replaces interval_hours", func(c *Config) {
+			c.Notification = NotificationConfig{Schedule: "0 9 * * MON-FRI", Timezone: "UTC", SkipWeekends: true,
+				QuietHours: QuietHoursConfig{Start: "20:00", End: "08:00"}}
+		}, nil},
+		{"invalid schedule", func(c *Config) {
+			c.Notification = NotificationConfig{Schedule: "0 25 * * *", Timezone: "Mars/Olympus", QuietHours: QuietHoursConfig{Start: "20:00"}}
+		}, []string{"notification.timezone", "notification.schedule", "notification.quiet_hours"}},
+		{"business days", func(c *Config) {
+			c.PRFilter.BusinessDays = BusinessDaysConfig{Enabled: true, Workweek: []string{"SUN", "MON", "TUE", "WED", "THU"},
+				Timezone: "Asia/Jerusalem", Holidays: []string{"2026-09-21"}}
+		}, nil},
+		{"invalid business days", func(c *Config) {
+			c.PRFilter.BusinessDays = BusinessDaysConfig{Enabled: true, Workweek: []string{"MON", "FUN"}, Timezone: "Mars/Olympus",
+				Holidays: []string{"2026-12-25", "25/12/2026"}, HolidayCalendar: "missing.ics"}
+		}, []string{"pr_filter.business_days.workweek", "pr_filter.business_days.timezone",
+			"pr_filter.business_days.holidays[1]", "pr_filter.business_days.holiday_calendar"}},
+		{"schedule that never fires", func(c *Config) { c.Notification.Schedule = "0 0 30 FEB *" }, []string{"notification.schedule"}},
+		{"no repositories", func(c *Config) { c.Bitbucket.Repositories = nil }, []string{"repositories"}},
+		{"no notifier", func(c *Config) { c.Notifiers.SMTP = SMTPConfig{Port: DefaultSMTPPort} }, []string{"notifiers"}},
+		{"missing SMTP settings", func(c *Config) {
+			c.Notifiers.SMTP = SMTPConfig{Port: DefaultSMTPPort, To: []string{"team@example.com"}}
+		}, []string{"notifiers.smtp.host", "notifiers.smtp.from"}},
+		{"Slack only", func(c *Config) {
+			c.Notifiers.SMTP = SMTPConfig{Port: DefaultSMTPPort}
+			c.Notifiers.Slack = SlackConfig{WebhookURL: "https://hooks.slack.example.com/x"}
+		}, nil},
+		{"Teams only", func(c *Config) {
+			c.Notifiers.SMTP = SMTPConfig{Port: DefaultSMTPPort}
+			c.Notifiers.Teams = TeamsConfig{WebhookURL: "https://teams.example.com/x"}
+		}, nil},
+		{"digests replace smtp.to", func(c *Config) {
+			c.Notifiers.SMTP.To = nil
+			c.Notifiers.Digests = DigestsConfig{Reviewers: true, OptOut: []string{"cto@example.com"}, MaxRecipients: 50}
+		}, nil},
+		{"negative digest recipients", func(c *Config) { c.Notifiers.Digests = DigestsConfig{Authors: true, MaxRecipients: -1} },
+			[]string{"notifiers.digests.max_recipients"}},
+		{"templates", func(c *Config) {
+			c.Notifiers.SMTP.Templates = EmailTemplatesConfig{Subject: "{{.TotalPRs}} stale PRs", Text: "../../templates/email.txt.tmpl"}
+			c.Notifiers.Teams.Template = "../../templates/teams.json.tmpl"
+		}, nil},
+		{"invalid templates", func(c *Config) {
+			c.Notifiers.SMTP.Templates = EmailTemplatesConfig{Subject: "{{.Unknown}}", Text: "missing.tmpl", HTML: "../../templates/email.txt.tmpl"}
+			c.Notifiers.Teams.Template = "../../templates/email.txt.tmpl"
+		}, []string{"notifiers.smtp.templates.subject", "notifiers.smtp.templates.text", "notifiers.teams.template"}},
+		{"Slack bot", func(c *Config) { c.Notifiers.Slack = SlackConfig{Token: "xoxb-1", Channel: "C0123"} }, nil},
+		{"invalid Slack", func(c *Config) {
+			c.Notifiers.Slack = SlackConfig{WebhookURL: "hooks", Token: "xoxb-1"}
+			c.Escalation.Tiers = []EscalationTier{{Name: "Leads", AfterDays: 7, Notifiers: NotifiersConfig{Slack: SlackConfig{Token: "xoxb-2"}}}}
+		}, []string{"notifiers.slack.webhook_url", "notifiers.slack", "escalation.tiers[0].notifiers.slack.channel"}},
+		{"missing Bitbucket domain", func(c *Config) { c.Bitbucket.Domain = "" }, []string{"bitbucket.domain"}},
+		{"password without user", func(c *Config) { c.Bitbucket.User = "" }, []string{"bitbucket"}},
+		{"bearer without token", func(c *Config) { c.Bitbucket.Auth.Type = "bearer" }, []string{"bitbucket.auth.token"}},
+		{"unknown auth type", func(c *Config) { c.Bitbucket.Auth.Type = "kerberos" }, []string{"bitbucket.auth.type"}},
+		{"oauth2 without client", func(c *Config) { c.Bitbucket.Auth = AuthConfig{Type: "oauth2", TokenURL: "token"} },
+			[]string{"bitbucket.auth.client_id", "bitbucket.auth.client_secret", "bitbucket.auth.token_url"}},
+		{"invalid discovery pattern", func(c *Config) { c.Bitbucket.Discovery.Exclude = []string{"ok-*", "re:(unclosed"} },
+			[]string{"bitbucket.discovery.exclude[1]"}},
+		{"target without project", func(c *Config) { c.Bitbucket.Targets = []BitbucketTarget{{Repositories: []string{"x"}}} },
+			[]string{"bitbucket.targets[0].project"}},
+		{"target inherits domain", func(c *Config) { c.Bitbucket.Targets = []BitbucketTarget{{Project: "OPS"}} }, nil},
+		{"cloud without workspace", func(c *Config) { c.BitbucketCloud.Repositories = []string{"site"} },
+			[]string{"bitbucket_cloud.workspace"}},
+		{"GitHub repository without owner", func(c *Config) { c.GitHub.Repositories = []string{"acme/api", "web"} },
+			[]string{"github.repositories[1]"}},
+		{"relative Teams webhook", func(c *Config) { c.Notifiers.Teams.WebhookURL = "webhook" }, []string{"notifiers.teams.webhook_url"}},
+		{"Teams Adaptive Card", func(c *Config) {
+			c.Notifiers.Teams = TeamsConfig{WebhookURL: "https://prod.westeurope.logic.azure.com/workflows/1", Format: TeamsAdaptiveCard}
+		}, nil},
+		{"unknown Teams format", func(c *Config) { c.Notifiers.Teams.Format = "hero_card" }, []string{"notifiers.teams.format"}},
+		{"bad log level", func(c *Config) { c.Log.Level = "verbose" }, []string{"log.level"}},
+		{"out of range port", func(c *Config) { c.Bitbucket.Port = 70000 }, []string{"bitbucket.port"}},
+		{"overrides", func(c *Config) {
+			days := 1
+			c.PRFilter.Overrides = []PRFilterOverride{{Repositories: []string{"hotfix-*"}, StaleAfterDays: &days}}
+		}, nil},
+		{"invalid overrides", func(c *Config) {
+			days, approvals := 0, -1
+			c.PRFilter.MinApprovals = -2
+			c.PRFilter.Overrides = []PRFilterOverride{
+				{StaleAfterDays: &days},
+				{Branches: []string{"re:(unclosed"}, MinApprovals: &approvals},
+			}
+		}, []string{"pr_filter.min_approvals", "pr_filter.overrides[0]", "pr_filter.overrides[0].stale_after_days",
+			"pr_filter.overrides[1].branches[0]", "pr_filter.overrides[1].min_approvals"}},
+		{"escalation tiers", func(c *Config) {
+			c.Escalation.Tiers = []EscalationTier{
+				{Name: "Team", AfterDays: 7, Notifiers: NotifiersConfig{Teams: TeamsConfig{WebhookURL: "https://teams.example.com/x"}}},
+				{Name: "Managers", AfterDays: 14, Notifiers: NotifiersConfig{SMTP: SMTPConfig{To: []string{"managers@example.com"}}}},
+			}
+		}, nil},
+		{"tier email without SMTP server", func(c *Config) {
+			c.Notifiers.SMTP = SMTPConfig{Port: DefaultSMTPPort}
+			c.Notifiers.Teams = TeamsConfig{WebhookURL: "https://teams.example.com/x"}
+			c.Escalation.Tiers = []EscalationTier{
+				{Name: "Managers", AfterDays: 14, Notifiers: NotifiersConfig{SMTP: SMTPConfig{To: []string{"managers@example.com"}}}},
+				{Name: "Leads", AfterDays: 21, Notifiers: NotifiersConfig{
+					SMTP:    SMTPConfig{Host: "smtp.example.com", From: "tracker@example.com"},
+					Digests: DigestsConfig{Reviewers: true},
+				}},
+			}
+		}, []string{"escalation.tiers[0].notifiers.smtp.host", "escalation.tiers[0].notifiers.smtp.from"}},
+		{"invalid escalation tiers", func(c *Config) {
+			c.Escalation.Tiers = []EscalationTier{
+				{Name: "Team", AfterDays: 7, Notifiers: NotifiersConfig{Teams: TeamsConfig{WebhookURL: "teams"}}},
+				{Name: "Team", AfterDays: 7},
+				{AfterDays: 0, Notifiers: NotifiersConfig{SMTP: SMTPConfig{To: []string{"x@example.com"}, User: "u"}}},
+			}
+		}, []string{"escalation.tiers[0].notifiers.teams.webhook_url", "escalation.tiers[1].name", "escalation.tiers[1].after_days",
+			"escalation.tiers[1].notifiers", "escalation.tiers[2].name", "escalation.tiers[2].after_days", "escalation.tiers[2].notifiers.smtp"}},
+		{"negative settings", func(c *Config) {
+			c.Concurrency.PerHost = -1
+			c.Notification.CycleTimeoutMinutes = -5
+			c.Bitbucket.Retry.MaxAttempts = -1
+		}, []string{"bitbucket.retry.max_attempts", "notification.cycle_timeout_minutes", "concurrency.per_host"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+			got := problemPaths(t, cfg.Validate())
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected problems %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoad_ReportsAllProblems(t *testing.T) {
+	configContent := `
+bitbucket:
+  domain: "git.example.com"
+  repositores: ["api"]
+  targets:
+    - project: "OPS"
+      tokn: "x"
+pr_filter:
+  stale_after_days: 7
+notification:
+  interval_hours: 0
+notifiers:
+  smtp:
+    host: "smtp.example.com"
+    from: "tracker@example.com"
+    to: ["team@example.com"]
+`
+	tempFile := "test_config_problems.yaml"
+	if err := os.WriteFile(tempFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer os.Remove(tempFile)
+
+	_, err := Load(tempFile)
+	got := problemPaths(t, err)
+	expected := []string{"bitbucket.repositores", "bitbucket.targets[0].tokn", "notification.interval_hours"}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected problems %v, got %v", expected, got)
+	}
+	if !strings.Contains(err.Error(), "bitbucket.repositores: unknown key (line 4)") {
+		t.Errorf("Expected the unknown key with its line, got:\n%v", err)
+	}
+}
+
+func TestLoad_AppliesDefaults(t *testing.T) {
+	configContent := `
+bitbucket:
+  domain: "git.example.com"
+  repositories: ["api"]
+` + requiredSettings
+	tempFile := "test_config_defaults.yaml"
+	if err := os.WriteFile(tempFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer os.Remove(tempFile)
+
+	cfg, err := Load(tempFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.Bitbucket.Port != DefaultBitbucketPort || cfg.Notifiers.SMTP.Port != DefaultSMTPPort {
+		t.Errorf("Expected default ports, got bitbucket %d and smtp %d", cfg.Bitbucket.Port, cfg.Notifiers.SMTP.Port)
+	}
+	if cfg.Concurrency.Workers != DefaultWorkers {
+		t.Errorf("Expected %d workers, got %d", DefaultWorkers, cfg.Concurrency.Workers)
+	}
+	if cfg.Log.File != DefaultLogFile || cfg.Log.Level != DefaultLogLevel || cfg.Log.Format != DefaultLogFormat {
+		t.Errorf("Expected default log settings, got %+v", cfg.Log)
+	}
+}
+
+func TestLoad_EmptyFile(t *testing.T) {
+	tempFile := "test_config_empty.yaml"
+	if err := os.WriteFile(tempFile, nil, 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	defer os.Remove(tempFile)
+
+	_, err := Load(tempFile)
+	got := problemPaths(t, err)
+	for _, want := range []string{"repositories", "pr_filter.stale_after_days", "notification.interval_hours", "notifiers"} {
+		if !strings.Contains(strings.Join(got, ","), want) {
+			t.Errorf("Expected a problem for %s, got %v", want, got)
+		}
+	}
+}
+
+func TestLoad_ExampleConfig(t *testing.T) {
+	if _, err := Load("../../config-example.yaml"); err != nil {
+		t.Errorf("Expected config-example.yaml to be valid, got %v", err)
+	}
+}
@@ -0,0 +1,386 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"fc-pr-tracker/internal/config"
+	"fc-pr-tracker/pkg/models"
+)
+
+// DefaultBaseURL is the public GitHub API root; GitHub Enterprise uses https://<host>/api/v3
+const DefaultBaseURL = "https://api.github.com"
+
+// Client represents a GitHub REST API client
+type Client struct {
+	Config  *config.Config
+	Client  *http.Client
+	BaseURL string
+}
+
+// NewClient creates a new GitHub client
+func NewClient(cfg *config.Config) *Client {
+	baseURL := strings.TrimRight(cfg.GitHub.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		Config:  cfg,
+		Client:  &http.Client{Timeout: 15 * time.Second},
+		BaseURL: baseURL,
+	}
+}
+
+// Name identifies the provider in logs
+func (c *Client) Name() string {
+	return "github"
+}
+
+// Host returns the GitHub API host the client talks to
+func (c *Client) Host() string {
+	if u, err := url.Parse(c.BaseURL); err == nil {
+		return u.Host
+	}
+	return c.BaseURL
+}
+
+// TestConnection checks if the GitHub API is reachable and the token is valid
+func (c *Client) TestConnection(ctx context.Context) error {
+	if _, err := c.get(ctx, c.BaseURL+"/user", nil); err != nil {
+		return fmt.Errorf("GitHub connection test failed: %v", err)
+	}
+	return nil
+}
+
+// ListOpenPRs fetches open PRs for a repository ("owner/repo" or "repo" in the configured owner)
+func (c *Client) ListOpenPRs(ctx context.Context, repo string) ([]models.PullRequest, error) {
+	var prs []models.PullRequest
+	url := fmt.Sprintf("%s/pulls?state=open&per_page=100", c.repoURL(repo))
+
+	for url != "" {
+		var page []pullRequest
+		next, err := c.get(ctx, url, &page)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching PRs: %v", err)
+		}
+		for _, pr := range page {
+			prs = append(prs, pr.toModel())
+		}
+		url = next
+	}
+	return prs, nil
+}
+
+// GetParticipants combines requested reviewers and submitted reviews into participants.
+// A reviewer's state is given by their latest approving, rejecting or dismissed review;
+// reviewers whose review was re-requested count as not approved.
+func (c *Client) GetParticipants(ctx context.Context, repo string, prID int) ([]models.Participant, error) {
+	slog.Info("Fetching participants for PR", "pr_id", prID, "repo", repo)
+
+	var requested requestedReviewers
+	if _, err := c.get(ctx, fmt.Sprintf("%s/pulls/%d/requested_reviewers", c.repoURL(repo), prID), &requested); err != nil {
+		return nil, fmt.Errorf("error fetching requested reviewers: %v", err)
+	}
+
+	var reviews []review
+	url := fmt.Sprintf("%s/pulls/%d/reviews?per_page=100", c.repoURL(repo), prID)
+	for url != "" {
+		var page []review
+		next, err := c.get(ctx, url, &page)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching reviews: %v", err)
+		}
+		reviews = append(reviews, page...)
+		url = next
+	}
+
+	return buildParticipants(requested, reviews), nil
+}
+
+// GetComments fetches the issue timeline of a PR (comments, reviews, commits and other events)
+func (c *Client) GetComments(ctx context.Context, repo string, prID int) ([]models.Comment, error) {
+	var comments []models.Comment
+	url := fmt.Sprintf("%s/issues/%d/timeline?per_page=100", c.repoURL(repo), prID)
+	slog.Info("Fetching timeline for PR", "pr_id", prID, "repo", repo, "url", url)
+
+	for url != "" {
+		var page []timelineEvent
+		next, err := c.get(ctx, url, &page)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching timeline: %v", err)
+		}
+		for _, e := range page {
+			if comment, ok := e.toModel(); ok {
+				comments = append(comments, comment)
+			}
+		}
+		url = next
+	}
+	return comments, nil
+}
+
+// repoURL builds the API URL of a repository, prefixing the configured owner when needed
+func (c *Client) repoURL(repo string) string {
+	if !strings.Contains(repo, "/") {
+		repo = c.Config.GitHub.Owner + "/" + repo
+	}
+	return fmt.Sprintf("%s/repos/%s", c.BaseURL, repo)
+}
+
+// get performs an authenticated GET request, decodes the JSON body into out (if not nil)
+// and returns the URL of the next page from the Link header, if any
+func (c *Client) get(ctx context.Context, url string, out interface{}) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	if c.Config.GitHub.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Config.GitHub.Token)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		slog.Error("GitHub request failed", "status", resp.Status, "url", url, "body", string(body))
+		return "", fmt.Errorf("%s (URL: %s, Body: %s)", resp.Status, url, string(body))
+	}
+	if out != nil {
+		if err := json.Unmarshal(body, out); err != nil {
+			return "", err
+		}
+	}
+	return nextLink(resp.Header.Get("Link")), nil
+}
+
+// nextLink extracts the rel="next" URL from a GitHub Link header
+func nextLink(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		sections := strings.Split(part, ";")
+		if len(sections) < 2 {
+			continue
+		}
+		for _, param := range sections[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(sections[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// Response types for the GitHub REST API
+type user struct {
+	Login string `json:"login"`
+	ID    int    `json:"id"`
+	Type  string `json:"type"`
+	Email string `json:"email"` // only set for users with a public email address
+}
+
+type pullRequest struct {
+	Number    int    `json:"number"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	State     string `json:"state"`
+	Draft     bool   `json:"draft"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	HTMLURL   string `json:"html_url"`
+	User      user   `json:"user"`
+	Base      struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+type requestedReviewers struct {
+	Users []user `json:"users"`
+	Teams []team `json:"teams"`
+}
+
+type team struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type review struct {
+	ID          int    `json:"id"`
+	User        user   `json:"user"`
+	State       string `json:"state"`
+	SubmittedAt string `json:"submitted_at"`
+}
+
+type timelineEvent struct {
+	ID        int    `json:"id"`
+	Event     string `json:"event"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	// SubmittedAt is set on "reviewed" events
+	SubmittedAt string `json:"submitted_at"`
+	Actor       *user  `json:"actor"`
+	User        *user  `json:"user"`
+	// Committer is set on "committed" events
+	Committer *struct {
+		Name string `json:"name"`
+		Date string `json:"date"`
+	} `json:"committer"`
+}
+
+// toModel maps a GitHub pull request into the shared model
+func (p pullRequest) toModel() models.PullRequest {
+	pr := models.PullRequest{
+		ID:          p.Number,
+		Title:       p.Title,
+		Description: p.Body,
+		State:       strings.ToUpper(p.State),
+		Open:        p.State == "open",
+		Closed:      p.State != "open",
+		CreatedDate: parseTime(p.CreatedAt),
+		UpdatedDate: parseTime(p.UpdatedAt),
+	}
+	pr.Author.User.DisplayName = p.User.Login
+	pr.Author.User.Username = p.User.Login
+	pr.Author.User.Email = p.User.Email
+	pr.ToRef.DisplayID = p.Base.Ref
+	pr.Author.Role = "AUTHOR"
+	if p.HTMLURL != "" {
+		pr.Links.Self = append(pr.Links.Self, struct {
+			Href string `json:"href"`
+		}{Href: p.HTMLURL})
+	}
+	return pr
+}
+
+// toModel maps a timeline event into a Comment; ok is false for events without a date
+func (e timelineEvent) toModel() (models.Comment, bool) {
+	comment := models.Comment{ID: e.ID, Content: e.Body}
+
+	switch {
+	case e.SubmittedAt != "":
+		comment.CreatedDate = parseTime(e.SubmittedAt)
+	case e.CreatedAt != "":
+		comment.CreatedDate = parseTime(e.CreatedAt)
+	case e.Committer != nil:
+		comment.CreatedDate = parseTime(e.Committer.Date)
+		comment.User.DisplayName = e.Committer.Name
+	}
+	comment.UpdatedDate = parseTime(e.UpdatedAt)
+	if comment.UpdatedDate == 0 {
+		comment.UpdatedDate = comment.CreatedDate
+	}
+
+	if author := e.User; author != nil || e.Actor != nil {
+		if author == nil {
+			author = e.Actor
+		}
+		comment.User.DisplayName = author.Login
+		comment.User.Username = author.Login
+	}
+	return comment, comment.UpdatedDate != 0
+}
+
+// buildParticipants merges requested reviewers and reviews into the shared participant model.
+// Requested teams become pending reviewers of type "Team", so a PR waiting only on a team
+// review still counts that review as missing.
+func buildParticipants(requested requestedReviewers, reviews []review) []models.Participant {
+	sort.SliceStable(reviews, func(i, j int) bool {
+		return reviews[i].SubmittedAt < reviews[j].SubmittedAt
+	})
+
+	var order []string
+	states := make(map[string]string)
+	users := make(map[string]user)
+	for _, r := range reviews {
+		login := r.User.Login
+		if _, seen := users[login]; !seen {
+			order = append(order, login)
+			users[login] = r.User
+		}
+		// Plain comments do not change an earlier approval or change request
+		if r.State == "COMMENTED" && states[login] != "" {
+			continue
+		}
+		states[login] = r.State
+	}
+
+	pending := make(map[string]bool)
+	for _, u := range requested.Users {
+		pending[u.Login] = true
+		if _, seen := users[u.Login]; !seen {
+			order = append(order, u.Login)
+			users[u.Login] = u
+		}
+	}
+
+	participants := make([]models.Participant, 0, len(order))
+	for _, login := range order {
+		var p models.Participant
+		u := users[login]
+		p.User.DisplayName = u.Login
+		p.User.Username = u.Login
+		p.User.Slug = u.Login
+		p.User.ID = u.ID
+		p.User.Type = u.Type
+		p.User.Active = true
+		p.Role = "REVIEWER"
+		p.Status = "UNAPPROVED"
+
+		switch state := states[login]; {
+		case pending[login]:
+			// Review requested (again): any previous approval no longer counts
+		case state == "APPROVED":
+			p.Approved = true
+			p.Status = "APPROVED"
+		case state == "CHANGES_REQUESTED":
+			p.Status = "NEEDS_WORK"
+		case state == "COMMENTED":
+			p.Role = "PARTICIPANT"
+		}
+		participants = append(participants, p)
+	}
+
+	for _, t := range requested.Teams {
+		var p models.Participant
+		p.User.DisplayName = t.Name
+		if p.User.DisplayName == "" {
+			p.User.DisplayName = t.Slug
+		}
+		p.User.Username = t.Slug
+		p.User.Slug = t.Slug
+		p.User.ID = t.ID
+		p.User.Type = "Team"
+		p.User.Active = true
+		p.Role = "REVIEWER"
+		p.Status = "UNAPPROVED"
+		participants = append(participants, p)
+	}
+	return participants
+}
+
+// parseTime converts a GitHub ISO-8601 timestamp into Unix milliseconds (0 if invalid)
+func parseTime(value string) int64 {
+	if value == "" {
+		return 0
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		slog.Debug("Could not parse GitHub timestamp", "value", value, "error", err)
+		return 0
+	}
+	return t.UnixMilli()
+}
@@ -0,0 +1,253 @@
+package github
+
+import (
+	"context"
+	"fc-pr-tracker/internal/bitbucket"
+	"fc-pr-tracker/internal/config"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestClient(handler http.HandlerFunc) *Client {
+	ts := httptest.NewServer(handler)
+	cfg := &config.Config{
+		GitHub: config.GitHubConfig{
+			Token: "test-token",
+			Owner: "acme",
+		},
+	}
+	return &Client{Config: cfg, Client: ts.Client(), BaseURL: ts.URL}
+}
+
+func TestNewClient(t *testing.T) {
+	client := NewClient(&config.Config{})
+	if client.BaseURL != DefaultBaseURL {
+		t.Errorf("Expected base URL '%s', got '%s'", DefaultBaseURL, client.BaseURL)
+	}
+
+	cfg := &config.Config{GitHub: config.GitHubConfig{BaseURL: "https://ghe.example.com/api/v3/"}}
+	client = NewClient(cfg)
+	if client.BaseURL != "https://ghe.example.com/api/v3" {
+		t.Errorf("Expected GitHub Enterprise base URL, got '%s'", client.BaseURL)
+	}
+}
+
+func TestClient_TestConnection(t *testing.T) {
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("Expected bearer token header, got '%s'", got)
+		}
+		w.Write([]byte(`{"login":"bot"}`))
+	})
+	if err := client.TestConnection(context.Background()); err != nil {
+		t.Errorf("Expected success, got error: %v", err)
+	}
+
+	failing := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+		w.Write([]byte(`{"message":"Bad credentials"}`))
+	})
+	if err := failing.TestConnection(context.Background()); err == nil {
+		t.Error("Expected error for bad credentials, got nil")
+	}
+}
+
+func TestClient_ListOpenPRs_Pagination(t *testing.T) {
+	var serverURL string
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/service/pulls" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`[{"number":13,"title":"Second","state":"open","created_at":"2024-01-02T10:00:00Z","updated_at":"2024-01-02T10:00:00Z","user":{"login":"bob"}}]`))
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s/repos/acme/service/pulls?state=open&page=2>; rel="next", <%s/repos/acme/service/pulls?state=open&page=2>; rel="last"`, serverURL, serverURL))
+		w.Write([]byte(`[{"number":12,"title":"First","body":"desc","state":"open",
+			"created_at":"2024-01-01T10:00:00Z","updated_at":"2024-01-01T12:00:00Z",
+			"html_url":"https://github.com/acme/service/pull/12","user":{"login":"alice","email":"alice@example.com"},"base":{"ref":"main"}}]`))
+	})
+	serverURL = client.BaseURL
+
+	prs, err := client.ListOpenPRs(context.Background(), "service")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(prs) != 2 {
+		t.Fatalf("Expected 2 PRs, got %+v", prs)
+	}
+	pr := prs[0]
+	if pr.ID != 12 || pr.Title != "First" || pr.Description != "desc" || !pr.Open {
+		t.Errorf("Unexpected PR mapping: %+v", pr)
+	}
+	if pr.Author.User.Username != "alice" || pr.Author.User.Email != "alice@example.com" {
+		t.Errorf("Expected author alice with her public email, got %+v", pr.Author.User)
+	}
+	if pr.TargetBranch() != "main" {
+		t.Errorf("Expected target branch main, got %q", pr.TargetBranch())
+	}
+	if len(pr.Links.Self) != 1 || pr.Links.Self[0].Href != "https://github.com/acme/service/pull/12" {
+		t.Errorf("Unexpected link mapping: %+v", pr.Links)
+	}
+	if pr.UpdatedDate != time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).UnixMilli() {
+		t.Errorf("Unexpected updated date %d", pr.UpdatedDate)
+	}
+}
+
+func TestClient_ListOpenPRs_OwnerInRepoName(t *testing.T) {
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/other-org/tool/pulls" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`[]`))
+	})
+	if _, err := client.ListOpenPRs(context.Background(), "other-org/tool"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestClient_ListOpenPRs_HTTPError(t *testing.T) {
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+	})
+	if _, err := client.ListOpenPRs(context.Background(), "service"); err == nil {
+		t.Error("Expected error for HTTP 404, got nil")
+	}
+}
+
+func TestClient_GetParticipants(t *testing.T) {
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/service/pulls/12/requested_reviewers":
+			w.Write([]byte(`{"users":[{"login":"carol"},{"login":"dave"}],"teams":[]}`))
+		case "/repos/acme/service/pulls/12/reviews":
+			w.Write([]byte(`[
+				{"id":1,"user":{"login":"erin"},"state":"APPROVED","submitted_at":"2024-01-02T10:00:00Z"},
+				{"id":2,"user":{"login":"erin"},"state":"COMMENTED","submitted_at":"2024-01-03T10:00:00Z"},
+				{"id":3,"user":{"login":"frank"},"state":"APPROVED","submitted_at":"2024-01-02T10:00:00Z"},
+				{"id":4,"user":{"login":"frank"},"state":"CHANGES_REQUESTED","submitted_at":"2024-01-04T10:00:00Z"},
+				{"id":5,"user":{"login":"dave"},"state":"APPROVED","submitted_at":"2024-01-01T10:00:00Z"},
+				{"id":6,"user":{"login":"grace"},"state":"COMMENTED","submitted_at":"2024-01-01T10:00:00Z"}]`))
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	})
+
+	participants, err := client.GetParticipants(context.Background(), "service", 12)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	byLogin := make(map[string]string)
+	for _, p := range participants {
+		byLogin[p.User.Username] = p.Role + "/" + p.Status
+	}
+	expected := map[string]string{
+		"erin":  "REVIEWER/APPROVED",
+		"frank": "REVIEWER/NEEDS_WORK",
+		"dave":  "REVIEWER/UNAPPROVED", // re-requested after approving
+		"carol": "REVIEWER/UNAPPROVED",
+		"grace": "PARTICIPANT/UNAPPROVED",
+	}
+	if len(byLogin) != len(expected) {
+		t.Fatalf("Expected %d participants, got %+v", len(expected), byLogin)
+	}
+	for login, state := range expected {
+		if byLogin[login] != state {
+			t.Errorf("Expected %s to be %s, got %s", login, state, byLogin[login])
+		}
+	}
+
+	approved, total := bitbucket.CountApprovals(participants)
+	if approved != 1 || total != 4 {
+		t.Errorf("Expected 1/4 approvals, got %d/%d", approved, total)
+	}
+	if bitbucket.IsPRApproved(participants) {
+		t.Error("Expected PR not to be approved")
+	}
+}
+
+func TestClient_GetParticipants_RequestedTeams(t *testing.T) {
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/service/pulls/12/requested_reviewers":
+			w.Write([]byte(`{"users":[],"teams":[{"id":7,"name":"Platform","slug":"platform"}]}`))
+		case "/repos/acme/service/pulls/12/reviews":
+			w.Write([]byte(`[]`))
+		default:
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+	})
+
+	participants, err := client.GetParticipants(context.Background(), "service", 12)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(participants) != 1 {
+		t.Fatalf("Expected the requested team as the only participant, got %+v", participants)
+	}
+	p := participants[0]
+	if p.User.DisplayName != "Platform" || p.User.Username != "platform" || p.User.Type != "Team" || p.Role != "REVIEWER" || p.Approved {
+		t.Errorf("Expected a pending Platform team reviewer, got %+v", p)
+	}
+	if approved, total := bitbucket.CountApprovals(participants); approved != 0 || total != 1 {
+		t.Errorf("Expected 0/1 approvals, got %d/%d", approved, total)
+	}
+}
+
+func TestClient_GetComments(t *testing.T) {
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/service/issues/12/timeline" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`[
+			{"id":1,"event":"commented","body":"ping","created_at":"2024-01-02T10:00:00Z","updated_at":"2024-01-02T11:00:00Z","user":{"login":"alice"}},
+			{"id":2,"event":"reviewed","body":"ok","submitted_at":"2024-01-05T10:00:00Z","user":{"login":"bob"}},
+			{"event":"committed","committer":{"name":"Alice","date":"2024-01-03T10:00:00Z"}},
+			{"id":3,"event":"labeled","created_at":"2024-01-04T10:00:00Z","actor":{"login":"carol"}},
+			{"event":"mentioned"}]`))
+	})
+
+	comments, err := client.GetComments(context.Background(), "service", 12)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(comments) != 4 {
+		t.Fatalf("Expected 4 dated timeline events, got %d", len(comments))
+	}
+	if comments[0].UpdatedDate != time.Date(2024, 1, 2, 11, 0, 0, 0, time.UTC).UnixMilli() {
+		t.Errorf("Expected comment updated date to be used, got %d", comments[0].UpdatedDate)
+	}
+	if comments[3].User.Username != "carol" {
+		t.Errorf("Expected actor to be used as author, got %+v", comments[3].User)
+	}
+
+	pr := pullRequest{CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-01T10:00:00Z"}.toModel()
+	lastActivity := bitbucket.GetLastActivity(pr, comments)
+	expected := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC).Local().Format(time.RFC3339)
+	if lastActivity != expected {
+		t.Errorf("Expected last activity %s, got %s", expected, lastActivity)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{`<https://api.github.com/x?page=2>; rel="next", <https://api.github.com/x?page=5>; rel="last"`, "https://api.github.com/x?page=2"},
+		{`<https://api.github.com/x?page=1>; rel="prev"`, ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := nextLink(tt.header); got != tt.expected {
+			t.Errorf("nextLink(%q) = %q, expected %q", tt.header, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"fc-pr-tracker/internal/config"
+	"fc-pr-tracker/pkg/models"
+)
+
+// DefaultBaseURL is the gitlab.com root; self-hosted instances use their own host
+const DefaultBaseURL = "https://gitlab.com"
+
+// Client represents a GitLab REST API (v4) client
+type Client struct {
+	Config  *config.Config
+	Client  *http.Client
+	BaseURL string
+}
+
+// NewClient creates a new GitLab client
+func NewClient(cfg *config.Config) *Client {
+	baseURL := strings.TrimRight(cfg.GitLab.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		Config:  cfg,
+		Client:  &http.Client{Timeout: 15 * time.Second},
+		BaseURL: baseURL,
+	}
+}
+
+// Name identifies the provider in logs
+func (c *Client) Name() string {
+	return "gitlab"
+}
+
+// Host returns the GitLab API host the client talks to
+func (c *Client) Host() string {
+	if u, err := url.Parse(c.BaseURL); err == nil {
+		return u.Host
+	}
+	return c.BaseURL
+}
+
+// TestConnection checks if the GitLab API is reachable and the token is valid
+func (c *Client) TestConnection(ctx context.Context) error {
+	if _, err := c.get(ctx, c.BaseURL+"/api/v4/user", nil); err != nil {
+		return fmt.Errorf("GitLab connection test failed: %v", err)
+	}
+	return nil
+}
+
+// ListOpenPRs fetches open merge requests for a project ("group/project" or "project" in the configured group)
+func (c *Client) ListOpenPRs(ctx context.Context, repo string) ([]models.PullRequest, error) {
+	var prs []models.PullRequest
+	next := fmt.Sprintf("%s/merge_requests?state=opened&per_page=100", c.projectURL(repo))
+
+	for next != "" {
+		var page []mergeRequest
+		nextPage, err := c.get(ctx, next, &page)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching merge requests: %v", err)
+		}
+		for _, mr := range page {
+			prs = append(prs, mr.toModel())
+		}
+		next = withPage(next, nextPage)
+	}
+	return prs, nil
+}
+
+// GetParticipants combines the MR reviewers with its approvals. Approvers that were
+// not assigned as reviewers are reported as approving reviewers too.
+func (c *Client) GetParticipants(ctx context.Context, repo string, prID int) ([]models.Participant, error) {
+	slog.Info("Fetching participants for MR", "pr_id", prID, "repo", repo)
+
+	var mr mergeRequest
+	if _, err := c.get(ctx, fmt.Sprintf("%s/merge_requests/%d", c.projectURL(repo), prID), &mr); err != nil {
+		return nil, fmt.Errorf("error fetching merge request: %v", err)
+	}
+
+	var approvals approvalState
+	if _, err := c.get(ctx, fmt.Sprintf("%s/merge_requests/%d/approvals", c.projectURL(repo), prID), &approvals); err != nil {
+		return nil, fmt.Errorf("error fetching approvals: %v", err)
+	}
+
+	approvedBy := make(map[string]bool)
+	for _, a := range approvals.ApprovedBy {
+		approvedBy[a.User.Username] = true
+	}
+
+	var participants []models.Participant
+	seen := make(map[string]bool)
+	for _, u := range mr.Reviewers {
+		seen[u.Username] = true
+		participants = append(participants, u.toParticipant(approvedBy[u.Username]))
+	}
+	for _, a := range approvals.ApprovedBy {
+		if !seen[a.User.Username] {
+			seen[a.User.Username] = true
+			participants = append(participants, a.User.toParticipant(true))
+		}
+	}
+	return participants, nil
+}
+
+// GetComments fetches all notes (discussion and system notes) of a merge request
+func (c *Client) GetComments(ctx context.Context, repo string, prID int) ([]models.Comment, error) {
+	var comments []models.Comment
+	next := fmt.Sprintf("%s/merge_requests/%d/notes?per_page=100&sort=asc", c.projectURL(repo), prID)
+	slog.Info("Fetching notes for MR", "pr_id", prID, "repo", repo, "url", next)
+
+	for next != "" {
+		var page []note
+		nextPage, err := c.get(ctx, next, &page)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching notes: %v", err)
+		}
+		for _, n := range page {
+			comments = append(comments, n.toModel())
+		}
+		next = withPage(next, nextPage)
+	}
+	return comments, nil
+}
+
+// projectURL builds the API URL of a project, prefixing the configured group when needed
+func (c *Client) projectURL(repo string) string {
+	if !strings.Contains(repo, "/") && c.Config.GitLab.Group != "" {
+		repo = c.Config.GitLab.Group + "/" + repo
+	}
+	return fmt.Sprintf("%s/api/v4/projects/%s", c.BaseURL, url.PathEscape(repo))
+}
+
+// get performs an authenticated GET request, decodes the JSON body into out (if not nil)
+// and returns the X-Next-Page header, empty on the last page
+func (c *Client) get(ctx context.Context, rawURL string, out interface{}) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if c.Config.GitLab.Token != "" {
+		req.Header.Set("PRIVATE-TOKEN", c.Config.GitLab.Token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		slog.Error("GitLab request failed", "status", resp.Status, "url", rawURL, "body", string(body))
+		return "", fmt.Errorf("%s (URL: %s, Body: %s)", resp.Status, rawURL, string(body))
+	}
+	if out != nil {
+		if err := json.Unmarshal(body, out); err != nil {
+			return "", err
+		}
+	}
+	return resp.Header.Get("X-Next-Page"), nil
+}
+
+// withPage returns rawURL with its page parameter set, or "" when there is no next page
+func withPage(rawURL, page string) string {
+	if page == "" {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	q.Set("page", page)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// Response types for the GitLab REST API
+type user struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	State    string `json:"state"`
+}
+
+type mergeRequest struct {
+	IID          int    `json:"iid"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	State        string `json:"state"`
+	CreatedAt    string `json:"created_at"`
+	UpdatedAt    string `json:"updated_at"`
+	WebURL       string `json:"web_url"`
+	TargetBranch string `json:"target_branch"`
+	Author       user   `json:"author"`
+	Reviewers    []user `json:"reviewers"`
+}
+
+type approvalState struct {
+	ApprovedBy []struct {
+		User user `json:"user"`
+	} `json:"approved_by"`
+}
+
+type note struct {
+	ID        int    `json:"id"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	System    bool   `json:"system"`
+	Author    user   `json:"author"`
+}
+
+// toModel maps a merge request into the shared model, using the project-scoped IID as ID
+func (m mergeRequest) toModel() models.PullRequest {
+	pr := models.PullRequest{
+		ID:          m.IID,
+		Title:       m.Title,
+		Description: m.Description,
+		State:       strings.ToUpper(m.State),
+		Open:        m.State == "opened",
+		Closed:      m.State != "opened",
+		CreatedDate: parseTime(m.CreatedAt),
+		UpdatedDate: parseTime(m.UpdatedAt),
+	}
+	pr.Author.User.DisplayName = m.Author.Name
+	pr.Author.User.Username = m.Author.Username
+	pr.ToRef.DisplayID = m.TargetBranch
+	pr.Author.Role = "AUTHOR"
+	if m.WebURL != "" {
+		pr.Links.Self = append(pr.Links.Self, struct {
+			Href string `json:"href"`
+		}{Href: m.WebURL})
+	}
+	for _, r := range m.Reviewers {
+		pr.Participants = append(pr.Participants, r.toParticipant(false))
+	}
+	return pr
+}
+
+// toParticipant maps a user into a reviewer participant
+func (u user) toParticipant(approved bool) models.Participant {
+	var p models.Participant
+	p.User.DisplayName = u.Name
+	p.User.Username = u.Username
+	p.User.Slug = u.Username
+	p.User.ID = u.ID
+	p.User.Active = u.State == "" || u.State == "active"
+	p.Role = "REVIEWER"
+	p.Approved = approved
+	p.Status = "UNAPPROVED"
+	if approved {
+		p.Status = "APPROVED"
+	}
+	return p
+}
+
+// toModel maps a note into a Comment
+func (n note) toModel() models.Comment {
+	comment := models.Comment{
+		ID:          n.ID,
+		Content:     n.Body,
+		CreatedDate: parseTime(n.CreatedAt),
+		UpdatedDate: parseTime(n.UpdatedAt),
+	}
+	if comment.UpdatedDate == 0 {
+		comment.UpdatedDate = comment.CreatedDate
+	}
+	comment.User.DisplayName = n.Author.Name
+	comment.User.Username = n.Author.Username
+	return comment
+}
+
+// parseTime converts a GitLab ISO-8601 timestamp into Unix milliseconds (0 if invalid)
+func parseTime(value string) int64 {
+	if value == "" {
+		return 0
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		slog.Debug("Could not parse GitLab timestamp", "value", value, "error", err)
+		return 0
+	}
+	return t.UnixMilli()
+}
@@ -0,0 +1,189 @@
+package gitlab
+
+import (
+	"context"
+	"fc-pr-tracker/internal/bitbucket"
+	"fc-pr-tracker/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestClient(handler http.HandlerFunc) *Client {
+	ts := httptest.NewServer(handler)
+	cfg := &config.Config{
+		GitLab: config.GitLabConfig{
+			Token: "test-token",
+			Group: "platform",
+		},
+	}
+	return &Client{Config: cfg, Client: ts.Client(), BaseURL: ts.URL}
+}
+
+func TestNewClient(t *testing.T) {
+	client := NewClient(&config.Config{})
+	if client.BaseURL != DefaultBaseURL {
+		t.Errorf("Expected base URL '%s', got '%s'", DefaultBaseURL, client.BaseURL)
+	}
+
+	cfg := &config.Config{GitLab: config.GitLabConfig{BaseURL: "https://gitlab.example.com/"}}
+	client = NewClient(cfg)
+	if client.BaseURL != "https://gitlab.example.com" {
+		t.Errorf("Expected self-hosted base URL, got '%s'", client.BaseURL)
+	}
+}
+
+func TestClient_TestConnection(t *testing.T) {
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/user" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("PRIVATE-TOKEN"); got != "test-token" {
+			t.Errorf("Expected private token header, got '%s'", got)
+		}
+		w.Write([]byte(`{"username":"bot"}`))
+	})
+	if err := client.TestConnection(context.Background()); err != nil {
+		t.Errorf("Expected success, got error: %v", err)
+	}
+
+	failing := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+	})
+	if err := failing.TestConnection(context.Background()); err == nil {
+		t.Error("Expected error for unauthorized, got nil")
+	}
+}
+
+func TestClient_ListOpenPRs_Pagination(t *testing.T) {
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/platform%2Fapi/merge_requests" {
+			t.Errorf("Unexpected path %s", r.URL.EscapedPath())
+		}
+		if r.URL.Query().Get("state") != "opened" {
+			t.Errorf("Expected state=opened, got %s", r.URL.RawQuery)
+		}
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`[{"iid":4,"title":"Second","state":"opened","created_at":"2024-01-02T10:00:00.000Z","updated_at":"2024-01-02T10:00:00.000Z","author":{"username":"bob","name":"Bob"}}]`))
+			return
+		}
+		w.Header().Set("X-Next-Page", "2")
+		w.Write([]byte(`[{"iid":3,"title":"First","description":"desc","state":"opened",
+			"created_at":"2024-01-01T10:00:00.000Z","updated_at":"2024-01-01T12:00:00.000Z",
+			"web_url":"https://gitlab.example.com/platform/api/-/merge_requests/3","target_branch":"release/1.2",
+			"author":{"username":"alice","name":"Alice"},
+			"reviewers":[{"id":9,"username":"carol","name":"Carol"}]}]`))
+	})
+
+	prs, err := client.ListOpenPRs(context.Background(), "api")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(prs) != 2 {
+		t.Fatalf("Expected 2 merge requests, got %+v", prs)
+	}
+	pr := prs[0]
+	if pr.ID != 3 || pr.Title != "First" || !pr.Open || pr.State != "OPENED" {
+		t.Errorf("Unexpected MR mapping: %+v", pr)
+	}
+	if pr.Author.User.DisplayName != "Alice" || pr.Author.User.Username != "alice" {
+		t.Errorf("Unexpected author mapping: %+v", pr.Author.User)
+	}
+	if pr.TargetBranch() != "release/1.2" {
+		t.Errorf("Expected target branch release/1.2, got %q", pr.TargetBranch())
+	}
+	if len(pr.Links.Self) != 1 || pr.Links.Self[0].Href != "https://gitlab.example.com/platform/api/-/merge_requests/3" {
+		t.Errorf("Unexpected link mapping: %+v", pr.Links)
+	}
+	if len(pr.Participants) != 1 || pr.Participants[0].Role != "REVIEWER" {
+		t.Errorf("Expected reviewer participant, got %+v", pr.Participants)
+	}
+	if pr.UpdatedDate != time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).UnixMilli() {
+		t.Errorf("Unexpected updated date %d", pr.UpdatedDate)
+	}
+}
+
+func TestClient_ListOpenPRs_HTTPError(t *testing.T) {
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	})
+	if _, err := client.ListOpenPRs(context.Background(), "other-group/api"); err == nil {
+		t.Error("Expected error for HTTP 500, got nil")
+	}
+}
+
+func TestClient_GetParticipants(t *testing.T) {
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects/platform%2Fapi/merge_requests/3":
+			w.Write([]byte(`{"iid":3,"reviewers":[{"id":1,"username":"carol","name":"Carol"},{"id":2,"username":"dave","name":"Dave"}]}`))
+		case "/api/v4/projects/platform%2Fapi/merge_requests/3/approvals":
+			w.Write([]byte(`{"approved_by":[{"user":{"id":1,"username":"carol","name":"Carol"}},{"user":{"id":3,"username":"erin","name":"Erin"}}]}`))
+		default:
+			t.Errorf("Unexpected path %s", r.URL.EscapedPath())
+		}
+	})
+
+	participants, err := client.GetParticipants(context.Background(), "api", 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(participants) != 3 {
+		t.Fatalf("Expected 3 participants, got %+v", participants)
+	}
+
+	expected := map[string]bool{"carol": true, "dave": false, "erin": true}
+	for _, p := range participants {
+		if p.Approved != expected[p.User.Username] {
+			t.Errorf("Expected %s approved=%v, got %v", p.User.Username, expected[p.User.Username], p.Approved)
+		}
+	}
+
+	approved, total := bitbucket.CountApprovals(participants)
+	if approved != 2 || total != 3 {
+		t.Errorf("Expected 2/3 approvals, got %d/%d", approved, total)
+	}
+	if bitbucket.IsPRApproved(participants) {
+		t.Error("Expected MR not to be approved while a reviewer is pending")
+	}
+}
+
+func TestClient_GetComments(t *testing.T) {
+	client := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/platform%2Fapi/merge_requests/3/notes" {
+			t.Errorf("Unexpected path %s", r.URL.EscapedPath())
+		}
+		w.Write([]byte(`[
+			{"id":1,"body":"please rebase","created_at":"2024-01-02T10:00:00.000Z","updated_at":"2024-01-03T10:00:00.000Z","author":{"username":"carol","name":"Carol"}},
+			{"id":2,"body":"added 1 commit","system":true,"created_at":"2024-01-04T10:00:00.000Z","author":{"username":"alice","name":"Alice"}}]`))
+	})
+
+	comments, err := client.GetComments(context.Background(), "api", 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(comments) != 2 {
+		t.Fatalf("Expected 2 notes, got %d", len(comments))
+	}
+	if comments[1].UpdatedDate != comments[1].CreatedDate {
+		t.Error("Expected created date to be used when updated date is missing")
+	}
+
+	pr := mergeRequest{CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-01T10:00:00Z"}.toModel()
+	lastActivity := bitbucket.GetLastActivity(pr, comments)
+	expected := time.Date(2024, 1, 4, 10, 0, 0, 0, time.UTC).Local().Format(time.RFC3339)
+	if lastActivity != expected {
+		t.Errorf("Expected last activity %s, got %s", expected, lastActivity)
+	}
+}
+
+func TestWithPage(t *testing.T) {
+	if got := withPage("https://gitlab.com/api/v4/projects/1/notes?per_page=100", ""); got != "" {
+		t.Errorf("Expected empty URL on last page, got %s", got)
+	}
+	got := withPage("https://gitlab.com/api/v4/projects/1/notes?per_page=100&page=1", "2")
+	if got != "https://gitlab.com/api/v4/projects/1/notes?page=2&per_page=100" {
+		t.Errorf("Unexpected next page URL %s", got)
+	}
+}
@@ -0,0 +1,158 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"fc-pr-tracker/pkg/models"
+)
+
+// generateAdaptiveCard creates the Teams payload for Workflows webhooks: a message with an
+// Adaptive Card holding a table per repository. Reviewers who have not approved are mentioned
+// when their email address is known, and every PR has a button opening it.
+func (t *TeamsNotifier) generateAdaptiveCard(report *models.Report) ([]byte, error) {
+	title := "🚨 Stale Pull Requests Alert"
+	if report.Tier != "" {
+		title += " · Escalation: " + report.Tier
+	}
+
+	body := []map[string]interface{}{
+		{"type": "TextBlock", "text": title, "size": "Large", "weight": "Bolder", "color": "Attention", "wrap": true},
+		{"type": "TextBlock", "text": fmt.Sprintf("%d pull requests have been inactive for %s or more", len(report.PRs), report.StaleThreshold()), "wrap": true},
+	}
+
+	mentions := newMentions()
+	for _, group := range report.ByProject() {
+		for _, repo := range group.Repos {
+			heading := fmt.Sprintf("Repository: %s", repo.Repo)
+			if group.Project != "" {
+				heading = fmt.Sprintf("Project: %s · Repository: %s", group.Project, repo.Repo)
+			}
+			rows := []map[string]interface{}{
+				tableRow(textCell("Pull request"), textCell("Author"), textCell("Approvals"), textCell("Idle"), textCell("Waiting on"), textCell("")),
+			}
+			for _, pr := range repo.PRs {
+				rows = append(rows, tableRow(
+					textCell(fmt.Sprintf("#%d %s", pr.ID, pr.Title)),
+					textCell(pr.Author.User.DisplayName),
+					textCell(fmt.Sprintf("%d/%d", pr.Approvals, pr.Reviewers)),
+					textCell(report.Days(pr.DaysInactive)),
+					textCell(mentions.pending(pr)),
+					openButton(pr.Link()),
+				))
+			}
+			body = append(body,
+				map[string]interface{}{"type": "TextBlock", "text": heading, "weight": "Bolder", "spacing": "Large", "wrap": true},
+				map[string]interface{}{
+					"type":             "Table",
+					"firstRowAsHeader": true,
+					"gridStyle":        "accent",
+					"columns": []map[string]interface{}{
+						{"width": 4}, {"width": 2}, {"width": 1}, {"width": 1}, {"width": 2}, {"width": 1},
+					},
+					"rows": rows,
+				})
+		}
+	}
+
+	facts := []map[string]interface{}{
+		{"title": "Total Stale PRs", "value": fmt.Sprintf("%d", len(report.PRs))},
+		{"title": "Stale Threshold", "value": report.StaleThreshold()},
+	}
+	if report.Tier != "" {
+		facts = append(facts, map[string]interface{}{"title": "Escalation Tier", "value": report.Tier})
+	}
+	body = append(body, map[string]interface{}{"type": "FactSet", "facts": facts, "spacing": "Large"})
+
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.5",
+		"body":    body,
+		"msteams": map[string]interface{}{
+			"width":    "Full",
+			"entities": mentions.entities,
+		},
+	}
+
+	payload := map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"contentUrl":  nil,
+				"content":     card,
+			},
+		},
+	}
+
+	return json.Marshal(payload)
+}
+
+// tableRow builds an Adaptive Card table row from its cells
+func tableRow(cells ...map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "TableRow", "cells": cells}
+}
+
+// textCell builds a table cell holding a wrapped text block
+func textCell(text string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":  "TableCell",
+		"items": []map[string]interface{}{{"type": "TextBlock", "text": text, "wrap": true}},
+	}
+}
+
+// openButton builds a table cell with a button opening url, or an empty cell without one
+func openButton(url string) map[string]interface{} {
+	if url == "" {
+		return textCell("")
+	}
+	return map[string]interface{}{
+		"type": "TableCell",
+		"items": []map[string]interface{}{{
+			"type":    "ActionSet",
+			"actions": []map[string]interface{}{{"type": "Action.OpenUrl", "title": "Open", "url": url}},
+		}},
+	}
+}
+
+// mentions collects the mention entities of a card, one per reviewer
+type mentions struct {
+	entities []map[string]interface{}
+	seen     map[string]bool
+}
+
+func newMentions() *mentions {
+	return &mentions{entities: []map[string]interface{}{}, seen: map[string]bool{}}
+}
+
+// pending lists the reviewers of pr who have not approved. Those with an email address are
+// mentioned, so Teams notifies them; the others are listed by name.
+func (m *mentions) pending(pr models.EnrichedPR) string {
+	var names []string
+	for _, p := range pr.Participants {
+		if p.Role != "REVIEWER" || p.Approved {
+			continue
+		}
+		name := p.User.DisplayName
+		if name == "" {
+			name = p.User.Username
+		}
+		if p.User.Email == "" {
+			names = append(names, name)
+			continue
+		}
+		text := "<at>" + name + "</at>"
+		names = append(names, text)
+		if id := strings.ToLower(p.User.Email); !m.seen[id] {
+			m.seen[id] = true
+			m.entities = append(m.entities, map[string]interface{}{
+				"type":      "mention",
+				"text":      text,
+				"mentioned": map[string]interface{}{"id": p.User.Email, "name": name},
+			})
+		}
+	}
+	return strings.Join(names, ", ")
+}
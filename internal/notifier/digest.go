@@ -0,0 +1,306 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"text/template"
+
+	"fc-pr-tracker/internal/config"
+	"fc-pr-tracker/internal/templates"
+	"fc-pr-tracker/pkg/models"
+)
+
+// DigestNotifier emails every reviewer the stale PRs waiting on their approval and every
+// author their own stale PRs, through the SMTP server of the email notifier
+type DigestNotifier struct {
+	email   *EmailNotifier
+	digests config.DigestsConfig
+	limit   *DigestLimit // shared by the digest notifiers of every tier, nil for none
+}
+
+// NewDigestNotifier creates a new digest notifier
+func NewDigestNotifier(cfg *config.Config) *DigestNotifier {
+	return &DigestNotifier{email: NewEmailNotifier(cfg), digests: cfg.Notifiers.Digests}
+}
+
+// ShareLimit makes the notifier count its digests against limit, along with the other
+// notifiers sharing it
+func (d *DigestNotifier) ShareLimit(limit *DigestLimit) {
+	d.limit = limit
+}
+
+// DigestLimit caps the digests sent in one cycle by the digest notifiers of every tier
+type DigestLimit struct {
+	mu   sync.Mutex
+	max  int // 0 for no limit
+	sent int
+}
+
+// NewDigestLimit creates a limit of max digests per cycle, 0 for no limit
+func NewDigestLimit(max int) *DigestLimit {
+	return &DigestLimit{max: max}
+}
+
+// Reset starts a new cycle
+func (l *DigestLimit) Reset() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sent = 0
+}
+
+// take returns the digests still within the limit of the cycle and counts them as sent
+func (l *DigestLimit) take(digests []digest) []digest {
+	if l == nil {
+		return digests
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.max > 0 && l.sent+len(digests) > l.max {
+		keep := l.max - l.sent
+		if keep < 0 {
+			keep = 0
+		}
+		slog.Warn("Digest recipients capped for this cycle", "max_recipients", l.max, "skipped", len(digests)-keep)
+		digests = digests[:keep]
+	}
+	l.sent += len(digests)
+	return digests
+}
+
+// digest holds the stale PRs of one recipient
+type digest struct {
+	Name     string
+	Email    string
+	Reviews  []models.EnrichedPR // PRs waiting on the recipient's approval
+	Authored []models.EnrichedPR // stale PRs the recipient opened
+}
+
+// Notify sends every recipient their digest. A failed delivery does not stop the others.
+func (d *DigestNotifier) Notify(ctx context.Context, report *models.Report) error {
+	digests := d.limit.take(d.buildDigests(report))
+	failed := 0
+	var firstErr error
+	for _, dg := range digests {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		content, err := d.compose(report, dg)
+		if err == nil {
+			err = d.email.sendEmail(ctx, []string{dg.Email}, content)
+		}
+		if err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to send %d of %d digests: %v", failed, len(digests), firstErr)
+	}
+	return nil
+}
+
+// Name identifies the notifier in logs and previews
+func (d *DigestNotifier) Name() string {
+	return "digest"
+}
+
+// Preview writes the message of every digest, headers included, to w. Previews do not
+// count against the limit shared by the digest notifiers.
+func (d *DigestNotifier) Preview(w io.Writer, report *models.Report) error {
+	for i, dg := range d.buildDigests(report) {
+		content, err := d.compose(report, dg)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			io.WriteString(w, "\n\n")
+		}
+		if _, err := io.WriteString(w, d.email.message([]string{dg.Email}, content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildDigests gathers the digest of every recipient in the order they first appear in the
+// report, leaving out opted-out recipients, those without an email address and those beyond
+// the max_recipients of the tier
+func (d *DigestNotifier) buildDigests(report *models.Report) []digest {
+	var digests []digest
+	index := map[string]int{}
+	noEmail := map[string]bool{}
+	add := func(name, email string, pr models.EnrichedPR, review bool) {
+		if email == "" {
+			noEmail[name] = true
+			return
+		}
+		if d.optedOut(email) {
+			return
+		}
+		key := strings.ToLower(email)
+		i, ok := index[key]
+		if !ok {
+			i = len(digests)
+			index[key] = i
+			digests = append(digests, digest{Name: name, Email: email})
+		}
+		if review {
+			digests[i].Reviews = append(digests[i].Reviews, pr)
+		} else {
+			digests[i].Authored = append(digests[i].Authored, pr)
+		}
+	}
+
+	for _, pr := range report.PRs {
+		for _, p := range pr.Participants {
+			if d.digests.Reviewers && p.Role == "REVIEWER" && !p.Approved && !d.optedOut(p.User.Username) {
+				add(p.User.DisplayName, p.User.Email, pr, true)
+			}
+		}
+		if d.digests.Authors && !d.optedOut(pr.Author.User.Username) {
+			name, email := authorEmail(pr)
+			add(name, email, pr, false)
+		}
+	}
+
+	if len(noEmail) > 0 {
+		// Only Bitbucket Server returns the email of every participant
+		slog.Warn("Digest recipients without an email address skipped", "skipped", len(noEmail), "recipients", len(digests))
+	}
+	if limit := d.digests.MaxRecipients; limit > 0 && len(digests) > limit {
+		slog.Warn("Digest recipients capped", "max_recipients", limit, "skipped", len(digests)-limit)
+		digests = digests[:limit]
+	}
+	return digests
+}
+
+// optedOut reports whether the email or username is in the opt-out list
+func (d *DigestNotifier) optedOut(id string) bool {
+	for _, o := range d.digests.OptOut {
+		if id != "" && strings.EqualFold(o, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorEmail finds the name and email of the PR author among its participants, falling back
+// to the author of the PR, which is the only source of the address for some providers
+func authorEmail(pr models.EnrichedPR) (string, string) {
+	name, email := pr.Author.User.DisplayName, pr.Author.User.Email
+	for _, p := range pr.Participants {
+		if p.Role == "AUTHOR" || (p.User.Username != "" && p.User.Username == pr.Author.User.Username) {
+			if p.User.DisplayName != "" {
+				name = p.User.DisplayName
+			}
+			if p.User.Email != "" {
+				email = p.User.Email
+			}
+			break
+		}
+	}
+	return name, email
+}
+
+// digestData is what digests are rendered with: the data of the report and the PRs of one recipient
+type digestData struct {
+	templates.Data
+	Name     string
+	Reviews  []models.EnrichedPR
+	Authored []models.EnrichedPR
+}
+
+// compose renders the subject, plain-text and HTML bodies of the digest dg
+func (d *DigestNotifier) compose(report *models.Report, dg digest) (emailContent, error) {
+	total := len(dg.Reviews) + len(dg.Authored)
+	content := emailContent{subject: fmt.Sprintf("Stale Pull Requests Digest - %d PRs need your attention", total)}
+	if report.Tier != "" {
+		content.subject = fmt.Sprintf("[%s] %s", report.Tier, content.subject)
+	}
+	data := digestData{Data: templates.NewData(report), Name: dg.Name, Reviews: dg.Reviews, Authored: dg.Authored}
+
+	var text strings.Builder
+	if err := digestText.Execute(&text, data); err != nil {
+		return emailContent{}, fmt.Errorf("error generating digest body: %v", err)
+	}
+	var html strings.Builder
+	t := htmltemplate.Must(htmltemplate.New("digest").Funcs(templates.Funcs(d.email.location, d.email.calendar)).Parse(digestHTML))
+	if err := t.Execute(&html, data); err != nil {
+		return emailContent{}, fmt.Errorf("error generating HTML digest body: %v", err)
+	}
+	content.text, content.html = text.String(), html.String()
+	return content, nil
+}
+
+var digestText = template.Must(template.New("digest").Parse(`
+Stale Pull Requests Digest{{if .Tier}} - Escalation: {{.Tier}}{{end}}
+
+Hello{{if .Name}} {{.Name}}{{end}},
+{{if .Reviews}}
+These {{len .Reviews}} pull requests are waiting on your review and have been
+inactive for {{.Threshold}} or more:
+{{range .Reviews}}
+- {{.Ref.Short}}: {{.Title}}
+  Author: {{.Author.User.DisplayName}} ({{.Author.User.Username}})
+{{with .Link}}  Link: {{.}}
+{{end}}  Approvals: {{.Approvals}}/{{.Reviewers}} reviewers
+{{end}}{{end}}{{if .Authored}}
+These {{len .Authored}} pull requests of yours have been inactive for
+{{.Threshold}} or more:
+{{range .Authored}}
+- {{.Ref.Short}}: {{.Title}}
+{{with .Link}}  Link: {{.}}
+{{end}}  Approvals: {{.Approvals}}/{{.Reviewers}} reviewers
+{{end}}{{end}}
+This is an automated notification from the PR Tracker service.
+`))
+
+const digestHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="UTF-8">
+<title>Stale Pull Requests Digest</title>
+</head>
+<body style="margin:0;padding:16px;font-family:Segoe UI,Helvetica,Arial,sans-serif;color:#2c3e50;">
+<h2 style="margin:0 0 8px;">Stale Pull Requests Digest{{if .Tier}} &middot; Escalation: {{.Tier}}{{end}}</h2>
+<p style="margin:0 0 16px;">Hello{{if .Name}} {{.Name}}{{end}},</p>
+{{if .Reviews}}
+<p style="margin:16px 0 4px;">These {{len .Reviews}} pull requests are waiting on your review and have been inactive for {{.Threshold}} or more:</p>
+<table cellpadding="6" cellspacing="0" style="border-collapse:collapse;width:100%;font-size:14px;">
+<tr style="background:#ecf0f1;text-align:left;">
+<th>Pull request</th><th>Author</th><th>Idle</th><th>Approvals</th>
+</tr>
+{{range .Reviews}}<tr style="border-top:1px solid #d5dbdb;">
+<td>{{if .Link}}<a href="{{.Link}}" style="color:#2874a6;">{{.Ref.Short}} {{.Title}}</a>{{else}}{{.Ref.Short}} {{.Title}}{{end}}</td>
+<td>{{.Author.User.DisplayName}}</td>
+<td style="color:{{idleColor . $.StaleAfterDays}};font-weight:bold;">{{$.Days .DaysInactive}}</td>
+<td><span style="background:{{approvalColor .}};color:#ffffff;border-radius:10px;padding:2px 8px;">{{.Approvals}}/{{.Reviewers}}</span></td>
+</tr>
+{{end}}</table>
+{{end}}{{if .Authored}}
+<p style="margin:16px 0 4px;">These {{len .Authored}} pull requests of yours have been inactive for {{.Threshold}} or more:</p>
+<table cellpadding="6" cellspacing="0" style="border-collapse:collapse;width:100%;font-size:14px;">
+<tr style="background:#ecf0f1;text-align:left;">
+<th>Pull request</th><th>Idle</th><th>Approvals</th>
+</tr>
+{{range .Authored}}<tr style="border-top:1px solid #d5dbdb;">
+<td>{{if .Link}}<a href="{{.Link}}" style="color:#2874a6;">{{.Ref.Short}} {{.Title}}</a>{{else}}{{.Ref.Short}} {{.Title}}{{end}}</td>
+<td style="color:{{idleColor . $.StaleAfterDays}};font-weight:bold;">{{$.Days .DaysInactive}}</td>
+<td><span style="background:{{approvalColor .}};color:#ffffff;border-radius:10px;padding:2px 8px;">{{.Approvals}}/{{.Reviewers}}</span></td>
+</tr>
+{{end}}</table>
+{{end}}
+<p style="margin:16px 0 0;font-size:12px;color:#7f8c8d;">This is an automated notification from the PR Tracker service.</p>
+</body>
+</html>
+`
@@ -0,0 +1,206 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"fc-pr-tracker/internal/config"
+	"fc-pr-tracker/pkg/models"
+)
+
+// participant builds a PR participant with an email address
+func participant(username, email, role string, approved bool) models.Participant {
+	var p models.Participant
+	p.User.DisplayName = strings.ToUpper(username[:1]) + username[1:]
+	p.User.Username = username
+	p.User.Email = email
+	p.Role = role
+	p.Approved = approved
+	return p
+}
+
+// digestReport has two PRs by alice: bob reviews both, carol approved the first and reviews the second
+func digestReport() *models.Report {
+	prs := make([]models.PullRequest, 2)
+	for i := range prs {
+		prs[i].ID = i + 1
+		prs[i].Title = fmt.Sprintf("Change %d", i+1)
+		prs[i].Author.User.DisplayName = "Alice"
+		prs[i].Author.User.Username = "alice"
+		prs[i].Links.Self = append(prs[i].Links.Self, struct {
+			Href string `json:"href"`
+		}{Href: fmt.Sprintf("https://example.com/pr/%d", i+1)})
+	}
+	return newTestReport(5, map[string][]models.PullRequest{"repo": prs}, map[int][]models.Participant{
+		1: {
+			participant("alice", "alice@example.com", "AUTHOR", false),
+			participant("bob", "bob@example.com", "REVIEWER", false),
+			participant("carol", "carol@example.com", "REVIEWER", true),
+		},
+		2: {
+			participant("alice", "Alice@example.com", "AUTHOR", false),
+			participant("bob", "bob@example.com", "REVIEWER", false),
+			participant("carol", "carol@example.com", "REVIEWER", false),
+			participant("dave", "", "REVIEWER", false),
+		},
+	})
+}
+
+func TestDigestNotifier_BuildDigests(t *testing.T) {
+	tests := []struct {
+		name     string
+		digests  config.DigestsConfig
+		expected string
+	}{
+		{"reviewers", config.DigestsConfig{Reviewers: true},
+			"bob@example.com reviews [1 2] authored []; carol@example.com reviews [2] authored []"},
+		{"authors", config.DigestsConfig{Authors: true},
+			"alice@example.com reviews [] authored [1 2]"},
+		{"both", config.DigestsConfig{Reviewers: true, Authors: true},
+			"bob@example.com reviews [1 2] authored []; alice@example.com reviews [] authored [1 2]; carol@example.com reviews [2] authored []"},
+		{"opt-out by email and username", config.DigestsConfig{Reviewers: true, Authors: true, OptOut: []string{"BOB@example.com", "alice"}},
+			"carol@example.com reviews [2] authored []"},
+		{"recipient cap", config.DigestsConfig{Reviewers: true, Authors: true, MaxRecipients: 2},
+			"bob@example.com reviews [1 2] authored []; alice@example.com reviews [] authored [1 2]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Notifiers: config.NotifiersConfig{Digests: tt.digests}}
+			var got []string
+			for _, d := range NewDigestNotifier(cfg).buildDigests(digestReport()) {
+				got = append(got, fmt.Sprintf("%s reviews %v authored %v", d.Email, prIDs(d.Reviews), prIDs(d.Authored)))
+			}
+			if strings.Join(got, "; ") != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, strings.Join(got, "; "))
+			}
+		})
+	}
+}
+
+func prIDs(prs []models.EnrichedPR) []int {
+	ids := []int{}
+	for _, pr := range prs {
+		ids = append(ids, pr.ID)
+	}
+	return ids
+}
+
+func TestDigestNotifier_Preview(t *testing.T) {
+	cfg := &config.Config{Notifiers: config.NotifiersConfig{
+		SMTP:    config.SMTPConfig{From: "tracker@example.com"},
+		Digests: config.DigestsConfig{Reviewers: true, Authors: true, OptOut: []string{"alice"}},
+	}}
+	report := digestReport()
+	report.Tier = "Reminder"
+
+	var out strings.Builder
+	if err := NewDigestNotifier(cfg).Preview(&out, report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	messages := strings.Split(out.String(), "\n\n\nTo: ")
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 digests, got:\n%s", out.String())
+	}
+	for _, want := range []string{"To: bob@example.com\r\n", "Subject: [Reminder] Stale Pull Requests Digest - 2 PRs need your attention",
+		"Hello Bob,", "These 2 pull requests are waiting on your review and have been\r\ninactive for 5 days or more",
+		"- repo#1: Change 1", "- repo#2: Change 2", "Approvals: 0/3 reviewers"} {
+		if !strings.Contains(messages[0], want) {
+			t.Errorf("Expected the first digest to contain %q, got:\n%s", want, messages[0])
+		}
+	}
+	if strings.Contains(messages[1], "Change 1") || !strings.Contains(messages[1], "carol@example.com") {
+		t.Errorf("Expected carol's digest to list only PR 2, got:\n%s", messages[1])
+	}
+	if strings.Contains(out.String(), "of yours") {
+		t.Errorf("Expected no author digest for opted-out alice, got:\n%s", out.String())
+	}
+}
+
+func TestDigestNotifier_AuthorWithoutParticipant(t *testing.T) {
+	// Cloud and GitHub PRs have no author participant and may have no link
+	var pr models.PullRequest
+	pr.ID = 3
+	pr.Title = "Cloud change"
+	pr.Author.User.DisplayName = "Erin"
+	pr.Author.User.Username = "erin"
+	pr.Author.User.Email = "erin@example.com"
+	report := newTestReport(5, map[string][]models.PullRequest{"repo": {pr}}, nil)
+
+	cfg := &config.Config{Notifiers: config.NotifiersConfig{
+		SMTP:    config.SMTPConfig{From: "tracker@example.com"},
+		Digests: config.DigestsConfig{Authors: true},
+	}}
+	var out strings.Builder
+	if err := NewDigestNotifier(cfg).Preview(&out, report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{"To: erin@example.com\r\n", "Hello Erin,", "- repo#3: Cloud change\r\n  Approvals: 0/0 reviewers"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected the digest to contain %q, got:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "Link:") {
+		t.Errorf("Expected no link line for a PR without link, got:\n%s", out.String())
+	}
+}
+
+func TestDigestLimit_SharedAcrossNotifiers(t *testing.T) {
+	cfg := &config.Config{Notifiers: config.NotifiersConfig{
+		SMTP:    config.SMTPConfig{From: "tracker@example.com"},
+		Digests: config.DigestsConfig{Reviewers: true},
+	}}
+	limit := NewDigestLimit(3)
+	first, second := NewDigestNotifier(cfg), NewDigestNotifier(cfg)
+	first.ShareLimit(limit)
+	second.ShareLimit(limit)
+
+	// Previews show every digest and leave the limit untouched
+	var out strings.Builder
+	if err := first.Preview(&out, digestReport()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := strings.Count(out.String(), "\r\nSubject: "); got != 2 {
+		t.Errorf("Expected 2 previewed digests, got %d", got)
+	}
+
+	take := func(n *DigestNotifier) int {
+		return len(n.limit.take(n.buildDigests(digestReport())))
+	}
+	if got := take(first); got != 2 {
+		t.Errorf("Expected 2 digests from the first notifier, got %d", got)
+	}
+	if got := take(second); got != 1 {
+		t.Errorf("Expected the cycle limit to leave 1 digest for the second notifier, got %d", got)
+	}
+	limit.Reset()
+	if got := take(second); got != 2 {
+		t.Errorf("Expected 2 digests after a new cycle, got %d", got)
+	}
+}
+
+func TestDigestNotifier_ComposeHTML(t *testing.T) {
+	cfg := &config.Config{Notifiers: config.NotifiersConfig{Digests: config.DigestsConfig{Reviewers: true}}}
+	report := digestReport()
+	report.BusinessDays = true
+	for i := range report.PRs {
+		report.PRs[i].Ref = models.PRRef{Server: "git.example.com:7990", Project: "CORE", Repo: "repo", ID: report.PRs[i].ID}
+		report.PRs[i].DaysInactive = 6
+	}
+	n := NewDigestNotifier(cfg)
+
+	content, err := n.compose(report, n.buildDigests(report)[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(content.text, "- CORE/repo#1: Change 1\n") || strings.Contains(content.text, "7990") {
+		t.Errorf("Expected project/repo#ID references without the server in the text body, got:\n%s", content.text)
+	}
+	for _, want := range []string{`<a href="https://example.com/pr/1" style="color:#2874a6;">CORE/repo#1 Change 1</a>`,
+		`font-weight:bold;">6 business days</td>`, "Hello Bob,"} {
+		if !strings.Contains(content.html, want) {
+			t.Errorf("Expected the HTML body to contain %q, got:\n%s", want, content.html)
+		}
+	}
+}
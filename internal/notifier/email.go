@@ -1,138 +1,250 @@
 package notifier
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
+	htmltemplate "html/template"
+	"io"
 	"log/slog"
+	"net"
 	"net/smtp"
 	"strings"
 	"text/template"
+	"time"
 
-	"fc-pr-tracker/internal/bitbucket"
+	"fc-pr-tracker/internal/calendar"
 	"fc-pr-tracker/internal/config"
+	"fc-pr-tracker/internal/templates"
 	"fc-pr-tracker/pkg/models"
 )
 
 // EmailNotifier implements email notifications
 type EmailNotifier struct {
-	config *config.Config
+	config   *config.Config
+	location *time.Location     // where dates are shown
+	calendar *calendar.Calendar // counts days in templates, nil when every day counts
+	// User templates from notifiers.smtp.templates; nil ones use the built-in message
+	subject *template.Template
+	text    *template.Template
+	html    *htmltemplate.Template
 }
 
 // NewEmailNotifier creates a new email notifier
 func NewEmailNotifier(cfg *config.Config) *EmailNotifier {
-	return &EmailNotifier{config: cfg}
+	e := &EmailNotifier{config: cfg}
+	loc, cal := templateClock(cfg)
+	e.location, e.calendar = loc, cal
+	var err error
+
+	// Load validates the templates, so only hand-built configurations or files changed since fail here
+	tt := cfg.Notifiers.SMTP.Templates
+	if tt.Subject != "" {
+		if e.subject, err = templates.Parse("subject", tt.Subject, loc, cal); err != nil {
+			slog.Error("Invalid email subject template, using the built-in subject", "error", err)
+		}
+	}
+	if tt.Text != "" {
+		if e.text, err = templates.ParseText(tt.Text, loc, cal); err != nil {
+			slog.Error("Invalid email text template, using the built-in body", "path", tt.Text, "error", err)
+		}
+	}
+	if tt.HTML != "" {
+		if e.html, err = templates.ParseHTML(tt.HTML, loc, cal); err != nil {
+			slog.Error("Invalid email HTML template, using the built-in body", "path", tt.HTML, "error", err)
+		}
+	}
+	return e
 }
 
-// Notify sends email notifications for stale PRs
-func (e *EmailNotifier) Notify(allPRs []models.PullRequest, repoPRs map[string][]models.PullRequest,
-	prParticipants map[int][]models.Participant, staleAfterDays int) error {
+// templateClock returns the time zone templates show dates in and the calendar they count
+// days with, the one stale PRs are counted with
+func templateClock(cfg *config.Config) (*time.Location, *calendar.Calendar) {
+	loc, err := cfg.Notification.Location()
+	if err != nil {
+		loc = time.Local
+	}
+	cal, err := cfg.NewCalendar()
+	if err != nil {
+		// Load validates the business days, so only hand-built configurations get here
+		slog.Error("Invalid business days, counting every day in templates", "error", err)
+	}
+	return loc, cal
+}
 
-	if len(allPRs) == 0 {
+// Notify sends email notifications for stale PRs
+func (e *EmailNotifier) Notify(ctx context.Context, report *models.Report) error {
+	if len(report.PRs) == 0 {
 		return nil
 	}
 
-	subject := fmt.Sprintf("Stale Pull Requests Alert - %d PRs need attention", len(allPRs))
-	body, err := e.generateEmailBody(allPRs, repoPRs, prParticipants, staleAfterDays)
+	content, err := e.compose(report)
 	if err != nil {
-		return fmt.Errorf("error generating email body: %v", err)
+		return err
 	}
+	return e.sendEmail(ctx, e.config.Notifiers.SMTP.To, content)
+}
 
-	return e.sendEmail(subject, body)
+// Name identifies the notifier in logs and previews
+func (e *EmailNotifier) Name() string {
+	return "email"
 }
 
-// generateEmailBody creates the email content
-func (e *EmailNotifier) generateEmailBody(allPRs []models.PullRequest, repoPRs map[string][]models.PullRequest,
-	prParticipants map[int][]models.Participant, staleAfterDays int) (string, error) {
+// Preview writes the email message, headers included, to w
+func (e *EmailNotifier) Preview(w io.Writer, report *models.Report) error {
+	content, err := e.compose(report)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, e.message(e.config.Notifiers.SMTP.To, content))
+	return err
+}
+
+// emailContent is the subject and bodies of an email; the HTML body is optional
+type emailContent struct {
+	subject string
+	text    string
+	html    string
+}
 
+// compose renders the subject, plain-text and HTML bodies of the email for report.
+// A text template without an HTML template sends a plain-text email.
+func (e *EmailNotifier) compose(report *models.Report) (emailContent, error) {
+	data := templates.NewData(report)
+	var content emailContent
+	var err error
+
+	if e.subject != nil {
+		if content.subject, err = render(e.subject, data); err != nil {
+			return emailContent{}, fmt.Errorf("error generating email subject: %v", err)
+		}
+		content.subject = strings.Join(strings.Fields(content.subject), " ")
+	} else {
+		content.subject = fmt.Sprintf("Stale Pull Requests Alert - %d PRs need attention", len(report.PRs))
+		if report.Tier != "" {
+			content.subject = fmt.Sprintf("[%s] %s", report.Tier, content.subject)
+		}
+	}
+
+	if e.text != nil {
+		content.text, err = render(e.text, data)
+	} else {
+		content.text, err = e.generateEmailBody(report)
+	}
+	if err != nil {
+		return emailContent{}, fmt.Errorf("error generating email body: %v", err)
+	}
+
+	switch {
+	case e.html != nil:
+		content.html, err = render(e.html, data)
+	case e.text == nil:
+		content.html, err = e.generateEmailHTML(report)
+	}
+	if err != nil {
+		return emailContent{}, fmt.Errorf("error generating HTML email body: %v", err)
+	}
+	return content, nil
+}
+
+// render executes a user template with data
+func render(t templates.Executor, data templates.Data) (string, error) {
+	var out strings.Builder
+	if err := t.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// generateEmailBody creates the plain-text email content
+func (e *EmailNotifier) generateEmailBody(report *models.Report) (string, error) {
 	tmpl := `
-Stale Pull Requests Alert
+Stale Pull Requests Alert{{if .Tier}} - Escalation: {{.Tier}}{{end}}
 
-The following {{.TotalPRs}} pull requests have been inactive for {{.StaleDays}} days or more:
+The following {{.TotalPRs}} pull requests have been inactive for {{.Threshold}} or more:
 
-{{range $repo, $prs := .RepoPRs}}
-Repository: {{$repo}}
-{{range $prs}}
+{{range .Projects}}{{if .Project}}
+Project: {{.Project}}
+{{end}}{{range .Repos}}
+Repository: {{.Repo}}
+{{range .PRs}}
 - PR #{{.ID}}: {{.Title}}
   Author: {{.Author.User.DisplayName}} ({{.Author.User.Username}})
-  Link: {{(index .Links.Self 0).Href}}
-  Created: {{.CreatedDate}}
-  Updated: {{.UpdatedDate}}
-  Approvals: {{index $.ApprovalCounts .ID "approved"}}/{{index $.ApprovalCounts .ID "total"}} reviewers
-{{end}}
+{{with .Link}}  Link: {{.}}
+{{end}}  Created: {{date .CreatedDate}}
+  Updated: {{date .UpdatedDate}}
+  Approvals: {{.Approvals}}/{{.Reviewers}} reviewers
 {{end}}
+{{end}}{{end}}
 
 Total stale PRs: {{.TotalPRs}}
 
 This is an automated notification from the PR Tracker service.
 `
 
-	t := template.Must(template.New("email").Parse(tmpl))
-
-	// Calculate approval counts for each PR
-	approvalCounts := make(map[int]map[string]int)
-	for prID, participants := range prParticipants {
-		approved, total := bitbucket.CountApprovals(participants)
-		approvalCounts[prID] = map[string]int{
-			"approved": approved,
-			"total":    total,
-		}
-	}
-
-	data := struct {
-		TotalPRs       int
-		StaleDays      int
-		RepoPRs        map[string][]models.PullRequest
-		ApprovalCounts map[int]map[string]int
-	}{
-		TotalPRs:       len(allPRs),
-		StaleDays:      staleAfterDays,
-		RepoPRs:        repoPRs,
-		ApprovalCounts: approvalCounts,
-	}
-
-	var body strings.Builder
-	err := t.Execute(&body, data)
-	if err != nil {
-		return "", err
-	}
+	t := template.Must(template.New("email").Funcs(templates.Funcs(e.location, e.calendar)).Parse(tmpl))
+	return render(t, templates.NewData(report))
+}
 
-	return body.String(), nil
+// generateEmailHTML creates the HTML email content: a table of stale PRs per repository
+func (e *EmailNotifier) generateEmailHTML(report *models.Report) (string, error) {
+	t := htmltemplate.Must(htmltemplate.New("email").Funcs(templates.Funcs(e.location, e.calendar)).Parse(emailHTML))
+	return render(t, templates.NewData(report))
 }
 
-// sendEmail sends the email using SMTP
-func (e *EmailNotifier) sendEmail(subject, body string) error {
-	to := strings.Join(e.config.Notifiers.SMTP.To, ",")
-	msg := fmt.Sprintf("To: %s\r\nFrom: %s\r\nSubject: %s\r\n\r\n%s",
-		to, e.config.Notifiers.SMTP.From, subject, body)
+const emailHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="UTF-8">
+<title>Stale Pull Requests Alert</title>
+</head>
+<body style="margin:0;padding:16px;font-family:Segoe UI,Helvetica,Arial,sans-serif;color:#2c3e50;">
+<h2 style="margin:0 0 8px;">Stale Pull Requests Alert{{if .Tier}} &middot; Escalation: {{.Tier}}{{end}}</h2>
+<p style="margin:0 0 16px;">The following {{.TotalPRs}} pull requests have been inactive for {{.Threshold}} or more.</p>
+{{range .Projects}}{{if .Project}}
+<h3 style="margin:16px 0 4px;">Project: {{.Project}}</h3>
+{{end}}{{range .Repos}}
+<h4 style="margin:12px 0 4px;">Repository: {{.Repo}}</h4>
+<table cellpadding="6" cellspacing="0" style="border-collapse:collapse;width:100%;font-size:14px;">
+<tr style="background:#ecf0f1;text-align:left;">
+<th>Pull request</th><th>Author</th><th>Created</th><th>Updated</th><th>Idle</th><th>Approvals</th>
+</tr>
+{{range .PRs}}<tr style="border-top:1px solid #d5dbdb;">
+<td>{{if .Link}}<a href="{{.Link}}" style="color:#2874a6;">#{{.ID}} {{.Title}}</a>{{else}}#{{.ID}} {{.Title}}{{end}}</td>
+<td>{{.Author.User.DisplayName}}</td>
+<td>{{date .CreatedDate}}</td>
+<td>{{date .UpdatedDate}}</td>
+<td style="color:{{idleColor . $.StaleAfterDays}};font-weight:bold;">{{$.Days .DaysInactive}}</td>
+<td><span style="background:{{approvalColor .}};color:#ffffff;border-radius:10px;padding:2px 8px;">{{.Approvals}}/{{.Reviewers}}</span></td>
+</tr>
+{{end}}</table>
+{{end}}{{end}}
+<p style="margin:16px 0 0;">Total stale PRs: <strong>{{.TotalPRs}}</strong></p>
+<p style="margin:8px 0 0;font-size:12px;color:#7f8c8d;">This is an automated notification from the PR Tracker service.</p>
+</body>
+</html>
+`
 
-	addr := fmt.Sprintf("%s:%d", e.config.Notifiers.SMTP.Host, e.config.Notifiers.SMTP.Port)
+// sendEmail sends the email to the to recipients using SMTP
+func (e *EmailNotifier) sendEmail(ctx context.Context, to []string, content emailContent) error {
+	msg := e.message(to, content)
 
-	var err error
+	addr := fmt.Sprintf("%s:%d", e.config.Notifiers.SMTP.Host, e.config.Notifiers.SMTP.Port)
 
-	// Determine authentication and connection method based on configuration
+	// Authenticate only when credentials are configured (local testing servers usually need none)
+	var auth smtp.Auth
 	if e.config.Notifiers.SMTP.User != "" && e.config.Notifiers.SMTP.Password != "" {
-		// Use authentication
-		auth := smtp.PlainAuth("", e.config.Notifiers.SMTP.User, e.config.Notifiers.SMTP.Password,
+		auth = smtp.PlainAuth("", e.config.Notifiers.SMTP.User, e.config.Notifiers.SMTP.Password,
 			e.config.Notifiers.SMTP.Host)
+	}
 
-		if e.config.Notifiers.SMTP.Port == 587 {
-			// Use STARTTLS for port 587
-			err = smtp.SendMail(addr, auth, e.config.Notifiers.SMTP.From, e.config.Notifiers.SMTP.To, []byte(msg))
-		} else if e.config.Notifiers.SMTP.Port == 465 {
-			// Use TLS for port 465
-			err = e.sendWithTLS(addr, auth, e.config.Notifiers.SMTP.From, e.config.Notifiers.SMTP.To, []byte(msg))
-		} else {
-			// For other ports (like 1025 for local testing), try without TLS first
-			err = smtp.SendMail(addr, auth, e.config.Notifiers.SMTP.From, e.config.Notifiers.SMTP.To, []byte(msg))
-		}
+	var err error
+	if e.config.Notifiers.SMTP.Port == 465 {
+		// Use TLS for port 465
+		err = e.sendWithTLS(ctx, addr, auth, e.config.Notifiers.SMTP.From, to, []byte(msg))
 	} else {
-		// No authentication (for local testing servers)
-		if e.config.Notifiers.SMTP.Port == 587 {
-			err = smtp.SendMail(addr, nil, e.config.Notifiers.SMTP.From, e.config.Notifiers.SMTP.To, []byte(msg))
-		} else {
-			// For local testing servers (like MailHog on port 1025)
-			err = smtp.SendMail(addr, nil, e.config.Notifiers.SMTP.From, e.config.Notifiers.SMTP.To, []byte(msg))
-		}
+		// Use STARTTLS when offered (port 587), plain SMTP otherwise (like MailHog on port 1025)
+		err = e.sendPlain(ctx, addr, auth, e.config.Notifiers.SMTP.From, to, []byte(msg))
 	}
 
 	if err != nil {
@@ -140,23 +252,55 @@ func (e *EmailNotifier) sendEmail(subject, body string) error {
 		return fmt.Errorf("failed to send email: %v", err)
 	}
 
-	slog.Info("Email notification sent successfully", "recipients", e.config.Notifiers.SMTP.To)
+	slog.Info("Email notification sent successfully", "recipients", to)
 	return nil
 }
 
-// sendWithTLS sends email with TLS encryption
-func (e *EmailNotifier) sendWithTLS(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
-	// Create TLS config
-	tlsConfig := &tls.Config{
-		ServerName: e.config.Notifiers.SMTP.Host,
+// message builds the raw email message
+func (e *EmailNotifier) message(to []string, content emailContent) string {
+	return mimeMessage(e.config.Notifiers.SMTP.From, to, content.subject, content.text, content.html, time.Now())
+}
+
+// sendPlain sends email over a plain connection, upgrading it with STARTTLS when the server supports it
+func (e *EmailNotifier) sendPlain(ctx context.Context, addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	conn, stop, err := e.dial(ctx, addr, false)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	client, err := smtp.NewClient(conn, e.config.Notifiers.SMTP.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: e.config.Notifiers.SMTP.Host}); err != nil {
+			return err
+		}
 	}
 
+	if auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("smtp: server doesn't support AUTH")
+		}
+		if err = client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	return deliver(client, from, to, msg)
+}
+
+// sendWithTLS sends email with TLS encryption
+func (e *EmailNotifier) sendWithTLS(ctx context.Context, addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
 	// Connect to SMTP server
-	conn, err := tls.Dial("tcp", addr, tlsConfig)
+	conn, stop, err := e.dial(ctx, addr, true)
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
+	defer stop()
 
 	// Create SMTP client
 	client, err := smtp.NewClient(conn, e.config.Notifiers.SMTP.Host)
@@ -166,18 +310,43 @@ func (e *EmailNotifier) sendWithTLS(addr string, auth smtp.Auth, from string, to
 	defer client.Close()
 
 	// Authenticate
-	if err = client.Auth(auth); err != nil {
-		return err
+	if auth != nil {
+		if err = client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	return deliver(client, from, to, msg)
+}
+
+// dial connects to the SMTP server, over TLS if requested. The connection is closed as
+// soon as ctx is done, which aborts any SMTP exchange in progress; call stop once finished.
+func (e *EmailNotifier) dial(ctx context.Context, addr string, useTLS bool) (conn net.Conn, stop func(), err error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	if useTLS {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: e.config.Notifiers.SMTP.Host}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
 	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cancel := context.AfterFunc(ctx, func() { conn.Close() })
+	return conn, func() { cancel() }, nil
+}
 
+// deliver sends the envelope and message over an established SMTP session
+func deliver(client *smtp.Client, from string, to []string, msg []byte) error {
 	// Set sender
-	if err = client.Mail(from); err != nil {
+	if err := client.Mail(from); err != nil {
 		return err
 	}
 
 	// Set recipients
 	for _, recipient := range to {
-		if err = client.Rcpt(recipient); err != nil {
+		if err := client.Rcpt(recipient); err != nil {
 			return err
 		}
 	}
@@ -187,8 +356,11 @@ func (e *EmailNotifier) sendWithTLS(addr string, auth smtp.Auth, from string, to
 	if err != nil {
 		return err
 	}
-	defer writer.Close()
-
-	_, err = writer.Write(msg)
-	return err
+	if _, err = writer.Write(msg); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
 }
@@ -1,8 +1,12 @@
 package notifier
 
 import (
+	"context"
 	"fc-pr-tracker/internal/config"
 	"fc-pr-tracker/pkg/models"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -24,7 +28,7 @@ func TestEmailNotifier_Notify_EmptyPRs(t *testing.T) {
 	cfg := &config.Config{}
 	notifier := NewEmailNotifier(cfg)
 
-	err := notifier.Notify([]models.PullRequest{}, map[string][]models.PullRequest{}, map[int][]models.Participant{}, 7)
+	err := notifier.Notify(context.Background(), &models.Report{StaleAfterDays: 7})
 	if err != nil {
 		t.Errorf("Expected no error when no PRs, got: %v", err)
 	}
@@ -47,6 +51,7 @@ func TestEmailNotifier_GenerateEmailBody(t *testing.T) {
 			User struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			} `json:"user"`
 			Role     string `json:"role"`
 			Approved bool   `json:"approved"`
@@ -55,6 +60,7 @@ func TestEmailNotifier_GenerateEmailBody(t *testing.T) {
 			User: struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			}{
 				DisplayName: "Test User",
 				Username:    "testuser",
@@ -82,6 +88,7 @@ func TestEmailNotifier_GenerateEmailBody(t *testing.T) {
 			User struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			} `json:"user"`
 			Role     string `json:"role"`
 			Approved bool   `json:"approved"`
@@ -90,6 +97,7 @@ func TestEmailNotifier_GenerateEmailBody(t *testing.T) {
 			User: struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			}{
 				DisplayName: "Another User",
 				Username:    "anotheruser",
@@ -108,7 +116,6 @@ func TestEmailNotifier_GenerateEmailBody(t *testing.T) {
 		},
 	}
 
-	allPRs := []models.PullRequest{pr1, pr2}
 	repoPRs := map[string][]models.PullRequest{
 		"test-repo": {pr1, pr2},
 	}
@@ -122,7 +129,7 @@ func TestEmailNotifier_GenerateEmailBody(t *testing.T) {
 		},
 	}
 
-	body, err := notifier.generateEmailBody(allPRs, repoPRs, prParticipants, 7)
+	body, err := notifier.generateEmailBody(newTestReport(7, repoPRs, prParticipants))
 	if err != nil {
 		t.Fatalf("Expected no error generating email body, got: %v", err)
 	}
@@ -165,6 +172,7 @@ func TestEmailNotifier_GenerateEmailBody_NoParticipants(t *testing.T) {
 			User struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			} `json:"user"`
 			Role     string `json:"role"`
 			Approved bool   `json:"approved"`
@@ -173,6 +181,7 @@ func TestEmailNotifier_GenerateEmailBody_NoParticipants(t *testing.T) {
 			User: struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			}{
 				DisplayName: "Test User",
 				Username:    "testuser",
@@ -191,13 +200,12 @@ func TestEmailNotifier_GenerateEmailBody_NoParticipants(t *testing.T) {
 		},
 	}
 
-	allPRs := []models.PullRequest{pr}
 	repoPRs := map[string][]models.PullRequest{
 		"test-repo": {pr},
 	}
 	prParticipants := map[int][]models.Participant{}
 
-	body, err := notifier.generateEmailBody(allPRs, repoPRs, prParticipants, 7)
+	body, err := notifier.generateEmailBody(newTestReport(7, repoPRs, prParticipants))
 	if err != nil {
 		t.Fatalf("Expected no error generating email body, got: %v", err)
 	}
@@ -219,6 +227,7 @@ func TestEmailNotifier_GenerateEmailBody_MultipleRepos(t *testing.T) {
 			User struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			} `json:"user"`
 			Role     string `json:"role"`
 			Approved bool   `json:"approved"`
@@ -227,6 +236,7 @@ func TestEmailNotifier_GenerateEmailBody_MultipleRepos(t *testing.T) {
 			User: struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			}{
 				DisplayName: "User 1",
 				Username:    "user1",
@@ -252,6 +262,7 @@ func TestEmailNotifier_GenerateEmailBody_MultipleRepos(t *testing.T) {
 			User struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			} `json:"user"`
 			Role     string `json:"role"`
 			Approved bool   `json:"approved"`
@@ -260,6 +271,7 @@ func TestEmailNotifier_GenerateEmailBody_MultipleRepos(t *testing.T) {
 			User: struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			}{
 				DisplayName: "User 2",
 				Username:    "user2",
@@ -278,14 +290,13 @@ func TestEmailNotifier_GenerateEmailBody_MultipleRepos(t *testing.T) {
 		},
 	}
 
-	allPRs := []models.PullRequest{pr1, pr2}
 	repoPRs := map[string][]models.PullRequest{
 		"repo1": {pr1},
 		"repo2": {pr2},
 	}
 	prParticipants := map[int][]models.Participant{}
 
-	body, err := notifier.generateEmailBody(allPRs, repoPRs, prParticipants, 7)
+	body, err := notifier.generateEmailBody(newTestReport(7, repoPRs, prParticipants))
 	if err != nil {
 		t.Fatalf("Expected no error generating email body, got: %v", err)
 	}
@@ -318,7 +329,7 @@ func TestEmailNotifier_SendEmail_Success(t *testing.T) {
 
 	// This test will fail if no SMTP server is running, but it tests the code path
 	// In a real environment, you'd use a mock SMTP server
-	err := notifier.sendEmail("Test Subject", "Test Body")
+	err := notifier.sendEmail(context.Background(), cfg.Notifiers.SMTP.To, emailContent{subject: "Test Subject", text: "Test Body"})
 
 	// We expect an error because there's no SMTP server running
 	// But this tests that the function executes without panicking
@@ -341,7 +352,7 @@ func TestEmailNotifier_SendEmail_WithAuth(t *testing.T) {
 	notifier := NewEmailNotifier(cfg)
 
 	// This test will fail because credentials are invalid, but it tests the auth code path
-	err := notifier.sendEmail("Test Subject", "Test Body")
+	err := notifier.sendEmail(context.Background(), cfg.Notifiers.SMTP.To, emailContent{subject: "Test Subject", text: "Test Body"})
 
 	// We expect an error because credentials are invalid
 	// But this tests that the authentication code path executes
@@ -364,7 +375,7 @@ func TestEmailNotifier_SendEmail_TLS(t *testing.T) {
 	notifier := NewEmailNotifier(cfg)
 
 	// This test will fail because credentials are invalid, but it tests the TLS code path
-	err := notifier.sendEmail("Test Subject", "Test Body")
+	err := notifier.sendEmail(context.Background(), cfg.Notifiers.SMTP.To, emailContent{subject: "Test Subject", text: "Test Body"})
 
 	// We expect an error because credentials are invalid
 	// But this tests that the TLS code path executes
@@ -383,7 +394,7 @@ func TestEmailNotifier_SendWithTLS_ConnectionError(t *testing.T) {
 	notifier := NewEmailNotifier(cfg)
 
 	// Test TLS connection failure
-	err := notifier.sendWithTLS("invalid-host.local:465", nil, "from@example.com", []string{"to@example.com"}, []byte("test"))
+	err := notifier.sendWithTLS(context.Background(), "invalid-host.local:465", nil, "from@example.com", []string{"to@example.com"}, []byte("test"))
 
 	if err == nil {
 		t.Error("Expected error when connecting to invalid host")
@@ -412,6 +423,7 @@ func TestEmailNotifier_Notify_WithPRs(t *testing.T) {
 			User struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			} `json:"user"`
 			Role     string `json:"role"`
 			Approved bool   `json:"approved"`
@@ -420,6 +432,7 @@ func TestEmailNotifier_Notify_WithPRs(t *testing.T) {
 			User: struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			}{
 				DisplayName: "Test User",
 				Username:    "testuser",
@@ -438,14 +451,13 @@ func TestEmailNotifier_Notify_WithPRs(t *testing.T) {
 		},
 	}
 
-	allPRs := []models.PullRequest{pr}
 	repoPRs := map[string][]models.PullRequest{
 		"test-repo": {pr},
 	}
 	prParticipants := map[int][]models.Participant{}
 
 	// This will fail because no SMTP server is running, but it tests the code path
-	err := notifier.Notify(allPRs, repoPRs, prParticipants, 7)
+	err := notifier.Notify(context.Background(), newTestReport(7, repoPRs, prParticipants))
 
 	if err == nil {
 		t.Log("Notify executed successfully (SMTP server available)")
@@ -453,3 +465,167 @@ func TestEmailNotifier_Notify_WithPRs(t *testing.T) {
 		t.Logf("Notify failed as expected: %v", err)
 	}
 }
+
+func TestEmailNotifier_GenerateEmailBody_GroupsByProject(t *testing.T) {
+	notifier := NewEmailNotifier(&config.Config{})
+
+	newPR := func(id int, project, repo string) models.PullRequest {
+		pr := models.PullRequest{ID: id, Title: fmt.Sprintf("PR %d", id)}
+		pr.Ref = models.PRRef{Project: project, Repo: repo, ID: id}
+		pr.Links.Self = append(pr.Links.Self, struct {
+			Href string `json:"href"`
+		}{Href: fmt.Sprintf("https://example.com/pr/%d", id)})
+		return pr
+	}
+	report := &models.Report{StaleAfterDays: 3, PRs: []models.EnrichedPR{
+		{PullRequest: newPR(1, "WEB", "api")},
+		{PullRequest: newPR(2, "CORE", "api")},
+	}}
+
+	body, err := notifier.generateEmailBody(report)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	corePos, webPos := strings.Index(body, "Project: CORE"), strings.Index(body, "Project: WEB")
+	if corePos < 0 || webPos < 0 || corePos > webPos {
+		t.Errorf("Expected projects CORE then WEB in the body, got:\n%s", body)
+	}
+	if strings.Count(body, "Repository: api") != 2 {
+		t.Errorf("Expected one api section per project, got:\n%s", body)
+	}
+}
+
+func TestEmailNotifier_Preview(t *testing.T) {
+	cfg := &config.Config{Notifiers: config.NotifiersConfig{SMTP: config.SMTPConfig{
+		From: "tracker@example.com",
+		To:   []string{"a@example.com", "b@example.com"},
+	}}}
+	notifier := NewEmailNotifier(cfg)
+
+	pr := models.PullRequest{ID: 1, Title: "Preview me"}
+	pr.Links.Self = append(pr.Links.Self, struct {
+		Href string `json:"href"`
+	}{Href: "https://example.com/pr/1"})
+
+	var out strings.Builder
+	if err := notifier.Preview(&out, newTestReport(3, map[string][]models.PullRequest{"repo": {pr}}, nil)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{"To: a@example.com,b@example.com", "Subject: Stale Pull Requests Alert - 1 PRs need attention", "Preview me"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected preview to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestEmailNotifier_PreviewTier(t *testing.T) {
+	cfg := &config.Config{Notifiers: config.NotifiersConfig{SMTP: config.SMTPConfig{
+		From: "tracker@example.com",
+		To:   []string{"managers@example.com"},
+	}}}
+	pr := models.PullRequest{ID: 1, Title: "Forgotten"}
+	pr.Links.Self = append(pr.Links.Self, struct {
+		Href string `json:"href"`
+	}{Href: "https://example.com/pr/1"})
+	report := newTestReport(14, map[string][]models.PullRequest{"repo": {pr}}, nil)
+	report.Tier = "Managers"
+
+	var out strings.Builder
+	if err := NewEmailNotifier(cfg).Preview(&out, report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{"Subject: [Managers] Stale Pull Requests Alert - 1 PRs need attention",
+		"Stale Pull Requests Alert - Escalation: Managers", "inactive for 14 days or more"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected preview to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestEmailNotifier_GenerateEmailHTML(t *testing.T) {
+	cfg := &config.Config{Notification: config.NotificationConfig{Timezone: "UTC"}}
+	pr := models.PullRequest{ID: 7, Title: "Fix <script> & escaping", CreatedDate: time.Date(2026, 3, 2, 14, 5, 0, 0, time.UTC).UnixMilli()}
+	pr.Author.User.DisplayName = "Alice"
+	pr.Links.Self = append(pr.Links.Self, struct {
+		Href string `json:"href"`
+	}{Href: "https://example.com/pr/7?tab=overview&x=1"})
+	report := newTestReport(3, map[string][]models.PullRequest{"repo": {pr, pr}}, nil)
+	report.PRs[0].DaysInactive, report.PRs[0].Approvals, report.PRs[0].Reviewers = 4, 2, 2
+	report.PRs[1].DaysInactive, report.PRs[1].Approvals, report.PRs[1].Reviewers = 6, 0, 2
+
+	html, err := NewEmailNotifier(cfg).generateEmailHTML(report)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`<a href="https://example.com/pr/7?tab=overview&amp;x=1"`,
+		"#7 Fix &lt;script&gt; &amp; escaping",
+		"Mon, 02 Mar 2026 14:05 UTC",
+		`<td style="color:#d68910;font-weight:bold;">4 days</td>`,
+		`<td style="color:#c0392b;font-weight:bold;">6 days</td>`,
+		`background:#1e8449;color:#ffffff;border-radius:10px;padding:2px 8px;">2/2</span>`,
+		`background:#7f8c8d;color:#ffffff;border-radius:10px;padding:2px 8px;">0/2</span>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Expected the HTML body to contain %q, got:\n%s", want, html)
+		}
+	}
+}
+
+func TestEmailNotifier_BusinessDaysWithoutLink(t *testing.T) {
+	pr := models.PullRequest{ID: 8, Title: "Cloud change"}
+	pr.Author.User.DisplayName = "Alice"
+	report := newTestReport(3, map[string][]models.PullRequest{"repo": {pr}}, nil)
+	report.BusinessDays = true
+	report.PRs[0].DaysInactive = 4
+
+	content, err := NewEmailNotifier(&config.Config{}).compose(report)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(content.html, `font-weight:bold;">4 business days</td>`) || !strings.Contains(content.html, "<td>#8 Cloud change</td>") {
+		t.Errorf("Expected business days and an unlinked title in the HTML body, got:\n%s", content.html)
+	}
+	if !strings.Contains(content.text, "- PR #8: Cloud change\n  Author: Alice ()\n  Created:") {
+		t.Errorf("Expected no link line in the text body, got:\n%s", content.text)
+	}
+}
+
+func TestEmailNotifier_ComposeTemplates(t *testing.T) {
+	dir := t.TempDir()
+	textPath, htmlPath := filepath.Join(dir, "email.txt"), filepath.Join(dir, "email.html")
+	os.WriteFile(textPath, []byte("{{range .PRs}}{{.Ref}} idle {{.DaysInactive}} days{{end}}"), 0o644)
+	os.WriteFile(htmlPath, []byte("<b>{{range .PRs}}{{.Title}}{{end}}</b>"), 0o644)
+
+	report := newTestReport(3, map[string][]models.PullRequest{"repo": {{ID: 4, Title: "A & B"}}}, nil)
+	report.PRs[0].DaysInactive = 5
+	report.Tier = "Leads"
+
+	tests := []struct {
+		name      string
+		templates config.EmailTemplatesConfig
+		subject   string
+		text      string
+		html      string
+	}{
+		{"text only", config.EmailTemplatesConfig{Subject: "{{.Tier}}:\n{{.TotalPRs}} waiting", Text: textPath},
+			"Leads: 1 waiting", "repo#4 idle 5 days", ""},
+		{"text and HTML", config.EmailTemplatesConfig{Text: textPath, HTML: htmlPath},
+			"[Leads] Stale Pull Requests Alert - 1 PRs need attention", "repo#4 idle 5 days", "<b>A &amp; B</b>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Notifiers: config.NotifiersConfig{SMTP: config.SMTPConfig{Templates: tt.templates}}}
+			content, err := NewEmailNotifier(cfg).compose(report)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if content.subject != tt.subject || content.text != tt.text || content.html != tt.html {
+				t.Errorf("Expected %q / %q / %q, got %q / %q / %q",
+					tt.subject, tt.text, tt.html, content.subject, content.text, content.html)
+			}
+		})
+	}
+}
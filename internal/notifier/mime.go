@@ -0,0 +1,90 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// mimeMessage builds an email message with MIME headers, UTF-8 bodies and encoded headers.
+// With an HTML body the message is multipart/alternative, the plain text part first so that
+// clients which cannot render HTML show it instead.
+func mimeMessage(from string, to []string, subject, text, html string, date time.Time) string {
+	var msg strings.Builder
+	header := func(name, value string) {
+		fmt.Fprintf(&msg, "%s: %s\r\n", name, value)
+	}
+	header("To", strings.Join(to, ","))
+	header("From", encodeAddress(from))
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("Message-ID", messageID(from, date))
+	header("MIME-Version", "1.0")
+
+	if html == "" {
+		header("Content-Type", "text/plain; charset=UTF-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		msg.WriteString("\r\n")
+		msg.WriteString(quotedPrintable(text))
+		return msg.String()
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, p := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", html},
+	} {
+		w, _ := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		w.Write([]byte(quotedPrintable(p.content)))
+	}
+	parts.Close()
+
+	header("Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": parts.Boundary()}))
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.String()
+}
+
+// quotedPrintable encodes s for a UTF-8 body part; line breaks become CRLF
+func quotedPrintable(s string) string {
+	var b strings.Builder
+	w := quotedprintable.NewWriter(&b)
+	w.Write([]byte(s))
+	w.Close()
+	return b.String()
+}
+
+// encodeAddress encodes the display name of an address such as "PR Tracker <tracker@example.com>".
+// Values that are not valid addresses are kept as they are.
+func encodeAddress(address string) string {
+	a, err := mail.ParseAddress(address)
+	if err != nil {
+		return address
+	}
+	return a.String()
+}
+
+// messageID generates a unique Message-ID in the domain of the sender
+func messageID(from string, date time.Time) string {
+	domain := "localhost"
+	if a, err := mail.ParseAddress(from); err == nil {
+		if at := strings.LastIndex(a.Address, "@"); at >= 0 {
+			domain = a.Address[at+1:]
+		}
+	}
+	random := make([]byte, 8)
+	rand.Read(random)
+	return fmt.Sprintf("<%d.%s@%s>", date.UnixNano(), hex.EncodeToString(random), domain)
+}
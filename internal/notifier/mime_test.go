@@ -0,0 +1,82 @@
+package notifier
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMimeMessage_Multipart(t *testing.T) {
+	date := time.Date(2026, 3, 20, 9, 30, 0, 0, time.UTC)
+	raw := mimeMessage("PR Trackér <tracker@example.com>", []string{"a@example.com", "b@example.com"},
+		"[Équipe] Stale Pull Requests", "Título: ação\n", "<p>Título: ação</p>\n", date)
+
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing the message: %v", err)
+	}
+	var dec mime.WordDecoder
+	subject, err := dec.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "[Équipe] Stale Pull Requests" {
+		t.Errorf("Expected the decoded subject, got %q (%v)", subject, err)
+	}
+	from, err := msg.Header.AddressList("From")
+	if err != nil || len(from) != 1 || from[0].Name != "PR Trackér" || from[0].Address != "tracker@example.com" {
+		t.Errorf("Expected the decoded sender, got %v (%v)", from, err)
+	}
+	if got, _ := msg.Header.Date(); !got.Equal(date) {
+		t.Errorf("Expected date %v, got %v", date, got)
+	}
+	if id := msg.Header.Get("Message-ID"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Expected a Message-ID in the sender domain, got %q", id)
+	}
+	if v := msg.Header.Get("MIME-Version"); v != "1.0" {
+		t.Errorf("Expected MIME-Version 1.0, got %q", v)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Expected multipart/alternative, got %q (%v)", mediaType, err)
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", "Título: ação\r\n"},
+		{"text/html; charset=UTF-8", "<p>Título: ação</p>\r\n"},
+	} {
+		part, err := parts.NextRawPart()
+		if err != nil {
+			t.Fatalf("Expected a %s part: %v", want.contentType, err)
+		}
+		if ct := part.Header.Get("Content-Type"); ct != want.contentType {
+			t.Errorf("Expected content type %q, got %q", want.contentType, ct)
+		}
+		body, _ := io.ReadAll(quotedprintable.NewReader(part))
+		if string(body) != want.body {
+			t.Errorf("Expected body %q, got %q", want.body, body)
+		}
+	}
+	if _, err := parts.NextRawPart(); err != io.EOF {
+		t.Errorf("Expected two parts, got %v", err)
+	}
+}
+
+func TestMimeMessage_PlainText(t *testing.T) {
+	raw := mimeMessage("tracker@example.com", []string{"a@example.com"}, "Digest", "Olá\n", "", time.Now())
+
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Unexpected error parsing the message: %v", err)
+	}
+	if ct := msg.Header.Get("Content-Type"); ct != "text/plain; charset=UTF-8" {
+		t.Errorf("Expected a plain-text message, got %q", ct)
+	}
+	body, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if string(body) != "Olá\r\n" {
+		t.Errorf("Expected body %q, got %q", "Olá\r\n", body)
+	}
+}
@@ -1,11 +1,18 @@
 package notifier
 
 import (
+	"context"
+	"io"
+
 	"fc-pr-tracker/pkg/models"
 )
 
-// Notifier interface defines the contract for notification services
+// Notifier interface defines the contract for notification services.
+// Implementations must abort any in-flight delivery once ctx is done.
 type Notifier interface {
-	Notify(allPRs []models.PullRequest, repoPRs map[string][]models.PullRequest,
-		prParticipants map[int][]models.Participant, staleAfterDays int) error
+	// Name identifies the notifier in logs and previews
+	Name() string
+	Notify(ctx context.Context, report *models.Report) error
+	// Preview writes the message Notify would send for report to w, without sending it
+	Preview(w io.Writer, report *models.Report) error
 }
@@ -0,0 +1,32 @@
+package notifier
+
+import (
+	"fc-pr-tracker/internal/bitbucket"
+	"fc-pr-tracker/pkg/models"
+	"sort"
+)
+
+// newTestReport builds a report from PRs grouped by repository name, with participants keyed by PR ID.
+// Repositories are added in name order and every PR gets a reference scoped to its repository.
+func newTestReport(staleDays int, repoPRs map[string][]models.PullRequest, participants map[int][]models.Participant) *models.Report {
+	repos := make([]string, 0, len(repoPRs))
+	for repo := range repoPRs {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+
+	report := &models.Report{StaleAfterDays: staleDays}
+	for _, repo := range repos {
+		for _, pr := range repoPRs[repo] {
+			pr.Ref = models.PRRef{Repo: repo, ID: pr.ID}
+			approvals, reviewers := bitbucket.CountApprovals(participants[pr.ID])
+			report.PRs = append(report.PRs, models.EnrichedPR{
+				PullRequest:  pr,
+				Participants: participants[pr.ID],
+				Approvals:    approvals,
+				Reviewers:    reviewers,
+			})
+		}
+	}
+	return report
+}
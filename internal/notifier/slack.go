@@ -0,0 +1,234 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"fc-pr-tracker/internal/config"
+	"fc-pr-tracker/pkg/models"
+)
+
+// Slack limits, see https://api.slack.com/reference/block-kit/blocks
+const (
+	slackMaxBlocks      = 50   // blocks in a message
+	slackMaxSectionText = 3000 // characters in the text of a section block
+	slackMaxHeaderText  = 150  // characters in the text of a header block
+)
+
+// DefaultSlackAPIURL is the Web API that chat.postMessage is called on
+const DefaultSlackAPIURL = "https://slack.com/api"
+
+// SlackNotifier implements Slack notifications, posted to an incoming webhook or with a bot token
+type SlackNotifier struct {
+	config config.SlackConfig
+}
+
+// NewSlackNotifier creates a new Slack notifier
+func NewSlackNotifier(cfg *config.Config) *SlackNotifier {
+	s := cfg.Notifiers.Slack
+	if s.BaseURL == "" {
+		s.BaseURL = DefaultSlackAPIURL
+	}
+	return &SlackNotifier{config: s}
+}
+
+// Notify posts the stale PRs to Slack, in as many messages as the block limits require
+func (s *SlackNotifier) Notify(ctx context.Context, report *models.Report) error {
+	if len(report.PRs) == 0 {
+		return nil
+	}
+
+	for i, msg := range s.generateSlackMessages(report) {
+		if err := s.post(ctx, msg); err != nil {
+			slog.Error("Failed to send Slack notification", "message", i+1, "error", err)
+			return fmt.Errorf("failed to send Slack notification: %v", err)
+		}
+	}
+	slog.Info("Slack notification sent successfully")
+	return nil
+}
+
+// Name identifies the notifier in logs and previews
+func (s *SlackNotifier) Name() string {
+	return "slack"
+}
+
+// Preview writes the indented payload of every Slack message to w
+func (s *SlackNotifier) Preview(w io.Writer, report *models.Report) error {
+	for _, msg := range s.generateSlackMessages(report) {
+		payload, err := json.MarshalIndent(msg, "", "  ")
+		if err != nil {
+			return err
+		}
+		payload = append(payload, '\n')
+		if _, err := w.Write(payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// slackMessage is the payload of a webhook or chat.postMessage call
+type slackMessage struct {
+	Channel string       `json:"channel,omitempty"`
+	Text    string       `json:"text"` // shown in notifications and by clients without Block Kit
+	Blocks  []slackBlock `json:"blocks"`
+}
+
+// slackBlock is a Block Kit layout block
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+// slackText is a Block Kit text object
+type slackText struct {
+	Type string `json:"type"` // plain_text or mrkdwn
+	Text string `json:"text"`
+}
+
+// generateSlackMessages renders the report as Block Kit messages grouped by repository.
+// Messages are split between repositories or PRs to stay within Slack's block limit.
+func (s *SlackNotifier) generateSlackMessages(report *models.Report) []slackMessage {
+	title := "🚨 Stale Pull Requests Alert"
+	summary := fmt.Sprintf("Stale Pull Requests Alert - %d PRs need attention", len(report.PRs))
+	if report.Tier != "" {
+		title += " · Escalation: " + report.Tier
+		summary = fmt.Sprintf("[%s] %s", report.Tier, summary)
+	}
+
+	blocks := []slackBlock{
+		{Type: "header", Text: &slackText{Type: "plain_text", Text: truncate(title, slackMaxHeaderText)}},
+		section(fmt.Sprintf("*%d* pull requests have been inactive for *%s* or more.", len(report.PRs), report.StaleThreshold())),
+	}
+	for _, group := range report.ByProject() {
+		for _, repo := range group.Repos {
+			heading := fmt.Sprintf("*Repository: %s*", slackEscape(repo.Repo))
+			if group.Project != "" {
+				heading = fmt.Sprintf("*Project: %s · Repository: %s*", slackEscape(group.Project), slackEscape(repo.Repo))
+			}
+			blocks = append(blocks, slackBlock{Type: "divider"}, section(heading))
+			for _, pr := range repo.PRs {
+				blocks = append(blocks, section(slackPR(report, pr)))
+			}
+		}
+	}
+	blocks = append(blocks, slackBlock{Type: "context", Elements: []slackText{
+		{Type: "mrkdwn", Text: fmt.Sprintf("Total stale PRs: *%d* · PR Tracker", len(report.PRs))},
+	}})
+
+	// Every message after the first starts with a context block naming the alert it continues
+	var chunks [][]slackBlock
+	for len(blocks) > 0 {
+		size := slackMaxBlocks
+		if len(chunks) > 0 {
+			size--
+		}
+		if size > len(blocks) {
+			size = len(blocks)
+		}
+		chunks = append(chunks, blocks[:size])
+		blocks = blocks[size:]
+	}
+	messages := make([]slackMessage, len(chunks))
+	for i, chunk := range chunks {
+		text := summary
+		if len(chunks) > 1 {
+			text = fmt.Sprintf("%s (%d/%d)", summary, i+1, len(chunks))
+		}
+		if i > 0 {
+			chunk = append([]slackBlock{{Type: "context", Elements: []slackText{{Type: "mrkdwn", Text: slackEscape(text)}}}}, chunk...)
+		}
+		messages[i] = slackMessage{Channel: s.config.Channel, Text: text, Blocks: chunk}
+	}
+	return messages
+}
+
+// slackPR describes one stale PR of report in mrkdwn
+func slackPR(report *models.Report, pr models.EnrichedPR) string {
+	title := slackEscape(fmt.Sprintf("#%d %s", pr.ID, pr.Title))
+	if link := pr.Link(); link != "" {
+		title = fmt.Sprintf("<%s|%s>", link, title)
+	}
+	approval := "⚪"
+	switch {
+	case pr.Reviewers > 0 && pr.Approvals == pr.Reviewers:
+		approval = "✅"
+	case pr.Approvals > 0:
+		approval = "🔵"
+	}
+	return fmt.Sprintf("%s\nby %s · %s %d/%d approvals · ⏳ %s idle",
+		title, slackEscape(pr.Author.User.DisplayName), approval, pr.Approvals, pr.Reviewers, report.Days(pr.DaysInactive))
+}
+
+// section builds a mrkdwn section block, truncating text beyond Slack's limit
+func section(text string) slackBlock {
+	return slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: truncate(text, slackMaxSectionText)}}
+}
+
+// slackEscape escapes the characters Slack reserves for links and mentions
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// truncate shortens s to at most limit characters, ending with an ellipsis when cut
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+// post sends one message to the webhook or, with a bot token, to chat.postMessage
+func (s *SlackNotifier) post(ctx context.Context, msg slackMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	url := s.config.WebhookURL
+	if s.config.Token != "" {
+		url = strings.TrimSuffix(s.config.BaseURL, "/") + "/chat.postMessage"
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create Slack request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if s.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.Token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Slack responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if s.config.Token == "" {
+		return nil
+	}
+	// The Web API reports errors in the body of 200 responses
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("invalid chat.postMessage response: %v", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("chat.postMessage failed: %s", result.Error)
+	}
+	return nil
+}
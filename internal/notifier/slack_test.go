@@ -0,0 +1,161 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fc-pr-tracker/internal/config"
+	"fc-pr-tracker/pkg/models"
+)
+
+// slackReport builds a report of n stale PRs in repo
+func slackReport(n int) *models.Report {
+	var prs []models.PullRequest
+	for i := 1; i <= n; i++ {
+		pr := models.PullRequest{ID: i, Title: fmt.Sprintf("Change %d", i)}
+		pr.Author.User.DisplayName = "Alice"
+		pr.Links.Self = append(pr.Links.Self, struct {
+			Href string `json:"href"`
+		}{Href: fmt.Sprintf("https://example.com/pr/%d", i)})
+		prs = append(prs, pr)
+	}
+	return newTestReport(3, map[string][]models.PullRequest{"repo": prs}, nil)
+}
+
+func TestSlackNotifier_GenerateSlackMessages(t *testing.T) {
+	report := slackReport(1)
+	report.PRs[0].Title = "Fix <b> & co"
+	report.PRs[0].Approvals, report.PRs[0].Reviewers, report.PRs[0].DaysInactive = 2, 2, 5
+	report.Tier = "Leads"
+
+	messages := NewSlackNotifier(&config.Config{}).generateSlackMessages(report)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	payload, _ := json.Marshal(messages[0])
+	for _, want := range []string{
+		`"text":"[Leads] Stale Pull Requests Alert - 1 PRs need attention"`,
+		`{"type":"header","text":{"type":"plain_text","text":"🚨 Stale Pull Requests Alert · Escalation: Leads"}}`,
+		`*Repository: repo*`,
+		`Total stale PRs: *1*`,
+	} {
+		if !strings.Contains(string(payload), want) {
+			t.Errorf("Expected payload to contain %s, got:\n%s", want, payload)
+		}
+	}
+	expected := "<https://example.com/pr/1|#1 Fix &lt;b&gt; &amp; co>\nby Alice · ✅ 2/2 approvals · ⏳ 5 days idle"
+	if pr := messages[0].Blocks[4]; pr.Text == nil || pr.Text.Text != expected {
+		t.Errorf("Expected the PR section %q, got %+v", expected, pr)
+	}
+}
+
+func TestSlackPR_BusinessDaysWithoutLink(t *testing.T) {
+	report := slackReport(1)
+	report.BusinessDays = true
+	report.PRs[0].Links.Self = nil
+	report.PRs[0].DaysInactive = 4
+
+	expected := "#1 Change 1\nby Alice · ⚪ 0/0 approvals · ⏳ 4 business days idle"
+	if got := slackPR(report, report.PRs[0]); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestSlackNotifier_GenerateSlackMessages_Split(t *testing.T) {
+	// 2 intro blocks, 2 repository blocks, 120 PR blocks and a footer: 125 blocks in 3 messages
+	messages := NewSlackNotifier(&config.Config{}).generateSlackMessages(slackReport(120))
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(messages))
+	}
+	total := 0
+	for i, msg := range messages {
+		if len(msg.Blocks) > slackMaxBlocks {
+			t.Errorf("Message %d has %d blocks, more than %d", i+1, len(msg.Blocks), slackMaxBlocks)
+		}
+		if want := fmt.Sprintf("(%d/3)", i+1); !strings.HasSuffix(msg.Text, want) {
+			t.Errorf("Expected message %d text to end with %s, got %q", i+1, want, msg.Text)
+		}
+		if i > 0 && msg.Blocks[0].Type != "context" {
+			t.Errorf("Expected message %d to start with a context block, got %s", i+1, msg.Blocks[0].Type)
+		}
+		total += len(msg.Blocks)
+	}
+	if total != 125+2 {
+		t.Errorf("Expected 125 blocks and 2 continuation blocks, got %d", total)
+	}
+}
+
+func TestSlackNotifier_Notify_Webhook(t *testing.T) {
+	var received []slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg slackMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("Invalid payload: %v", err)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("Expected no Authorization header for a webhook, got %q", auth)
+		}
+		received = append(received, msg)
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{Notifiers: config.NotifiersConfig{Slack: config.SlackConfig{WebhookURL: server.URL}}}
+	if err := NewSlackNotifier(cfg).Notify(context.Background(), slackReport(60)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(received) != 2 || received[0].Channel != "" {
+		t.Errorf("Expected 2 messages without a channel, got %+v", received)
+	}
+}
+
+func TestSlackNotifier_Notify_BotToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		status   int
+		errMsg   string
+	}{
+		{"posted", `{"ok":true,"ts":"1.2"}`, http.StatusOK, ""},
+		{"API error", `{"ok":false,"error":"channel_not_found"}`, http.StatusOK, "channel_not_found"},
+		{"rate limited", `{"ok":false,"error":"ratelimited"}`, http.StatusTooManyRequests, "status 429"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/chat.postMessage" {
+					t.Errorf("Expected chat.postMessage, got %s", r.URL.Path)
+				}
+				if auth := r.Header.Get("Authorization"); auth != "Bearer xoxb-test" {
+					t.Errorf("Expected the bot token, got %q", auth)
+				}
+				var msg slackMessage
+				json.NewDecoder(r.Body).Decode(&msg)
+				if msg.Channel != "#reviews" {
+					t.Errorf("Expected channel #reviews, got %q", msg.Channel)
+				}
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.response)
+			}))
+			defer server.Close()
+
+			cfg := &config.Config{Notifiers: config.NotifiersConfig{Slack: config.SlackConfig{
+				Token: "xoxb-test", Channel: "#reviews", BaseURL: server.URL + "/api/",
+			}}}
+			err := NewSlackNotifier(cfg).Notify(context.Background(), slackReport(1))
+			if tt.errMsg == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.errMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.errMsg)) {
+				t.Errorf("Expected an error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
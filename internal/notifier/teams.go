@@ -2,65 +2,137 @@ package notifier
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"text/template"
 
-	"fc-pr-tracker/internal/bitbucket"
 	"fc-pr-tracker/internal/config"
+	"fc-pr-tracker/internal/templates"
 	"fc-pr-tracker/pkg/models"
 )
 
 // TeamsNotifier implements Microsoft Teams notifications
 type TeamsNotifier struct {
 	webhookURL string
+	format     string             // config.TeamsMessageCard or config.TeamsAdaptiveCard
+	template   *template.Template // renders the payload instead of the built-in card when set
 }
 
 // NewTeamsNotifier creates a new Teams notifier
 func NewTeamsNotifier(cfg *config.Config) *TeamsNotifier {
-	return &TeamsNotifier{webhookURL: cfg.Notifiers.Teams.WebhookURL}
+	t := &TeamsNotifier{webhookURL: cfg.Notifiers.Teams.WebhookURL, format: cfg.Notifiers.Teams.Format}
+	if path := cfg.Notifiers.Teams.Template; path != "" {
+		loc, cal := templateClock(cfg)
+		var err error
+		// Load validates the template, so only hand-built configurations or files changed since fail here
+		if t.template, err = templates.ParseText(path, loc, cal); err != nil {
+			slog.Error("Invalid Teams template, using the built-in card", "path", path, "error", err)
+		}
+	}
+	return t
 }
 
 // Notify sends Teams notifications for stale PRs
-func (t *TeamsNotifier) Notify(allPRs []models.PullRequest, repoPRs map[string][]models.PullRequest,
-	prParticipants map[int][]models.Participant, staleAfterDays int) error {
-
-	if len(allPRs) == 0 {
+func (t *TeamsNotifier) Notify(ctx context.Context, report *models.Report) error {
+	if len(report.PRs) == 0 {
 		return nil
 	}
 
-	payload, err := t.generateTeamsPayload(allPRs, repoPRs, prParticipants, staleAfterDays)
+	payload, err := t.generateTeamsPayload(report)
 	if err != nil {
 		return fmt.Errorf("error generating Teams payload: %v", err)
 	}
 
-	return t.sendTeamsNotification(payload)
+	return t.sendTeamsNotification(ctx, payload)
 }
 
-// generateTeamsPayload creates the Teams message payload
-func (t *TeamsNotifier) generateTeamsPayload(allPRs []models.PullRequest, repoPRs map[string][]models.PullRequest,
-	prParticipants map[int][]models.Participant, staleAfterDays int) ([]byte, error) {
+// Name identifies the notifier in logs and previews
+func (t *TeamsNotifier) Name() string {
+	return "teams"
+}
+
+// Preview writes the indented Teams payload to w
+func (t *TeamsNotifier) Preview(w io.Writer, report *models.Report) error {
+	payload, err := t.generateTeamsPayload(report)
+	if err != nil {
+		return fmt.Errorf("error generating Teams payload: %v", err)
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, payload, "", "  "); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+	_, err = out.WriteTo(w)
+	return err
+}
+
+// generateTeamsPayload creates the Teams message payload: a MessageCard, or an Adaptive Card
+// for Workflows webhooks
+func (t *TeamsNotifier) generateTeamsPayload(report *models.Report) ([]byte, error) {
+	if t.template != nil {
+		payload, err := render(t.template, templates.NewData(report))
+		if err != nil {
+			return nil, err
+		}
+		if !json.Valid([]byte(payload)) {
+			return nil, fmt.Errorf("template %s did not render valid JSON", t.template.Name())
+		}
+		return []byte(payload), nil
+	}
+	if t.format == config.TeamsAdaptiveCard {
+		return t.generateAdaptiveCard(report)
+	}
 
 	var sections []map[string]interface{}
 
-	for repo, prs := range repoPRs {
-		var facts []map[string]interface{}
-		for _, pr := range prs {
-			// Calculate approval count for this PR
-			participants := prParticipants[pr.ID]
-			approved, total := bitbucket.CountApprovals(participants)
-
-			facts = append(facts, map[string]interface{}{
-				"name": fmt.Sprintf("PR #%d", pr.ID),
-				"value": fmt.Sprintf("[%s](%s) by %s (%d/%d approvals)",
-					pr.Title, pr.Links.Self[0].Href, pr.Author.User.DisplayName, approved, total),
+	for _, group := range report.ByProject() {
+		for _, repo := range group.Repos {
+			var facts []map[string]interface{}
+			for _, pr := range repo.PRs {
+				title := pr.Title
+				if link := pr.Link(); link != "" {
+					title = fmt.Sprintf("[%s](%s)", pr.Title, link)
+				}
+				facts = append(facts, map[string]interface{}{
+					"name": fmt.Sprintf("PR #%d", pr.ID),
+					"value": fmt.Sprintf("%s by %s (%d/%d approvals)",
+						title, pr.Author.User.DisplayName, pr.Approvals, pr.Reviewers),
+				})
+			}
+
+			title := fmt.Sprintf("Repository: %s", repo.Repo)
+			if group.Project != "" {
+				title = fmt.Sprintf("Project: %s · Repository: %s", group.Project, repo.Repo)
+			}
+			sections = append(sections, map[string]interface{}{
+				"activityTitle": title,
+				"facts":         facts,
 			})
 		}
+	}
 
-		sections = append(sections, map[string]interface{}{
-			"activityTitle": fmt.Sprintf("Repository: %s", repo),
-			"facts":         facts,
+	title := "🚨 Stale Pull Requests Alert"
+	summary := fmt.Sprintf("Stale Pull Requests Alert - %d PRs need attention", len(report.PRs))
+	facts := []map[string]interface{}{
+		{
+			"name":  "Total Stale PRs",
+			"value": fmt.Sprintf("%d", len(report.PRs)),
+		},
+		{
+			"name":  "Stale Threshold",
+			"value": report.StaleThreshold(),
+		},
+	}
+	if report.Tier != "" {
+		title += " · Escalation: " + report.Tier
+		summary = fmt.Sprintf("[%s] %s", report.Tier, summary)
+		facts = append(facts, map[string]interface{}{
+			"name":  "Escalation Tier",
+			"value": report.Tier,
 		})
 	}
 
@@ -68,25 +140,16 @@ func (t *TeamsNotifier) generateTeamsPayload(allPRs []models.PullRequest, repoPR
 		"@type":      "MessageCard",
 		"@context":   "http://schema.org/extensions",
 		"themeColor": "FF0000",
-		"summary":    fmt.Sprintf("Stale Pull Requests Alert - %d PRs need attention", len(allPRs)),
+		"summary":    summary,
 		"sections": append([]map[string]interface{}{
 			{
-				"activityTitle":    "🚨 Stale Pull Requests Alert",
-				"activitySubtitle": fmt.Sprintf("%d pull requests have been inactive for %d days or more", len(allPRs), staleAfterDays),
+				"activityTitle":    title,
+				"activitySubtitle": fmt.Sprintf("%d pull requests have been inactive for %s or more", len(report.PRs), report.StaleThreshold()),
 				"text":             "The following pull requests need attention:",
 			},
 		}, append(sections, map[string]interface{}{
 			"activityTitle": "📊 Summary",
-			"facts": []map[string]interface{}{
-				{
-					"name":  "Total Stale PRs",
-					"value": fmt.Sprintf("%d", len(allPRs)),
-				},
-				{
-					"name":  "Stale Threshold",
-					"value": fmt.Sprintf("%d days", staleAfterDays),
-				},
-			},
+			"facts":         facts,
 		})...),
 	}
 
@@ -94,15 +157,22 @@ func (t *TeamsNotifier) generateTeamsPayload(allPRs []models.PullRequest, repoPR
 }
 
 // sendTeamsNotification sends the notification to Microsoft Teams
-func (t *TeamsNotifier) sendTeamsNotification(payload []byte) error {
-	resp, err := http.Post(t.webhookURL, "application/json", bytes.NewBuffer(payload))
+func (t *TeamsNotifier) sendTeamsNotification(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", t.webhookURL, bytes.NewBuffer(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create Teams request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		slog.Error("Failed to send Teams notification", "error", err)
 		return fmt.Errorf("failed to send Teams notification: %v", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	// Connectors answer 200, Workflows webhooks 202 once the flow is triggered
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
 		slog.Error("Teams notification failed", "status", resp.StatusCode)
 		return fmt.Errorf("Teams notification failed with status: %d", resp.StatusCode)
 	}
@@ -1,9 +1,14 @@
 package notifier
 
 import (
+	"context"
 	"encoding/json"
 	"fc-pr-tracker/internal/config"
 	"fc-pr-tracker/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -11,22 +16,8 @@ import (
 
 func TestNewTeamsNotifier(t *testing.T) {
 	cfg := &config.Config{
-		Notifiers: struct {
-			SMTP struct {
-				Host     string   `yaml:"host"`
-				Port     int      `yaml:"port"`
-				User     string   `yaml:"user"`
-				Password string   `yaml:"password"`
-				From     string   `yaml:"from"`
-				To       []string `yaml:"to"`
-			} `yaml:"smtp"`
-			Teams struct {
-				WebhookURL string `yaml:"webhook_url"`
-			} `yaml:"teams"`
-		}{
-			Teams: struct {
-				WebhookURL string `yaml:"webhook_url"`
-			}{
+		Notifiers: config.NotifiersConfig{
+			Teams: config.TeamsConfig{
 				WebhookURL: "https://webhook.url",
 			},
 		},
@@ -46,7 +37,7 @@ func TestTeamsNotifier_Notify_EmptyPRs(t *testing.T) {
 	cfg := &config.Config{}
 	notifier := NewTeamsNotifier(cfg)
 
-	err := notifier.Notify([]models.PullRequest{}, map[string][]models.PullRequest{}, map[int][]models.Participant{}, 7)
+	err := notifier.Notify(context.Background(), &models.Report{StaleAfterDays: 7})
 	if err != nil {
 		t.Errorf("Expected no error when no PRs, got: %v", err)
 	}
@@ -69,6 +60,7 @@ func TestTeamsNotifier_GenerateTeamsPayload(t *testing.T) {
 			User struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			} `json:"user"`
 			Role     string `json:"role"`
 			Approved bool   `json:"approved"`
@@ -77,6 +69,7 @@ func TestTeamsNotifier_GenerateTeamsPayload(t *testing.T) {
 			User: struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			}{
 				DisplayName: "Test User",
 				Username:    "testuser",
@@ -104,6 +97,7 @@ func TestTeamsNotifier_GenerateTeamsPayload(t *testing.T) {
 			User struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			} `json:"user"`
 			Role     string `json:"role"`
 			Approved bool   `json:"approved"`
@@ -112,6 +106,7 @@ func TestTeamsNotifier_GenerateTeamsPayload(t *testing.T) {
 			User: struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			}{
 				DisplayName: "Another User",
 				Username:    "anotheruser",
@@ -130,7 +125,6 @@ func TestTeamsNotifier_GenerateTeamsPayload(t *testing.T) {
 		},
 	}
 
-	allPRs := []models.PullRequest{pr1, pr2}
 	repoPRs := map[string][]models.PullRequest{
 		"test-repo": {pr1, pr2},
 	}
@@ -144,7 +138,7 @@ func TestTeamsNotifier_GenerateTeamsPayload(t *testing.T) {
 		},
 	}
 
-	payload, err := notifier.generateTeamsPayload(allPRs, repoPRs, prParticipants, 7)
+	payload, err := notifier.generateTeamsPayload(newTestReport(7, repoPRs, prParticipants))
 	if err != nil {
 		t.Fatalf("Expected no error generating Teams payload, got: %v", err)
 	}
@@ -220,6 +214,7 @@ func TestTeamsNotifier_GenerateTeamsPayload_NoParticipants(t *testing.T) {
 			User struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			} `json:"user"`
 			Role     string `json:"role"`
 			Approved bool   `json:"approved"`
@@ -228,6 +223,7 @@ func TestTeamsNotifier_GenerateTeamsPayload_NoParticipants(t *testing.T) {
 			User: struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			}{
 				DisplayName: "Test User",
 				Username:    "testuser",
@@ -246,13 +242,12 @@ func TestTeamsNotifier_GenerateTeamsPayload_NoParticipants(t *testing.T) {
 		},
 	}
 
-	allPRs := []models.PullRequest{pr}
 	repoPRs := map[string][]models.PullRequest{
 		"test-repo": {pr},
 	}
 	prParticipants := map[int][]models.Participant{}
 
-	payload, err := notifier.generateTeamsPayload(allPRs, repoPRs, prParticipants, 7)
+	payload, err := notifier.generateTeamsPayload(newTestReport(7, repoPRs, prParticipants))
 	if err != nil {
 		t.Fatalf("Expected no error generating Teams payload, got: %v", err)
 	}
@@ -267,6 +262,20 @@ func TestTeamsNotifier_GenerateTeamsPayload_NoParticipants(t *testing.T) {
 	}
 }
 
+func TestTeamsNotifier_GenerateTeamsPayload_NoLink(t *testing.T) {
+	notifier := NewTeamsNotifier(&config.Config{})
+	pr := models.PullRequest{ID: 1, Title: "Cloud PR"}
+	pr.Author.User.DisplayName = "Test User"
+
+	payload, err := notifier.generateTeamsPayload(newTestReport(7, map[string][]models.PullRequest{"test-repo": {pr}}, nil))
+	if err != nil {
+		t.Fatalf("Expected no error generating Teams payload, got: %v", err)
+	}
+	if !strings.Contains(string(payload), `"value":"Cloud PR by Test User (0/0 approvals)"`) {
+		t.Errorf("Expected the PR title without a link, got %s", payload)
+	}
+}
+
 func TestTeamsNotifier_GenerateTeamsPayload_MultipleRepos(t *testing.T) {
 	cfg := &config.Config{}
 	notifier := NewTeamsNotifier(cfg)
@@ -278,6 +287,7 @@ func TestTeamsNotifier_GenerateTeamsPayload_MultipleRepos(t *testing.T) {
 			User struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			} `json:"user"`
 			Role     string `json:"role"`
 			Approved bool   `json:"approved"`
@@ -286,6 +296,7 @@ func TestTeamsNotifier_GenerateTeamsPayload_MultipleRepos(t *testing.T) {
 			User: struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			}{
 				DisplayName: "User 1",
 				Username:    "user1",
@@ -311,6 +322,7 @@ func TestTeamsNotifier_GenerateTeamsPayload_MultipleRepos(t *testing.T) {
 			User struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			} `json:"user"`
 			Role     string `json:"role"`
 			Approved bool   `json:"approved"`
@@ -319,6 +331,7 @@ func TestTeamsNotifier_GenerateTeamsPayload_MultipleRepos(t *testing.T) {
 			User: struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			}{
 				DisplayName: "User 2",
 				Username:    "user2",
@@ -337,14 +350,13 @@ func TestTeamsNotifier_GenerateTeamsPayload_MultipleRepos(t *testing.T) {
 		},
 	}
 
-	allPRs := []models.PullRequest{pr1, pr2}
 	repoPRs := map[string][]models.PullRequest{
 		"repo1": {pr1},
 		"repo2": {pr2},
 	}
 	prParticipants := map[int][]models.Participant{}
 
-	payload, err := notifier.generateTeamsPayload(allPRs, repoPRs, prParticipants, 7)
+	payload, err := notifier.generateTeamsPayload(newTestReport(7, repoPRs, prParticipants))
 	if err != nil {
 		t.Fatalf("Expected no error generating Teams payload, got: %v", err)
 	}
@@ -373,7 +385,7 @@ func TestTeamsNotifier_SendTeamsNotification_Success(t *testing.T) {
 
 	// This test will fail because the webhook URL is invalid, but it tests the code path
 	payload := []byte(`{"test": "payload"}`)
-	err := notifier.sendTeamsNotification(payload)
+	err := notifier.sendTeamsNotification(context.Background(), payload)
 
 	// We expect an error because the webhook URL is invalid
 	// But this tests that the function executes without panicking
@@ -391,7 +403,7 @@ func TestTeamsNotifier_SendTeamsNotification_InvalidURL(t *testing.T) {
 	notifier := NewTeamsNotifier(cfg)
 
 	payload := []byte(`{"test": "payload"}`)
-	err := notifier.sendTeamsNotification(payload)
+	err := notifier.sendTeamsNotification(context.Background(), payload)
 
 	if err == nil {
 		t.Error("Expected error when using invalid webhook URL")
@@ -417,6 +429,7 @@ func TestTeamsNotifier_Notify_WithPRs(t *testing.T) {
 			User struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			} `json:"user"`
 			Role     string `json:"role"`
 			Approved bool   `json:"approved"`
@@ -425,6 +438,7 @@ func TestTeamsNotifier_Notify_WithPRs(t *testing.T) {
 			User: struct {
 				DisplayName string `json:"displayName"`
 				Username    string `json:"name"`
+				Email       string `json:"emailAddress"`
 			}{
 				DisplayName: "Test User",
 				Username:    "testuser",
@@ -443,14 +457,13 @@ func TestTeamsNotifier_Notify_WithPRs(t *testing.T) {
 		},
 	}
 
-	allPRs := []models.PullRequest{pr}
 	repoPRs := map[string][]models.PullRequest{
 		"test-repo": {pr},
 	}
 	prParticipants := map[int][]models.Participant{}
 
 	// This will fail because the webhook URL is invalid, but it tests the code path
-	err := notifier.Notify(allPRs, repoPRs, prParticipants, 7)
+	err := notifier.Notify(context.Background(), newTestReport(7, repoPRs, prParticipants))
 
 	if err == nil {
 		t.Log("Notify executed successfully (webhook available)")
@@ -466,9 +479,181 @@ func TestTeamsNotifier_SendTeamsNotification_NetworkError(t *testing.T) {
 	notifier := NewTeamsNotifier(cfg)
 
 	payload := []byte(`{"test": "payload"}`)
-	err := notifier.sendTeamsNotification(payload)
+	err := notifier.sendTeamsNotification(context.Background(), payload)
 
 	if err == nil {
 		t.Error("Expected error when connecting to invalid host")
 	}
 }
+
+func TestTeamsNotifier_SendTeamsNotification_ContextCancelled(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Notifiers.Teams.WebhookURL = "http://127.0.0.1:1/webhook"
+
+	notifier := NewTeamsNotifier(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := notifier.sendTeamsNotification(ctx, []byte(`{"test": "payload"}`))
+	if err == nil {
+		t.Error("Expected error when context is cancelled")
+	}
+}
+
+func TestTeamsNotifier_GenerateTeamsPayload_Tier(t *testing.T) {
+	pr := models.PullRequest{ID: 1, Title: "Forgotten"}
+	pr.Links.Self = append(pr.Links.Self, struct {
+		Href string `json:"href"`
+	}{Href: "https://example.com/pr/1"})
+	report := newTestReport(7, map[string][]models.PullRequest{"repo": {pr}}, nil)
+	report.Tier = "Team"
+
+	payload, err := NewTeamsNotifier(&config.Config{}).generateTeamsPayload(report)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{`"summary":"[Team] Stale Pull Requests Alert - 1 PRs need attention"`,
+		`Stale Pull Requests Alert · Escalation: Team`, `"name":"Escalation Tier","value":"Team"`, `"value":"7 days"`} {
+		if !strings.Contains(string(payload), want) {
+			t.Errorf("Expected payload to contain %s, got:\n%s", want, payload)
+		}
+	}
+}
+
+func TestTeamsNotifier_GenerateTeamsPayload_Template(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teams.json.tmpl")
+	tmpl := `{"text": {{json (printf "%d stale, first: %s" .TotalPRs (index .PRs 0).Title)}}}`
+	if err := os.WriteFile(path, []byte(tmpl), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{Notifiers: config.NotifiersConfig{Teams: config.TeamsConfig{Template: path}}}
+	report := newTestReport(7, map[string][]models.PullRequest{"repo": {{ID: 1, Title: `Say "hi"`}}}, nil)
+
+	payload, err := NewTeamsNotifier(cfg).generateTeamsPayload(report)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := `{"text": "1 stale, first: Say \"hi\""}`; string(payload) != expected {
+		t.Errorf("Expected payload %s, got %s", expected, payload)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"text": {{.TotalPRs}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTeamsNotifier(cfg).generateTeamsPayload(report); err == nil || !strings.Contains(err.Error(), "valid JSON") {
+		t.Errorf("Expected an invalid JSON error, got %v", err)
+	}
+}
+
+func TestTeamsNotifier_GenerateAdaptiveCard(t *testing.T) {
+	pr := models.PullRequest{ID: 3, Title: "Adaptive"}
+	pr.Author.User.DisplayName = "Alice"
+	pr.Links.Self = append(pr.Links.Self, struct {
+		Href string `json:"href"`
+	}{Href: "https://example.com/pr/3"})
+	reviewer := func(name, email string, approved bool) models.Participant {
+		var p models.Participant
+		p.User.DisplayName, p.User.Email, p.Role, p.Approved = name, email, "REVIEWER", approved
+		return p
+	}
+	report := newTestReport(5, map[string][]models.PullRequest{"repo": {pr}}, map[int][]models.Participant{
+		3: {reviewer("Bob", "bob@example.com", false), reviewer("Carol", "carol@example.com", true), reviewer("Dave", "", false)},
+	})
+	report.PRs[0].DaysInactive = 9
+	report.Tier = "Leads"
+
+	cfg := &config.Config{Notifiers: config.NotifiersConfig{Teams: config.TeamsConfig{Format: config.TeamsAdaptiveCard}}}
+	payload, err := NewTeamsNotifier(cfg).generateTeamsPayload(report)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var msg struct {
+		Type        string
+		Attachments []struct {
+			ContentType string
+			Content     struct {
+				Type    string
+				Version string
+				Body    []map[string]interface{}
+				MSTeams struct {
+					Entities []struct {
+						Type      string
+						Text      string
+						Mentioned struct{ ID, Name string }
+					}
+				} `json:"msteams"`
+			}
+		}
+	}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		t.Fatalf("Invalid payload: %v", err)
+	}
+	if msg.Type != "message" || len(msg.Attachments) != 1 || msg.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("Expected a message with one Adaptive Card attachment, got %s", payload)
+	}
+	card := msg.Attachments[0].Content
+	if card.Type != "AdaptiveCard" || card.Version != "1.5" {
+		t.Errorf("Expected an Adaptive Card 1.5, got %s %s", card.Type, card.Version)
+	}
+	entities := card.MSTeams.Entities
+	if len(entities) != 1 || entities[0].Text != "<at>Bob</at>" || entities[0].Mentioned.ID != "bob@example.com" {
+		t.Errorf("Expected a mention of Bob only, got %+v", entities)
+	}
+	for _, want := range []string{`Stale Pull Requests Alert · Escalation: Leads`, `"type":"Table"`, `"text":"#3 Adaptive"`,
+		`"text":"\u003cat\u003eBob\u003c/at\u003e, Dave"`, `"text":"9 days"`, `"text":"1/3"`,
+		`{"title":"Open","type":"Action.OpenUrl","url":"https://example.com/pr/3"}`} {
+		if !strings.Contains(string(payload), want) {
+			t.Errorf("Expected payload to contain %s, got:\n%s", want, payload)
+		}
+	}
+}
+
+func TestTeamsNotifier_SendTeamsNotification_Status(t *testing.T) {
+	tests := []struct {
+		status  int
+		wantErr bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusAccepted, false},
+		{http.StatusBadRequest, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			cfg := &config.Config{Notifiers: config.NotifiersConfig{Teams: config.TeamsConfig{WebhookURL: server.URL}}}
+			err := NewTeamsNotifier(cfg).sendTeamsNotification(context.Background(), []byte(`{}`))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTeamsNotifier_GenerateAdaptiveCard_BusinessDays(t *testing.T) {
+	// A PR without link gets no Open button
+	pr := models.PullRequest{ID: 4, Title: "Weekend"}
+	report := newTestReport(3, map[string][]models.PullRequest{"repo": {pr}}, nil)
+	report.BusinessDays = true
+	report.PRs[0].DaysInactive = 4
+
+	cfg := &config.Config{Notifiers: config.NotifiersConfig{Teams: config.TeamsConfig{Format: config.TeamsAdaptiveCard}}}
+	payload, err := NewTeamsNotifier(cfg).generateTeamsPayload(report)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{`"text":"4 business days"`, `"value":"3 business days"`} {
+		if !strings.Contains(string(payload), want) {
+			t.Errorf("Expected payload to contain %s, got:\n%s", want, payload)
+		}
+	}
+	if strings.Contains(string(payload), "Action.OpenUrl") || strings.Contains(string(payload), `"text":"4 days"`) {
+		t.Errorf("Expected no Open button and no calendar days, got:\n%s", payload)
+	}
+}
@@ -0,0 +1,162 @@
+package provider
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"fc-pr-tracker/internal/bitbucket"
+	"fc-pr-tracker/internal/config"
+	"fc-pr-tracker/internal/github"
+	"fc-pr-tracker/internal/gitlab"
+	"fc-pr-tracker/pkg/models"
+)
+
+// Provider is implemented by every SCM backend the tracker can poll
+type Provider interface {
+	// Name identifies the provider in logs
+	Name() string
+	// Host identifies the API server, used to apply per-host concurrency limits
+	Host() string
+	TestConnection(ctx context.Context) error
+	ListOpenPRs(ctx context.Context, repo string) ([]models.PullRequest, error)
+	GetParticipants(ctx context.Context, repo string, prID int) ([]models.Participant, error)
+	GetComments(ctx context.Context, repo string, prID int) ([]models.Comment, error)
+}
+
+// RetryReporter is implemented by providers that retry transient API failures
+type RetryReporter interface {
+	// TakeRetryStats returns the retry activity since the previous call and resets it
+	TakeRetryStats() bitbucket.RetryStats
+}
+
+// Repository binds a configured repository to the provider that serves it
+type Repository struct {
+	Name     string // as passed to the provider, e.g. "repo" or "PROJECT/repo"
+	Project  string // project key, workspace, owner or group the repository belongs to
+	Provider Provider
+}
+
+// Slug returns the repository name without its project prefix
+func (r Repository) Slug() string {
+	return r.Name[strings.LastIndex(r.Name, "/")+1:]
+}
+
+// Key identifies the repository across projects, as "PROJECT/slug"
+func (r Repository) Key() string {
+	if r.Project == "" {
+		return r.Slug()
+	}
+	return r.Project + "/" + r.Slug()
+}
+
+// id identifies the repository across providers, as "host/PROJECT/slug", so the same
+// repository written with or without its project prefix is recognized
+func (r Repository) id() string {
+	return r.Provider.Host() + "/" + r.Key()
+}
+
+// newRepository binds name to p, taking the project from a "PROJECT/" prefix or defaulting to project
+func newRepository(name, project string, p Provider) Repository {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		project = name[:i]
+	}
+	return Repository{Name: name, Project: project, Provider: p}
+}
+
+// Discoverer is implemented by providers that can enumerate the repositories to track
+type Discoverer interface {
+	DiscoverRepositories(ctx context.Context) ([]string, error)
+}
+
+// Catalog holds the configured providers and resolves the repositories they serve
+type Catalog struct {
+	Providers []Provider
+
+	static     []Repository
+	projects   map[Provider]string   // default project of every provider
+	discovered map[Provider][]string // last successful discovery, reused when a refresh fails
+}
+
+// NewCatalog creates one provider for every SCM section present in the configuration
+// and binds the repositories listed in each section to it
+func NewCatalog(cfg *config.Config) *Catalog {
+	c := &Catalog{projects: make(map[Provider]string)}
+	if cfg.Bitbucket.Domain != "" || len(cfg.Bitbucket.Repositories) > 0 || len(cfg.Bitbucket.Discovery.Projects) > 0 {
+		c.add(bitbucket.NewClient(cfg), cfg.Bitbucket.Workspace, cfg.Bitbucket.Repositories)
+	}
+	for _, t := range cfg.Bitbucket.Targets {
+		c.add(bitbucket.NewClient(cfg.ForTarget(t)), t.Project, t.Repositories)
+	}
+	if cfg.BitbucketCloud.Workspace != "" || len(cfg.BitbucketCloud.Repositories) > 0 {
+		c.add(bitbucket.NewCloudClient(cfg), cfg.BitbucketCloud.Workspace, cfg.BitbucketCloud.Repositories)
+	}
+	if len(cfg.GitHub.Repositories) > 0 {
+		c.add(github.NewClient(cfg), cfg.GitHub.Owner, cfg.GitHub.Repositories)
+	}
+	if len(cfg.GitLab.Repositories) > 0 {
+		c.add(gitlab.NewClient(cfg), cfg.GitLab.Group, cfg.GitLab.Repositories)
+	}
+	return c
+}
+
+// NewStaticCatalog serves the given repositories, for providers built by hand
+func NewStaticCatalog(repos []Repository) *Catalog {
+	c := &Catalog{static: repos}
+	seen := make(map[Provider]bool)
+	for _, r := range repos {
+		if !seen[r.Provider] {
+			seen[r.Provider] = true
+			c.Providers = append(c.Providers, r.Provider)
+		}
+	}
+	return c
+}
+
+func (c *Catalog) add(p Provider, project string, names []string) {
+	c.Providers = append(c.Providers, p)
+	c.projects[p] = project
+	for _, name := range names {
+		c.static = append(c.static, newRepository(name, project, p))
+	}
+}
+
+// Configured returns the repositories listed in the configuration, without running discovery
+func (c *Catalog) Configured() []Repository {
+	return append([]Repository(nil), c.static...)
+}
+
+// Repositories returns the configured repositories, in configuration order, followed by
+// the ones discovered now. A provider whose discovery fails keeps its previous result.
+func (c *Catalog) Repositories(ctx context.Context) []Repository {
+	repos := append([]Repository(nil), c.static...)
+	seen := make(map[string]bool)
+	for _, r := range repos {
+		seen[r.id()] = true
+	}
+
+	for _, p := range c.Providers {
+		d, ok := p.(Discoverer)
+		if !ok {
+			continue
+		}
+		names, err := d.DiscoverRepositories(ctx)
+		if err != nil {
+			slog.Warn("Repository discovery failed, using the previous result", "provider", p.Name(), "error", err)
+			names = c.discovered[p]
+		} else {
+			if c.discovered == nil {
+				c.discovered = make(map[Provider][]string)
+			}
+			c.discovered[p] = names
+		}
+		for _, name := range names {
+			r := newRepository(name, c.projects[p], p)
+			if !seen[r.id()] {
+				seen[r.id()] = true
+				repos = append(repos, r)
+			}
+		}
+	}
+	return repos
+}
@@ -0,0 +1,162 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fc-pr-tracker/internal/config"
+	"fc-pr-tracker/pkg/models"
+	"testing"
+)
+
+func TestRepositories(t *testing.T) {
+	cfg := &config.Config{
+		Bitbucket: config.BitbucketConfig{
+			Domain:       "bitbucket.example.com",
+			Repositories: []string{"server-repo1", "server-repo2"},
+		},
+		BitbucketCloud: config.BitbucketCloudConfig{
+			Workspace:    "team",
+			Repositories: []string{"cloud-repo"},
+		},
+		GitHub: config.GitHubConfig{
+			Repositories: []string{"acme/github-repo"},
+		},
+		GitLab: config.GitLabConfig{
+			Repositories: []string{"platform/gitlab-project"},
+		},
+	}
+
+	repos := NewCatalog(cfg).Repositories(context.Background())
+	if len(repos) != 5 {
+		t.Fatalf("Expected 5 repositories, got %d", len(repos))
+	}
+
+	expected := []struct{ name, provider string }{
+		{"server-repo1", "bitbucket-server"},
+		{"server-repo2", "bitbucket-server"},
+		{"cloud-repo", "bitbucket-cloud"},
+		{"acme/github-repo", "github"},
+		{"platform/gitlab-project", "gitlab"},
+	}
+	for i, e := range expected {
+		if repos[i].Name != e.name || repos[i].Provider.Name() != e.provider {
+			t.Errorf("Expected %s served by %s, got %s served by %s", e.name, e.provider, repos[i].Name, repos[i].Provider.Name())
+		}
+	}
+}
+
+func TestProviders(t *testing.T) {
+	if providers := NewCatalog(&config.Config{}).Providers; len(providers) != 0 {
+		t.Errorf("Expected no providers for empty config, got %d", len(providers))
+	}
+
+	cfg := &config.Config{
+		BitbucketCloud: config.BitbucketCloudConfig{Workspace: "team"},
+	}
+	providers := NewCatalog(cfg).Providers
+	if len(providers) != 1 || providers[0].Name() != "bitbucket-cloud" {
+		t.Errorf("Expected only the Bitbucket Cloud provider, got %+v", providers)
+	}
+}
+
+func TestNewCatalog_Targets(t *testing.T) {
+	cfg := &config.Config{
+		Bitbucket: config.BitbucketConfig{
+			Domain:       "git.example.com",
+			Port:         443,
+			Workspace:    "CORE",
+			Repositories: []string{"api", "WEB/legacy"},
+			Targets: []config.BitbucketTarget{
+				{Project: "OPS", Domain: "ops.example.com", Repositories: []string{"infra"}},
+			},
+		},
+		GitHub: config.GitHubConfig{Owner: "acme", Repositories: []string{"tool", "other-org/lib"}},
+	}
+
+	catalog := NewCatalog(cfg)
+	if len(catalog.Providers) != 3 {
+		t.Fatalf("Expected server, target and GitHub providers, got %d", len(catalog.Providers))
+	}
+	if host := catalog.Providers[1].Host(); host != "ops.example.com:443" {
+		t.Errorf("Expected the target client to use its own server, got %s", host)
+	}
+
+	expected := []string{"CORE/api", "WEB/legacy", "OPS/infra", "acme/tool", "other-org/lib"}
+	repos := catalog.Repositories(context.Background())
+	if len(repos) != len(expected) {
+		t.Fatalf("Expected %d repositories, got %d", len(expected), len(repos))
+	}
+	for i, key := range expected {
+		if repos[i].Key() != key {
+			t.Errorf("Expected repository %d to be %s, got %s", i, key, repos[i].Key())
+		}
+	}
+	if repos[1].Name != "WEB/legacy" || repos[1].Slug() != "legacy" {
+		t.Errorf("Expected the provider name to be kept, got %+v", repos[1])
+	}
+}
+
+// discoveringProvider is a provider whose repositories come from discovery
+type discoveringProvider struct {
+	names []string
+	err   error
+}
+
+func (p *discoveringProvider) Name() string                         { return "discovering" }
+func (p *discoveringProvider) Host() string                         { return "example.com" }
+func (p *discoveringProvider) TestConnection(context.Context) error { return nil }
+func (p *discoveringProvider) ListOpenPRs(context.Context, string) ([]models.PullRequest, error) {
+	return nil, nil
+}
+func (p *discoveringProvider) GetParticipants(context.Context, string, int) ([]models.Participant, error) {
+	return nil, nil
+}
+func (p *discoveringProvider) GetComments(context.Context, string, int) ([]models.Comment, error) {
+	return nil, nil
+}
+func (p *discoveringProvider) DiscoverRepositories(context.Context) ([]string, error) {
+	return p.names, p.err
+}
+
+func TestCatalog_Discovery(t *testing.T) {
+	p := &discoveringProvider{names: []string{"listed", "new-repo"}}
+	catalog := NewStaticCatalog([]Repository{{Name: "listed", Provider: p}})
+
+	names := func() []string {
+		var out []string
+		for _, r := range catalog.Repositories(context.Background()) {
+			out = append(out, r.Name)
+		}
+		return out
+	}
+
+	if got := names(); len(got) != 2 || got[0] != "listed" || got[1] != "new-repo" {
+		t.Errorf("Expected [listed new-repo] without duplicates, got %v", got)
+	}
+
+	// A refresh picks up repositories created since the previous cycle
+	p.names = []string{"new-repo", "newer-repo"}
+	if got := names(); len(got) != 3 || got[2] != "newer-repo" {
+		t.Errorf("Expected the refreshed repository to be added, got %v", got)
+	}
+
+	// A failed refresh keeps the previous result
+	p.names, p.err = nil, errors.New("boom")
+	if got := names(); len(got) != 3 {
+		t.Errorf("Expected the previous discovery to be reused, got %v", got)
+	}
+}
+
+func TestCatalog_DiscoveryWithProjectPrefix(t *testing.T) {
+	p := &discoveringProvider{names: []string{"repo", "other"}}
+	catalog := &Catalog{projects: make(map[Provider]string)}
+	catalog.add(p, "PROJ", []string{"PROJ/repo"})
+
+	var got []string
+	for _, r := range catalog.Repositories(context.Background()) {
+		got = append(got, r.Key())
+	}
+	if len(got) != 2 || got[0] != "PROJ/repo" || got[1] != "PROJ/other" {
+		t.Errorf("Expected [PROJ/repo PROJ/other] with the listed repository once, got %v", got)
+	}
+}
@@ -0,0 +1,113 @@
+// Package schedule decides when the monitoring cycles run: on a fixed interval or a cron
+// expression, postponed out of quiet hours and weekends.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Trigger computes the next run after a given time
+type Trigger interface {
+	Next(after time.Time) time.Time
+}
+
+// Every fires a fixed interval after the previous run
+type Every time.Duration
+
+// Next returns after plus the interval
+func (e Every) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(e))
+}
+
+// QuietHours is a daily period during which nothing runs, in minutes since midnight.
+// End may be before Start for periods spanning midnight, such as 20:00-08:00.
+type QuietHours struct {
+	Start, End int
+}
+
+// ParseQuietHours parses the "HH:MM" bounds of a quiet period
+func ParseQuietHours(start, end string) (*QuietHours, error) {
+	s, err := parseClock(start)
+	if err != nil {
+		return nil, err
+	}
+	e, err := parseClock(end)
+	if err != nil {
+		return nil, err
+	}
+	if s == e {
+		return nil, fmt.Errorf("quiet hours start and end are both %s", start)
+	}
+	return &QuietHours{Start: s, End: e}, nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	hour, err1 := strconv.Atoi(h)
+	minute, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// contains reports whether the time of day, in minutes since midnight, is quiet
+func (q QuietHours) contains(minute int) bool {
+	if q.Start < q.End {
+		return minute >= q.Start && minute < q.End
+	}
+	return minute >= q.Start || minute < q.End
+}
+
+// Schedule fires on its trigger, except during quiet hours and, optionally, weekends:
+// a run due then is postponed to the first time allowed.
+type Schedule struct {
+	Trigger      Trigger
+	Location     *time.Location // quiet hours and weekends are evaluated in this zone
+	Quiet        *QuietHours    // nil when every hour is allowed
+	SkipWeekends bool
+}
+
+// Next returns the first allowed run after t
+func (s *Schedule) Next(t time.Time) time.Time {
+	next := s.Trigger.Next(t)
+	if next.IsZero() {
+		return next
+	}
+	return s.Defer(next)
+}
+
+// Defer returns t if a run is allowed then, or else the first time it is
+func (s *Schedule) Defer(t time.Time) time.Time {
+	loc := s.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	// A weekend followed by quiet hours takes two steps; the bound guards against loops
+	for i := 0; i < 8; i++ {
+		lt := t.In(loc)
+		y, m, d := lt.Date()
+		switch {
+		case s.SkipWeekends && (lt.Weekday() == time.Saturday || lt.Weekday() == time.Sunday):
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+		case s.Quiet != nil && s.Quiet.contains(lt.Hour()*60+lt.Minute()):
+			day := d
+			if s.Quiet.Start > s.Quiet.End && lt.Hour()*60+lt.Minute() >= s.Quiet.Start {
+				day++ // the quiet period ends tomorrow
+			}
+			t = time.Date(y, m, day, s.Quiet.End/60, s.Quiet.End%60, 0, 0, loc)
+		default:
+			return t
+		}
+	}
+	return t
+}
+
+// Allowed reports whether a run may happen at t
+func (s *Schedule) Allowed(t time.Time) bool {
+	return s.Defer(t).Equal(t)
+}
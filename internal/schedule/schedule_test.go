@@ -0,0 +1,176 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// lisbon is used for the tests that depend on a zone with daylight saving time
+func lisbon(t *testing.T) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Lisbon")
+	if err != nil {
+		t.Skipf("Time zone database unavailable: %v", err)
+	}
+	return loc
+}
+
+func TestParseCron_Errors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		message string
+	}{
+		{"0 9 * *", "must have 5 fields"},
+		{"60 9 * * *", "minute 60 out of range"},
+		{"0 9 * * FUN", `invalid day of week "FUN"`},
+		{"0 18-9 * * *", `invalid hour range "18-9"`},
+		{"*/0 * * * *", `invalid minute step "0"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseCron(tt.expr, time.UTC)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected an error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
+
+func TestCron_Next(t *testing.T) {
+	// Wednesday, March 18th 2026
+	from := time.Date(2026, 3, 18, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"0 9 * * MON-FRI", time.Date(2026, 3, 19, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * SAT,SUN", time.Date(2026, 3, 21, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2026, 3, 22, 9, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 3, 18, 10, 45, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2026, 3, 19, 10, 30, 0, 0, time.UTC)},
+		{"0 8-18/4 * * *", time.Date(2026, 3, 18, 12, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 FEB *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: the 1st of the month or any Monday
+		{"0 9 1 * MON", time.Date(2026, 3, 23, 9, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 3, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 FEB *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := ParseCron(tt.expr, time.UTC)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := c.Next(from); !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCron_NextInTimezone(t *testing.T) {
+	loc := lisbon(t)
+	c, err := ParseCron("0 9 * * *", loc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Lisbon switches to summer time (UTC+1) on March 29th 2026
+	next := c.Next(time.Date(2026, 3, 28, 12, 0, 0, 0, time.UTC))
+	if expected := time.Date(2026, 3, 29, 8, 0, 0, 0, time.UTC); !next.Equal(expected) {
+		t.Errorf("Expected 09:00 summer time (%v), got %v", expected, next.UTC())
+	}
+	next = c.Next(time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC))
+	if expected := time.Date(2026, 1, 11, 9, 0, 0, 0, time.UTC); !next.Equal(expected) {
+		t.Errorf("Expected 09:00 winter time (%v), got %v", expected, next.UTC())
+	}
+}
+
+func TestParseQuietHours(t *testing.T) {
+	q, err := ParseQuietHours("20:00", "08:30")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if q.Start != 20*60 || q.End != 8*60+30 {
+		t.Errorf("Expected 1200-510, got %d-%d", q.Start, q.End)
+	}
+
+	for _, bounds := range [][2]string{{"25:00", "08:00"}, {"20:00", "8h"}, {"09:00", "09:00"}} {
+		if _, err := ParseQuietHours(bounds[0], bounds[1]); err == nil {
+			t.Errorf("Expected an error for %v", bounds)
+		}
+	}
+}
+
+func TestSchedule_Defer(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		// March 2026: the 20th is a Friday, the 21st and 22nd a weekend
+		return time.Date(2026, 3, day, hour, minute, 0, 0, time.UTC)
+	}
+	overnight := &QuietHours{Start: 20 * 60, End: 8 * 60}
+	lunch := &QuietHours{Start: 12 * 60, End: 13*60 + 30}
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		at       time.Time
+		expected time.Time
+	}{
+		{"allowed", Schedule{Quiet: overnight}, at(18, 10, 0), at(18, 10, 0)},
+		{"late evening", Schedule{Quiet: overnight}, at(18, 23, 15), at(19, 8, 0)},
+		{"early morning", Schedule{Quiet: overnight}, at(19, 3, 0), at(19, 8, 0)},
+		{"quiet period end is allowed", Schedule{Quiet: overnight}, at(19, 8, 0), at(19, 8, 0)},
+		{"daytime quiet period", Schedule{Quiet: lunch}, at(18, 12, 10), at(18, 13, 30)},
+		{"weekend", Schedule{SkipWeekends: true}, at(21, 9, 0), at(23, 0, 0)},
+		{"weekend then quiet hours", Schedule{Quiet: overnight, SkipWeekends: true}, at(20, 22, 0), at(23, 8, 0)},
+		{"weekday", Schedule{SkipWeekends: true}, at(20, 22, 0), at(20, 22, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.schedule.Location = time.UTC
+			if got := tt.schedule.Defer(tt.at); !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+			if allowed := tt.schedule.Allowed(tt.at); allowed != tt.at.Equal(tt.expected) {
+				t.Errorf("Expected Allowed to be %v", !allowed)
+			}
+		})
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	cron, err := ParseCron("0 7 * * *", time.UTC)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s := &Schedule{Trigger: cron, Location: time.UTC, Quiet: &QuietHours{Start: 20 * 60, End: 8 * 60}, SkipWeekends: true}
+
+	// Friday afternoon: the Saturday and Sunday runs are skipped, Monday's is postponed to 08:00
+	next := s.Next(time.Date(2026, 3, 20, 15, 0, 0, 0, time.UTC))
+	if expected := time.Date(2026, 3, 23, 8, 0, 0, 0, time.UTC); !next.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, next)
+	}
+
+	every := &Schedule{Trigger: Every(6 * time.Hour), Location: time.UTC, Quiet: &QuietHours{Start: 20 * 60, End: 8 * 60}}
+	next = every.Next(time.Date(2026, 3, 18, 16, 0, 0, 0, time.UTC))
+	if expected := time.Date(2026, 3, 19, 8, 0, 0, 0, time.UTC); !next.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, next)
+	}
+}
+
+func TestSchedule_QuietHoursInTimezone(t *testing.T) {
+	loc := lisbon(t)
+	s := &Schedule{Trigger: Every(time.Hour), Location: loc, Quiet: &QuietHours{Start: 20 * 60, End: 8 * 60}}
+
+	// 20:30 UTC is 21:30 in Lisbon summer time, so the run moves to 08:00 local, 07:00 UTC
+	next := s.Defer(time.Date(2026, 6, 10, 20, 30, 0, 0, time.UTC))
+	if expected := time.Date(2026, 6, 11, 7, 0, 0, 0, time.UTC); !next.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, next.UTC())
+	}
+}
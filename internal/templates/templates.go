@@ -0,0 +1,250 @@
+// Package templates renders notifications from user-supplied text/template and html/template
+// files. Every template is executed with Data and may call the helpers of Funcs.
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"fc-pr-tracker/internal/calendar"
+	"fc-pr-tracker/pkg/models"
+)
+
+// Data is what notification templates are executed with
+type Data struct {
+	GeneratedAt    time.Time
+	TotalPRs       int
+	StaleAfterDays int    // threshold of the report, or of its escalation tier
+	Threshold      string // human-readable threshold, e.g. "3 business days"
+	BusinessDays   bool   // StaleAfterDays and DaysInactive count business days only
+	Tier           string // escalation tier, empty for the notifiers section
+	// PRs are the stale PRs in report order; Projects holds the same PRs by project and repository
+	PRs      []models.EnrichedPR
+	Projects []models.ProjectGroup
+}
+
+// NewData builds the template data of report
+func NewData(report *models.Report) Data {
+	return Data{
+		GeneratedAt:    report.GeneratedAt,
+		TotalPRs:       len(report.PRs),
+		StaleAfterDays: report.StaleAfterDays,
+		Threshold:      report.StaleThreshold(),
+		BusinessDays:   report.BusinessDays,
+		Tier:           report.Tier,
+		PRs:            report.PRs,
+		Projects:       report.ByProject(),
+	}
+}
+
+// Days formats a number of days in the unit of the report, e.g. {{$.Days .DaysInactive}}
+// renders "4 business days" when business days are counted
+func (d Data) Days(n int) string {
+	return (&models.Report{BusinessDays: d.BusinessDays}).Days(n)
+}
+
+// Funcs returns the helpers available to templates; dates are rendered in loc and days are
+// counted with cal, or every day counts when cal is nil, like .DaysInactive:
+//
+//	date      formats a millisecond timestamp or time.Time, e.g. "Mon, 02 Mar 2026 14:05 UTC"
+//	daysSince counts the whole days from a millisecond timestamp or time.Time until now
+//	humanize  describes a millisecond timestamp or time.Time relative to now, e.g. "3 days ago"
+//	approvals formats the approvals of a PR, e.g. "1/2"
+//	link      returns the web link of a PR
+//	json      encodes a value as JSON, e.g. a quoted and escaped string
+//	idleColor and approvalColor return the hex colors of the inactivity and approvals of a PR
+func Funcs(loc *time.Location, cal *calendar.Calendar) map[string]any {
+	if loc == nil {
+		loc = time.Local
+	}
+	return map[string]any{
+		"date": func(v any) (string, error) {
+			t, err := toTime(v)
+			if err != nil || t.IsZero() {
+				return "-", err
+			}
+			return t.In(loc).Format("Mon, 02 Jan 2006 15:04 MST"), nil
+		},
+		"daysSince": func(v any) (int, error) {
+			t, err := toTime(v)
+			if err != nil || t.IsZero() {
+				return 0, err
+			}
+			return cal.Days(t, time.Now()), nil
+		},
+		"humanize": func(v any) (string, error) {
+			t, err := toTime(v)
+			if err != nil || t.IsZero() {
+				return "never", err
+			}
+			return Humanize(time.Since(t)), nil
+		},
+		"approvals": func(pr models.EnrichedPR) string {
+			return fmt.Sprintf("%d/%d", pr.Approvals, pr.Reviewers)
+		},
+		"link": func(pr models.EnrichedPR) string {
+			return pr.Link()
+		},
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"idleColor":     IdleColor,
+		"approvalColor": ApprovalColor,
+	}
+}
+
+// toTime accepts the millisecond timestamps of pull requests and time.Time values
+func toTime(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case int64:
+		if t == 0 {
+			return time.Time{}, nil
+		}
+		return time.UnixMilli(t), nil
+	case time.Time:
+		return t, nil
+	default:
+		return time.Time{}, fmt.Errorf("expected a millisecond timestamp or time, got %T", v)
+	}
+}
+
+// Humanize describes an elapsed duration in its largest unit, e.g. "3 days ago" or "just now"
+func Humanize(d time.Duration) string {
+	unit := func(n int, name string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", name)
+		}
+		return fmt.Sprintf("%d %ss ago", n, name)
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return unit(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return unit(int(d/time.Hour), "hour")
+	default:
+		return unit(int(d/(24*time.Hour)), "day")
+	}
+}
+
+// IdleColor shades the inactivity of pr: amber once stale, red from twice its threshold.
+// threshold applies to PRs without a repository threshold of their own.
+func IdleColor(pr models.EnrichedPR, threshold int) string {
+	if pr.StaleAfterDays > 0 {
+		threshold = pr.StaleAfterDays
+	}
+	if threshold > 0 && pr.DaysInactive >= 2*threshold {
+		return "#c0392b"
+	}
+	return "#d68910"
+}
+
+// ApprovalColor is the badge color of the approvals of pr: green when every reviewer
+// approved, blue when some did, grey otherwise
+func ApprovalColor(pr models.EnrichedPR) string {
+	switch {
+	case pr.Reviewers > 0 && pr.Approvals == pr.Reviewers:
+		return "#1e8449"
+	case pr.Approvals > 0:
+		return "#2874a6"
+	default:
+		return "#7f8c8d"
+	}
+}
+
+// Parse parses an inline text/template, such as an email subject
+func Parse(name, text string, loc *time.Location, cal *calendar.Calendar) (*template.Template, error) {
+	t, err := template.New(name).Funcs(Funcs(loc, cal)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template: %v", err)
+	}
+	return t, nil
+}
+
+// ParseText parses the text/template file at path
+func ParseText(path string, loc *time.Location, cal *calendar.Calendar) (*template.Template, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading template: %v", err)
+	}
+	return Parse(filepath.Base(path), string(content), loc, cal)
+}
+
+// ParseHTML parses the html/template file at path
+func ParseHTML(path string, loc *time.Location, cal *calendar.Calendar) (*htmltemplate.Template, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading template: %v", err)
+	}
+	t, err := htmltemplate.New(filepath.Base(path)).Funcs(Funcs(loc, cal)).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template: %v", err)
+	}
+	return t, nil
+}
+
+// Executor is implemented by text and HTML templates
+type Executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// Check executes t against a sample report, which catches references to fields that do not exist
+func Check(t Executor) error {
+	if err := t.Execute(io.Discard, NewData(SampleReport())); err != nil {
+		return fmt.Errorf("error executing template with a sample report: %v", err)
+	}
+	return nil
+}
+
+// CheckJSON executes t against a sample report and verifies the output is valid JSON
+func CheckJSON(t Executor) error {
+	var out bytes.Buffer
+	if err := t.Execute(&out, NewData(SampleReport())); err != nil {
+		return fmt.Errorf("error executing template with a sample report: %v", err)
+	}
+	if !json.Valid(out.Bytes()) {
+		return fmt.Errorf("template output with a sample report is not valid JSON")
+	}
+	return nil
+}
+
+// SampleReport is a report with one stale PR, used to check templates
+func SampleReport() *models.Report {
+	idle := time.Now().AddDate(0, 0, -4)
+	pr := models.PullRequest{
+		ID:          1,
+		Title:       "Sample pull request",
+		State:       "OPEN",
+		Open:        true,
+		CreatedDate: idle.UnixMilli(),
+		UpdatedDate: idle.UnixMilli(),
+		Ref:         models.PRRef{Project: "TEST", Repo: "sample-repository", ID: 1},
+	}
+	pr.Author.User.DisplayName = "PR Tracker"
+	pr.Author.User.Username = "pr-tracker"
+	pr.ToRef.DisplayID = "main"
+	pr.Links.Self = append(pr.Links.Self, struct {
+		Href string `json:"href"`
+	}{Href: "https://example.com/pr-tracker/test"})
+
+	return &models.Report{
+		GeneratedAt:    time.Now(),
+		StaleAfterDays: 3,
+		PRs: []models.EnrichedPR{{
+			PullRequest:  pr,
+			Reviewers:    1,
+			LastActivity: idle,
+			DaysInactive: 4,
+			Stale:        true,
+		}},
+	}
+}
@@ -0,0 +1,157 @@
+package templates
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"fc-pr-tracker/internal/calendar"
+	"fc-pr-tracker/pkg/models"
+)
+
+func TestFuncs(t *testing.T) {
+	created := time.Date(2026, 3, 2, 14, 5, 0, 0, time.UTC)
+	pr := models.EnrichedPR{Approvals: 1, Reviewers: 2}
+	pr.CreatedDate = created.UnixMilli()
+	pr.Title = `Quote " and <tag>`
+	pr.Links.Self = append(pr.Links.Self, struct {
+		Href string `json:"href"`
+	}{Href: "https://example.com/pr/1"})
+
+	loc, err := time.LoadLocation("Europe/Lisbon")
+	if err != nil {
+		t.Skipf("Time zone database unavailable: %v", err)
+	}
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"date of a timestamp", "{{date .CreatedDate}}", "Mon, 02 Mar 2026 14:05 WET"},
+		{"date of a time", "{{date .LastActivity}}", "-"},
+		{"approvals", "{{approvals .}}", "1/2"},
+		{"link", "{{link .}}", "https://example.com/pr/1"},
+		{"json", "{{json .Title}}", `"Quote \" and \u003ctag\u003e"`},
+		{"approval color", "{{approvalColor .}}", "#2874a6"},
+		{"idle color", "{{idleColor . 3}}", "#d68910"},
+		{"days since", "{{if ge (daysSince .CreatedDate) 200}}old{{end}}", "old"},
+		{"humanize", "{{humanize .CreatedDate}}", "days ago"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.name, tt.template, loc, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var out strings.Builder
+			if err := tmpl.Execute(&out, pr); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !strings.Contains(out.String(), tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestFuncs_DaysSinceBusinessDays(t *testing.T) {
+	var pr models.EnrichedPR
+	pr.CreatedDate = time.Now().AddDate(0, 0, -70).UnixMilli()
+	sundays := calendar.New(time.UTC, calendar.Workweek{time.Sunday: true}, nil)
+
+	for _, tt := range []struct {
+		name     string
+		cal      *calendar.Calendar
+		min, max int
+	}{
+		{"every day", nil, 69, 70},
+		{"business days", sundays, 9, 10},
+	} {
+		tmpl, err := Parse(tt.name, "{{daysSince .CreatedDate}}", time.UTC, tt.cal)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var out strings.Builder
+		if err := tmpl.Execute(&out, pr); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if days, _ := strconv.Atoi(out.String()); days < tt.min || days > tt.max {
+			t.Errorf("%s: expected %d to %d days, got %s", tt.name, tt.min, tt.max, out.String())
+		}
+	}
+}
+
+func TestHumanize(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		expected string
+	}{
+		{30 * time.Second, "just now"},
+		{time.Minute, "1 minute ago"},
+		{5 * time.Hour, "5 hours ago"},
+		{49 * time.Hour, "2 days ago"},
+	}
+	for _, tt := range tests {
+		if got := Humanize(tt.d); got != tt.expected {
+			t.Errorf("Humanize(%v): expected %q, got %q", tt.d, tt.expected, got)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	valid, _ := Parse("valid", "{{range .PRs}}{{.Title}}{{end}}", nil, nil)
+	if err := Check(valid); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	unknown, _ := Parse("unknown", "{{range .PRs}}{{.Titel}}{{end}}", nil, nil)
+	if err := Check(unknown); err == nil || !strings.Contains(err.Error(), "Titel") {
+		t.Errorf("Expected an error about the unknown field, got %v", err)
+	}
+	notJSON, _ := Parse("json", `{"title": {{.TotalPRs}}`, nil, nil)
+	if err := CheckJSON(notJSON); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
+
+func TestExampleTemplates(t *testing.T) {
+	text, err := ParseText("../../templates/email.txt.tmpl", time.UTC, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := Check(text); err != nil {
+		t.Errorf("Example email template: %v", err)
+	}
+
+	html, err := ParseHTML("../../templates/email.html.tmpl", time.UTC, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := Check(html); err != nil {
+		t.Errorf("Example HTML email template: %v", err)
+	}
+
+	teams, err := ParseText("../../templates/teams.json.tmpl", time.UTC, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	report := SampleReport()
+	report.PRs = append(report.PRs, report.PRs[0])
+	var out strings.Builder
+	if err := teams.Execute(&out, NewData(report)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var payload struct {
+		Sections []struct {
+			Facts []struct{ Name, Value string }
+		}
+	}
+	if err := json.Unmarshal([]byte(out.String()), &payload); err != nil {
+		t.Fatalf("Example Teams template rendered invalid JSON: %v\n%s", err, out.String())
+	}
+	if len(payload.Sections) != 1 || len(payload.Sections[0].Facts) != 2 {
+		t.Errorf("Expected one section with 2 facts, got %+v", payload)
+	}
+}
@@ -0,0 +1,86 @@
+package tracker
+
+import (
+	"context"
+	"sync"
+)
+
+// pool runs jobs on a bounded number of goroutines, additionally limiting
+// how many jobs may talk to the same host at once
+type pool struct {
+	workers int
+	perHost int
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+// newPool creates a pool; non-positive limits fall back to a single worker
+// and to the number of workers respectively
+func newPool(workers, perHost int) *pool {
+	if workers <= 0 {
+		workers = 1
+	}
+	if perHost <= 0 || perHost > workers {
+		perHost = workers
+	}
+	return &pool{workers: workers, perHost: perHost, hosts: make(map[string]chan struct{})}
+}
+
+// run calls fn(i) for every i in [0, n) and waits for all calls to return.
+// Once ctx is done, remaining jobs are no longer dispatched. Jobs are dispatched
+// in order, so run returns the number of jobs started: jobs from that index on did not run.
+func (p *pool) run(ctx context.Context, n int, fn func(i int)) int {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	workers := p.workers
+	if n < workers {
+		workers = n
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+
+	started := 0
+dispatch:
+	for ; started < n && ctx.Err() == nil; started++ {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case jobs <- started:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return started
+}
+
+// withHost runs fn while holding one of the host's concurrency slots and returns its error.
+// It returns the context error without running fn if ctx is done before a slot is free.
+func (p *pool) withHost(ctx context.Context, host string, fn func() error) error {
+	p.mu.Lock()
+	slots, ok := p.hosts[host]
+	if !ok {
+		slots = make(chan struct{}, p.perHost)
+		p.hosts[host] = slots
+	}
+	p.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case slots <- struct{}{}:
+	}
+	defer func() { <-slots }()
+	return fn()
+}
@@ -0,0 +1,236 @@
+package tracker
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"fc-pr-tracker/internal/bitbucket"
+	"fc-pr-tracker/internal/calendar"
+	"fc-pr-tracker/internal/config"
+	"fc-pr-tracker/internal/provider"
+	"fc-pr-tracker/pkg/models"
+)
+
+// Result holds the outcome of one monitoring cycle
+type Result struct {
+	Report models.Report
+	// Open holds every open PR that passed the keyword filter, stale or not
+	Open []models.EnrichedPR
+	// Failures counts the repositories and PRs skipped because an API call failed
+	// or the cycle ended before they were processed
+	Failures int
+	// Retries summarizes the API calls retried or abandoned during the cycle
+	Retries bitbucket.RetryStats
+}
+
+// repoResult holds the filtered PRs of one repository and the pr_filter rule of each
+type repoResult struct {
+	prs   []models.PullRequest
+	rules []config.PRRule
+	ok    bool
+}
+
+// prResult holds the enrichment outcome of one PR
+type prResult struct {
+	participants []models.Participant
+	lastActivity time.Time
+	daysInactive int
+	stale        bool
+	failed       bool // an API call failed, so staleness is unknown
+}
+
+// prJob identifies one PR of one repository
+type prJob struct {
+	repo int
+	pr   int
+}
+
+// Collect fetches the open PRs of every repository, enriches them with participants
+// and activities, and returns the PRs that are stale. Work is spread over the
+// configured worker pool, but the result always follows the order of the
+// repositories and of the PRs returned by each provider.
+// Once ctx is done, pending API calls are cancelled and the PRs collected so far are returned;
+// the repositories and PRs left unprocessed count as failures.
+func Collect(ctx context.Context, cfg *config.Config, repos []provider.Repository) Result {
+	p := newPool(cfg.Concurrency.Workers, cfg.Concurrency.PerHost)
+	var failures atomic.Int64
+	now := time.Now()
+	cal, err := cfg.NewCalendar()
+	if err != nil {
+		slog.Error("Error loading the business day calendar, counting every day", "error", err)
+	}
+
+	// Stage 1: list and filter open PRs per repository
+	listed := make([]repoResult, len(repos))
+	listedRepos := p.run(ctx, len(repos), func(i int) {
+		repo, client := repos[i].Name, repos[i].Provider
+		slog.Info("Fetching open PRs for repository", "repo", repo, "provider", client.Name())
+
+		var prs []models.PullRequest
+		err := p.withHost(ctx, client.Host(), func() (err error) {
+			prs, err = client.ListOpenPRs(ctx, repo)
+			return err
+		})
+		if err != nil {
+			slog.Error("Error fetching PRs for repository", "repo", repo, "error", err)
+			failures.Add(1)
+			return
+		}
+		slog.Info("Total open PRs", "repo", repo, "total", len(prs))
+
+		res := repoResult{ok: true}
+		for _, pr := range prs {
+			rule := cfg.PRFilter.Rule(repos[i].Project, repos[i].Slug(), pr.TargetBranch())
+			if bitbucket.ContainsIgnoreKeyword(pr.Title, rule.IgnoreKeywords) {
+				continue
+			}
+			res.prs = append(res.prs, pr)
+			res.rules = append(res.rules, rule)
+		}
+		slog.Info("PRs after keyword filter", "repo", repo, "filtered_total", len(res.prs))
+		listed[i] = res
+	})
+	if skipped := len(repos) - listedRepos; skipped > 0 {
+		slog.Error("Cycle ended before every repository was fetched", "skipped", skipped, "error", ctx.Err())
+		failures.Add(int64(skipped))
+	}
+
+	// Stage 2: enrich every PR with participants and activities
+	var jobs []prJob
+	for r, res := range listed {
+		for i := range res.prs {
+			jobs = append(jobs, prJob{repo: r, pr: i})
+		}
+	}
+	enriched := make([]prResult, len(jobs))
+	enrichedPRs := p.run(ctx, len(jobs), func(i int) {
+		job := jobs[i]
+		enriched[i] = enrich(ctx, p, listed[job.repo].rules[job.pr], cal, now, repos[job.repo], listed[job.repo].prs[job.pr])
+		if enriched[i].failed {
+			failures.Add(1)
+		}
+	})
+	if skipped := len(jobs) - enrichedPRs; skipped > 0 {
+		slog.Error("Cycle ended before every PR was enriched", "skipped", skipped, "error", ctx.Err())
+		failures.Add(int64(skipped))
+	}
+
+	// Assemble the report in repository and PR order, leaving out the PRs that were not enriched
+	result := Result{Report: models.Report{
+		GeneratedAt:    now,
+		StaleAfterDays: cfg.PRFilter.StaleAfterDays,
+		BusinessDays:   cal != nil,
+	}}
+	for i, job := range jobs[:enrichedPRs] {
+		res := enriched[i]
+		if res.failed {
+			continue
+		}
+		repo := repos[job.repo]
+		pr := listed[job.repo].prs[job.pr]
+		pr.Ref = models.PRRef{Server: repo.Provider.Host(), Project: repo.Project, Repo: repo.Slug(), ID: pr.ID}
+		approvals, reviewers := bitbucket.CountApprovals(res.participants)
+		e := models.EnrichedPR{
+			PullRequest:    pr,
+			Participants:   res.participants,
+			Approvals:      approvals,
+			Reviewers:      reviewers,
+			LastActivity:   res.lastActivity,
+			DaysInactive:   res.daysInactive,
+			StaleAfterDays: listed[job.repo].rules[job.pr].StaleAfterDays,
+			Stale:          res.stale,
+		}
+		result.Open = append(result.Open, e)
+		if e.Stale {
+			result.Report.PRs = append(result.Report.PRs, e)
+		}
+	}
+	result.Failures = int(failures.Load())
+	result.Retries = takeRetryStats(repos)
+	if result.Retries.Retried > 0 || result.Retries.Abandoned > 0 {
+		slog.Warn("API retry summary", "retried", result.Retries.Retried, "abandoned", result.Retries.Abandoned)
+	}
+	return result
+}
+
+// takeRetryStats collects and resets the retry counters of every distinct provider
+func takeRetryStats(repos []provider.Repository) bitbucket.RetryStats {
+	var total bitbucket.RetryStats
+	seen := make(map[provider.Provider]bool)
+	for _, r := range repos {
+		reporter, ok := r.Provider.(provider.RetryReporter)
+		if !ok || seen[r.Provider] {
+			continue
+		}
+		seen[r.Provider] = true
+		stats := reporter.TakeRetryStats()
+		if stats.Retried > 0 || stats.Abandoned > 0 {
+			slog.Info("Provider retry summary", "provider", r.Provider.Name(), "retried", stats.Retried, "abandoned", stats.Abandoned)
+		}
+		total.Retried += stats.Retried
+		total.Abandoned += stats.Abandoned
+	}
+	return total
+}
+
+// enrich fetches the participants and activities of a PR and decides with its rule whether
+// it is stale, counting the days of inactivity up to now with cal
+func enrich(ctx context.Context, p *pool, rule config.PRRule, cal *calendar.Calendar, now time.Time, r provider.Repository, pr models.PullRequest) prResult {
+	repo, client := r.Name, r.Provider
+	var res prResult
+
+	var participants []models.Participant
+	err := p.withHost(ctx, client.Host(), func() (err error) {
+		participants, err = client.GetParticipants(ctx, repo, pr.ID)
+		return err
+	})
+	if err != nil {
+		slog.Error("Error fetching PR participants", "repo", repo, "pr_id", pr.ID, "error", err)
+		res.failed = true
+		return res
+	}
+	res.participants = participants
+
+	// Approved PRs are never stale, so their activities are not fetched
+	if bitbucket.HasRequiredApprovals(participants, rule.MinApprovals) {
+		res.lastActivity, res.daysInactive, _ = lastActivity(pr, nil, cal, now)
+		return res
+	}
+
+	var comments []models.Comment
+	err = p.withHost(ctx, client.Host(), func() (err error) {
+		comments, err = client.GetComments(ctx, repo, pr.ID)
+		return err
+	})
+	if err != nil {
+		slog.Error("Error fetching PR comments", "repo", repo, "pr_id", pr.ID, "error", err)
+		res.failed = true
+		return res
+	}
+
+	lastTime, days, ok := lastActivity(pr, comments, cal, now)
+	if !ok {
+		return res
+	}
+	res.lastActivity, res.daysInactive = lastTime, days
+	res.stale = days >= rule.StaleAfterDays
+	return res
+}
+
+// lastActivity returns the latest activity of a PR and the whole days, counted by cal, elapsed until now
+func lastActivity(pr models.PullRequest, comments []models.Comment, cal *calendar.Calendar, now time.Time) (time.Time, int, bool) {
+	last := bitbucket.GetLastActivity(pr, comments)
+	if last == "" {
+		slog.Warn("No last activity date found for PR", "pr_id", pr.ID, "title", pr.Title)
+		return time.Time{}, 0, false
+	}
+
+	lastTime, err := time.Parse(time.RFC3339, last)
+	if err != nil {
+		slog.Warn("Error parsing PR last activity date", "pr_id", pr.ID, "title", pr.Title, "date", last, "error", err)
+		return time.Time{}, 0, false
+	}
+	return lastTime, cal.Days(lastTime, now), true
+}
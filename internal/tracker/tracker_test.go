@@ -0,0 +1,370 @@
+package tracker
+
+import (
+	"context"
+	"fc-pr-tracker/internal/bitbucket"
+	"fc-pr-tracker/internal/config"
+	"fc-pr-tracker/internal/provider"
+	"fc-pr-tracker/pkg/models"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeProvider serves canned PRs and records the peak number of concurrent calls
+type fakeProvider struct {
+	host     string
+	prs      map[string][]models.PullRequest
+	approved map[int]bool
+	delay    time.Duration
+
+	mu      sync.Mutex
+	active  int
+	peak    int
+	counter *peakCounter
+}
+
+// peakCounter tracks concurrent calls across several providers
+type peakCounter struct {
+	mu     sync.Mutex
+	active int
+	peak   int
+}
+
+func (c *peakCounter) enter() {
+	c.mu.Lock()
+	c.active++
+	if c.active > c.peak {
+		c.peak = c.active
+	}
+	c.mu.Unlock()
+}
+
+func (c *peakCounter) leave() {
+	c.mu.Lock()
+	c.active--
+	c.mu.Unlock()
+}
+
+func (f *fakeProvider) call() func() {
+	f.mu.Lock()
+	f.active++
+	if f.active > f.peak {
+		f.peak = f.active
+	}
+	f.mu.Unlock()
+	if f.counter != nil {
+		f.counter.enter()
+	}
+	time.Sleep(f.delay)
+	return func() {
+		f.mu.Lock()
+		f.active--
+		f.mu.Unlock()
+		if f.counter != nil {
+			f.counter.leave()
+		}
+	}
+}
+
+func (f *fakeProvider) Name() string                             { return "fake" }
+func (f *fakeProvider) Host() string                             { return f.host }
+func (f *fakeProvider) TestConnection(ctx context.Context) error { return nil }
+
+func (f *fakeProvider) ListOpenPRs(ctx context.Context, repo string) ([]models.PullRequest, error) {
+	defer f.call()()
+	prs, ok := f.prs[repo]
+	if !ok {
+		return nil, fmt.Errorf("unknown repository %s", repo)
+	}
+	return prs, nil
+}
+
+func (f *fakeProvider) GetParticipants(ctx context.Context, repo string, prID int) ([]models.Participant, error) {
+	defer f.call()()
+	return []models.Participant{{Role: "REVIEWER", Approved: f.approved[prID]}}, nil
+}
+
+func (f *fakeProvider) GetComments(ctx context.Context, repo string, prID int) ([]models.Comment, error) {
+	defer f.call()()
+	return nil, nil
+}
+
+func stalePRs(ids ...int) []models.PullRequest {
+	old := time.Now().AddDate(0, 0, -10).UnixMilli()
+	var prs []models.PullRequest
+	for _, id := range ids {
+		prs = append(prs, models.PullRequest{ID: id, Title: fmt.Sprintf("PR %d", id), CreatedDate: old, UpdatedDate: old})
+	}
+	return prs
+}
+
+func testConfig(workers, perHost int) *config.Config {
+	return &config.Config{
+		PRFilter:    config.PRFilterConfig{StaleAfterDays: 3, IgnoreKeywords: []string{"WIP"}},
+		Concurrency: config.ConcurrencyConfig{Workers: workers, PerHost: perHost},
+	}
+}
+
+func TestCollect_DeterministicOrder(t *testing.T) {
+	fake := &fakeProvider{
+		host: "bitbucket.example.com",
+		prs: map[string][]models.PullRequest{
+			"repo-a": stalePRs(5, 3, 9),
+			"repo-b": stalePRs(1, 2),
+			"repo-c": stalePRs(7),
+		},
+		approved: map[int]bool{2: true},
+		delay:    time.Millisecond,
+	}
+	repos := []provider.Repository{
+		{Name: "repo-a", Provider: fake},
+		{Name: "repo-b", Provider: fake},
+		{Name: "repo-c", Provider: fake},
+	}
+
+	for run := 0; run < 5; run++ {
+		result := Collect(context.Background(), testConfig(8, 8), repos)
+
+		var refs []string
+		for _, pr := range result.Report.PRs {
+			refs = append(refs, pr.Ref.String())
+			if len(pr.Participants) != 1 || pr.Reviewers != 1 || pr.Approvals != 0 {
+				t.Errorf("Expected PR %d to carry its single unapproved reviewer, got %+v", pr.ID, pr.Participants)
+			}
+		}
+		expected := "[bitbucket.example.com/repo-a#5 bitbucket.example.com/repo-a#3 bitbucket.example.com/repo-a#9 bitbucket.example.com/repo-b#1 bitbucket.example.com/repo-c#7]"
+		if fmt.Sprint(refs) != expected {
+			t.Fatalf("Expected PRs in repository and provider order %s, got %v", expected, refs)
+		}
+	}
+}
+
+func TestCollect_SkipsFailingRepositoriesAndIgnoredPRs(t *testing.T) {
+	prs := stalePRs(1, 2)
+	prs[1].Title = "WIP: not ready"
+	fake := &fakeProvider{host: "h", prs: map[string][]models.PullRequest{"repo-a": prs}}
+	repos := []provider.Repository{
+		{Name: "missing", Provider: fake},
+		{Name: "repo-a", Provider: fake},
+	}
+
+	result := Collect(context.Background(), testConfig(2, 0), repos)
+	prs = nil
+	for _, pr := range result.Report.PRs {
+		prs = append(prs, pr.PullRequest)
+	}
+	if len(prs) != 1 || prs[0].ID != 1 || prs[0].Ref.Repo != "repo-a" {
+		t.Errorf("Expected only PR 1 of repo-a to be stale, got %+v", prs)
+	}
+}
+
+func TestCollect_PerHostLimit(t *testing.T) {
+	counter := &peakCounter{}
+	hostA := &fakeProvider{host: "a.example.com", prs: map[string][]models.PullRequest{}, delay: 5 * time.Millisecond, counter: counter}
+	hostB := &fakeProvider{host: "b.example.com", prs: map[string][]models.PullRequest{}, delay: 5 * time.Millisecond, counter: counter}
+
+	var repos []provider.Repository
+	for i := 0; i < 6; i++ {
+		name := fmt.Sprintf("repo-%d", i)
+		hostA.prs[name] = stalePRs(1, 2, 3)
+		hostB.prs[name] = stalePRs(1, 2, 3)
+		repos = append(repos, provider.Repository{Name: name, Provider: hostA}, provider.Repository{Name: name, Provider: hostB})
+	}
+
+	Collect(context.Background(), testConfig(6, 2), repos)
+
+	if hostA.peak > 2 || hostB.peak > 2 {
+		t.Errorf("Expected at most 2 concurrent calls per host, got %d and %d", hostA.peak, hostB.peak)
+	}
+	if counter.peak < 3 {
+		t.Errorf("Expected calls to different hosts to run in parallel, peak was %d", counter.peak)
+	}
+}
+
+// retryingProvider reports canned retry statistics
+type retryingProvider struct {
+	fakeProvider
+	stats bitbucket.RetryStats
+}
+
+func (r *retryingProvider) TakeRetryStats() bitbucket.RetryStats {
+	stats := r.stats
+	r.stats = bitbucket.RetryStats{}
+	return stats
+}
+
+func TestCollect_RetrySummary(t *testing.T) {
+	fake := &retryingProvider{
+		fakeProvider: fakeProvider{host: "h", prs: map[string][]models.PullRequest{"repo-a": nil, "repo-b": nil}},
+		stats:        bitbucket.RetryStats{Retried: 4, Abandoned: 1},
+	}
+	repos := []provider.Repository{
+		{Name: "repo-a", Provider: fake},
+		{Name: "repo-b", Provider: fake},
+	}
+
+	result := Collect(context.Background(), testConfig(2, 2), repos)
+	if result.Retries.Retried != 4 || result.Retries.Abandoned != 1 {
+		t.Errorf("Expected provider stats to be counted once, got %+v", result.Retries)
+	}
+}
+
+func TestNewPool_Defaults(t *testing.T) {
+	p := newPool(0, 0)
+	if p.workers != 1 || p.perHost != 1 {
+		t.Errorf("Expected 1 worker and 1 per host, got %d/%d", p.workers, p.perHost)
+	}
+	p = newPool(8, 20)
+	if p.perHost != 8 {
+		t.Errorf("Expected per-host limit capped at workers, got %d", p.perHost)
+	}
+}
+
+func TestCollect_CancelledContext(t *testing.T) {
+	counter := &peakCounter{}
+	fake := &fakeProvider{host: "h", prs: map[string][]models.PullRequest{"repo-a": stalePRs(1)}, counter: counter}
+	repos := []provider.Repository{{Name: "repo-a", Provider: fake}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result := Collect(ctx, testConfig(2, 2), repos)
+	if len(result.Report.PRs) != 0 {
+		t.Errorf("Expected no PRs for a cancelled cycle, got %d", len(result.Report.PRs))
+	}
+	if counter.peak != 0 {
+		t.Errorf("Expected no provider calls for a cancelled cycle, got %d", counter.peak)
+	}
+	if result.Failures != 1 {
+		t.Errorf("Expected the unfetched repository to count as a failure, got %d", result.Failures)
+	}
+}
+
+// cancellingProvider cancels the cycle once the participants of the first PR are fetched
+type cancellingProvider struct {
+	*fakeProvider
+	cancel context.CancelFunc
+}
+
+func (c *cancellingProvider) GetParticipants(ctx context.Context, repo string, prID int) ([]models.Participant, error) {
+	c.cancel()
+	return c.fakeProvider.GetParticipants(ctx, repo, prID)
+}
+
+func TestCollect_DeadlineDuringEnrichment(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := &cancellingProvider{fakeProvider: &fakeProvider{host: "h", prs: map[string][]models.PullRequest{"repo-a": stalePRs(1, 2, 3)}}, cancel: cancel}
+	repos := []provider.Repository{{Name: "repo-a", Provider: fake}}
+
+	result := Collect(ctx, testConfig(1, 1), repos)
+	if len(result.Open) != 0 || len(result.Report.PRs) != 0 {
+		t.Errorf("Expected no PRs from an interrupted enrichment, got %d open and %d stale", len(result.Open), len(result.Report.PRs))
+	}
+	if result.Failures != 3 {
+		t.Errorf("Expected the 3 unfinished PRs to count as failures, got %d", result.Failures)
+	}
+}
+
+func TestCollect_RepositoryScopedIdentity(t *testing.T) {
+	// PR #12 exists in both repositories; only the CORE one is approved
+	fake := &fakeProvider{
+		host: "h",
+		prs: map[string][]models.PullRequest{
+			"CORE/api": stalePRs(12),
+			"WEB/api":  stalePRs(12),
+		},
+	}
+	approvedIn := map[string]bool{"CORE/api": true}
+	repos := []provider.Repository{
+		{Name: "CORE/api", Project: "CORE", Provider: &scopedProvider{fakeProvider: fake, approvedIn: approvedIn}},
+		{Name: "WEB/api", Project: "WEB", Provider: &scopedProvider{fakeProvider: fake, approvedIn: approvedIn}},
+	}
+
+	result := Collect(context.Background(), testConfig(2, 2), repos)
+
+	if len(result.Report.PRs) != 1 {
+		t.Fatalf("Expected only the unapproved PR #12 to be stale, got %d PRs", len(result.Report.PRs))
+	}
+	pr := result.Report.PRs[0]
+	want := models.PRRef{Server: "h", Project: "WEB", Repo: "api", ID: 12}
+	if pr.Ref != want {
+		t.Errorf("Expected ref %+v, got %+v", want, pr.Ref)
+	}
+	if pr.Approvals != 0 || pr.Reviewers != 1 {
+		t.Errorf("Expected the WEB participants (0/1), got %d/%d", pr.Approvals, pr.Reviewers)
+	}
+}
+
+// scopedProvider approves PRs per repository, unlike fakeProvider which approves per PR ID
+type scopedProvider struct {
+	*fakeProvider
+	approvedIn map[string]bool
+}
+
+func (s *scopedProvider) GetParticipants(ctx context.Context, repo string, prID int) ([]models.Participant, error) {
+	return []models.Participant{{Role: "REVIEWER", Approved: s.approvedIn[repo]}}, nil
+}
+
+func TestCollect_BusinessDays(t *testing.T) {
+	fake := &fakeProvider{host: "h", prs: map[string][]models.PullRequest{"repo-a": stalePRs(1)}}
+	repos := []provider.Repository{{Name: "repo-a", Provider: fake}}
+	cfg := testConfig(2, 0)
+	cfg.PRFilter.BusinessDays = config.BusinessDaysConfig{
+		Enabled:  true,
+		Workweek: []string{"MON", "TUE", "WED", "THU", "FRI", "SAT", "SUN"},
+		Timezone: "UTC",
+	}
+	// The PR was updated 10 days ago, but fewer than 3 of them are business days
+	for day := 1; day <= 8; day++ {
+		cfg.PRFilter.BusinessDays.Holidays = append(cfg.PRFilter.BusinessDays.Holidays, time.Now().UTC().AddDate(0, 0, -day).Format("2006-01-02"))
+	}
+
+	result := Collect(context.Background(), cfg, repos)
+	if len(result.Report.PRs) != 0 || len(result.Open) != 1 {
+		t.Fatalf("Expected one open PR that is not stale, got %d stale and %d open", len(result.Report.PRs), len(result.Open))
+	}
+	if days := result.Open[0].DaysInactive; days >= 3 {
+		t.Errorf("Expected fewer than 3 business days of inactivity, got %d", days)
+	}
+	if !result.Report.BusinessDays {
+		t.Error("Expected the report to count business days")
+	}
+}
+
+func TestCollect_Overrides(t *testing.T) {
+	prs := stalePRs(1, 2, 3)
+	prs[1].Title = "WIP: urgent fix"
+	prs[1].ToRef.DisplayID = "hotfix/login"
+	fake := &fakeProvider{
+		host:     "h",
+		prs:      map[string][]models.PullRequest{"api": prs, "docs": stalePRs(4)},
+		approved: map[int]bool{3: true},
+	}
+	repos := []provider.Repository{
+		{Name: "api", Project: "CORE", Provider: fake},
+		{Name: "docs", Project: "CORE", Provider: fake},
+	}
+	thirty, two := 30, 2
+	cfg := testConfig(2, 0)
+	cfg.PRFilter.Overrides = []config.PRFilterOverride{
+		{Repositories: []string{"docs"}, StaleAfterDays: &thirty},
+		{Branches: []string{"hotfix/*"}, IgnoreKeywords: []string{}},
+		{Repositories: []string{"CORE/api"}, MinApprovals: &two},
+	}
+
+	result := Collect(context.Background(), cfg, repos)
+	var stale []int
+	for _, pr := range result.Report.PRs {
+		stale = append(stale, pr.ID)
+	}
+	// PR 2 keeps its WIP title on a hotfix branch, PR 3 lacks a second approval and PR 4 is in docs
+	if fmt.Sprint(stale) != "[1 2 3]" {
+		t.Errorf("Expected PRs [1 2 3] to be stale, got %v", stale)
+	}
+	if len(result.Open) != 4 || result.Open[3].StaleAfterDays != 30 {
+		t.Errorf("Expected 4 open PRs, the docs one with a 30 day threshold, got %+v", result.Open)
+	}
+}
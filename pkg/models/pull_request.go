@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"strings"
 	"time"
 )
 
@@ -17,15 +18,22 @@ type PullRequest struct {
 	Closed      bool   `json:"closed"`
 	CreatedDate int64  `json:"createdDate"` // Unix timestamp in milliseconds
 	UpdatedDate int64  `json:"updatedDate"` // Unix timestamp in milliseconds
+	Ref         PRRef  `json:"ref"`         // repository-scoped identity, set by the tracker
 	Author      struct {
 		User struct {
 			DisplayName string `json:"displayName"`
 			Username    string `json:"name"`
+			Email       string `json:"emailAddress"` // empty when the provider does not expose it
 		} `json:"user"`
 		Role     string `json:"role"`
 		Approved bool   `json:"approved"`
 		Status   string `json:"status"`
 	} `json:"author"`
+	// ToRef is the branch the PR merges into
+	ToRef struct {
+		ID        string `json:"id"`        // full ref, e.g. refs/heads/main
+		DisplayID string `json:"displayId"` // branch name, e.g. main
+	} `json:"toRef"`
 	Participants []Participant `json:"participants"`
 	Links        struct {
 		Self []struct {
@@ -34,6 +42,22 @@ type PullRequest struct {
 	} `json:"links"`
 }
 
+// TargetBranch returns the name of the branch the PR merges into
+func (pr PullRequest) TargetBranch() string {
+	if pr.ToRef.DisplayID != "" {
+		return pr.ToRef.DisplayID
+	}
+	return strings.TrimPrefix(pr.ToRef.ID, "refs/heads/")
+}
+
+// Link returns the web link of the PR, or "" when the provider returned none
+func (pr PullRequest) Link() string {
+	if len(pr.Links.Self) == 0 {
+		return ""
+	}
+	return pr.Links.Self[0].Href
+}
+
 // Participant represents a PR participant (reviewer, author, etc.)
 type Participant struct {
 	User struct {
@@ -127,6 +127,32 @@ func TestPullRequest_IsStale(t *testing.T) {
 	}
 }
 
+func TestPullRequest_TargetBranch(t *testing.T) {
+	var pr PullRequest
+	pr.ToRef.ID = "refs/heads/release/2.0"
+	if got := pr.TargetBranch(); got != "release/2.0" {
+		t.Errorf("Expected release/2.0 from the ref, got %q", got)
+	}
+	pr.ToRef.DisplayID = "release/2.0"
+	pr.ToRef.ID = ""
+	if got := pr.TargetBranch(); got != "release/2.0" {
+		t.Errorf("Expected release/2.0 from the display ID, got %q", got)
+	}
+}
+
+func TestPullRequest_Link(t *testing.T) {
+	var pr PullRequest
+	if got := pr.Link(); got != "" {
+		t.Errorf("Expected no link, got %q", got)
+	}
+	pr.Links.Self = append(pr.Links.Self, struct {
+		Href string `json:"href"`
+	}{Href: "https://example.com/pr/1"})
+	if got := pr.Link(); got != "https://example.com/pr/1" {
+		t.Errorf("Expected https://example.com/pr/1, got %q", got)
+	}
+}
+
 func TestPullRequest_GetApprovalCount(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PRRef identifies a pull request. PR IDs are only unique within a repository,
+// so the server, project and repository are part of the identity.
+type PRRef struct {
+	Server  string `json:"server,omitempty"`
+	Project string `json:"project,omitempty"`
+	Repo    string `json:"repo,omitempty"`
+	ID      int    `json:"id"`
+}
+
+// String formats the reference as "server/project/repo#id", leaving out empty parts
+func (r PRRef) String() string {
+	return formatRef(r.ID, r.Server, r.Project, r.Repo)
+}
+
+// Short formats the reference without its server, as "project/repo#id", for messages
+// sent outside the tracker
+func (r PRRef) Short() string {
+	return formatRef(r.ID, r.Project, r.Repo)
+}
+
+func formatRef(id int, names ...string) string {
+	var parts []string
+	for _, p := range names {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return fmt.Sprintf("%s#%d", strings.Join(parts, "/"), id)
+}
+
+// Report is the outcome of a monitoring cycle, handed to every notifier
+type Report struct {
+	GeneratedAt    time.Time
+	StaleAfterDays int
+	BusinessDays   bool         // StaleAfterDays and DaysInactive count business days only
+	PRs            []EnrichedPR // stale PRs, in repository and provider order
+	// Tier names the escalation tier the report is sent to, whose threshold is StaleAfterDays
+	Tier string
+}
+
+// StaleThreshold describes the inactivity after which the PRs are stale, e.g. "3 business days",
+// or "1 to 7 days" when their repositories have different thresholds. The PRs of an escalation
+// tier are described by the tier's threshold.
+func (r *Report) StaleThreshold() string {
+	low, high := r.StaleAfterDays, r.StaleAfterDays
+	for i, pr := range r.PRs {
+		if r.Tier != "" {
+			break
+		}
+		days := pr.StaleAfterDays
+		if days == 0 {
+			days = r.StaleAfterDays
+		}
+		if i == 0 || days < low {
+			low = days
+		}
+		if i == 0 || days > high {
+			high = days
+		}
+	}
+	if low == high {
+		return r.Days(low)
+	}
+	return fmt.Sprintf("%d to %d %s", low, high, r.dayUnit(high))
+}
+
+// Days formats a number of days in the unit the report counts, e.g. "4 business days" or "1 day"
+func (r *Report) Days(n int) string {
+	return fmt.Sprintf("%d %s", n, r.dayUnit(n))
+}
+
+func (r *Report) dayUnit(n int) string {
+	unit := "days"
+	if r.BusinessDays {
+		unit = "business days"
+	}
+	if n == 1 {
+		unit = strings.TrimSuffix(unit, "s")
+	}
+	return unit
+}
+
+// EnrichedPR is an open pull request enriched with the data gathered during the cycle
+type EnrichedPR struct {
+	PullRequest
+	Participants []Participant
+	Approvals    int       // reviewers who approved
+	Reviewers    int       // reviewers assigned
+	LastActivity time.Time // latest update, comment or approval
+	DaysInactive int
+	// StaleAfterDays is the threshold of the PR's repository, which may override the report's
+	StaleAfterDays int
+	Stale          bool
+}
+
+// ProjectGroup holds the stale PRs of one project, grouped by repository
+type ProjectGroup struct {
+	Server  string
+	Project string
+	Repos   []RepoGroup
+}
+
+// RepoGroup holds the stale PRs of one repository
+type RepoGroup struct {
+	Repo string
+	PRs  []EnrichedPR
+}
+
+// ByProject arranges the stale PRs by project and repository, both sorted by name.
+// PRs keep their report order within a repository.
+func (r *Report) ByProject() []ProjectGroup {
+	type projectKey struct{ server, project string }
+	projects := make(map[projectKey]*ProjectGroup)
+	repos := make(map[PRRef]int) // index in the project group, keyed by the ref without ID
+	var order []projectKey
+
+	for _, pr := range r.PRs {
+		key := projectKey{pr.Ref.Server, pr.Ref.Project}
+		group, ok := projects[key]
+		if !ok {
+			group = &ProjectGroup{Server: pr.Ref.Server, Project: pr.Ref.Project}
+			projects[key] = group
+			order = append(order, key)
+		}
+		repoKey := pr.Ref
+		repoKey.ID = 0
+		i, ok := repos[repoKey]
+		if !ok {
+			i = len(group.Repos)
+			repos[repoKey] = i
+			group.Repos = append(group.Repos, RepoGroup{Repo: pr.Ref.Repo})
+		}
+		group.Repos[i].PRs = append(group.Repos[i].PRs, pr)
+	}
+
+	groups := make([]ProjectGroup, 0, len(order))
+	for _, key := range order {
+		group := *projects[key]
+		sort.SliceStable(group.Repos, func(a, b int) bool { return group.Repos[a].Repo < group.Repos[b].Repo })
+		groups = append(groups, group)
+	}
+	sort.SliceStable(groups, func(a, b int) bool {
+		if groups[a].Project != groups[b].Project {
+			return groups[a].Project < groups[b].Project
+		}
+		return groups[a].Server < groups[b].Server
+	})
+	return groups
+}
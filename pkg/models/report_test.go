@@ -0,0 +1,94 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestPRRef_String(t *testing.T) {
+	tests := []struct {
+		ref      PRRef
+		expected string
+		short    string
+	}{
+		{PRRef{Server: "git.example.com", Project: "CORE", Repo: "api", ID: 12}, "git.example.com/CORE/api#12", "CORE/api#12"},
+		{PRRef{Server: "git.example.com:7990", Repo: "api", ID: 3}, "git.example.com:7990/api#3", "api#3"},
+		{PRRef{ID: 7}, "#7", "#7"},
+	}
+	for _, tt := range tests {
+		if got := tt.ref.String(); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+		if got := tt.ref.Short(); got != tt.short {
+			t.Errorf("Expected short %q, got %q", tt.short, got)
+		}
+	}
+}
+
+func TestReport_StaleThreshold(t *testing.T) {
+	if got := (&Report{StaleAfterDays: 3}).StaleThreshold(); got != "3 days" {
+		t.Errorf("Expected \"3 days\", got %q", got)
+	}
+	if got := (&Report{StaleAfterDays: 3, BusinessDays: true}).StaleThreshold(); got != "3 business days" {
+		t.Errorf("Expected \"3 business days\", got %q", got)
+	}
+
+	// PRs carry the thresholds of their repositories
+	report := &Report{StaleAfterDays: 3, PRs: []EnrichedPR{{StaleAfterDays: 1}, {StaleAfterDays: 7}, {}}}
+	if got := report.StaleThreshold(); got != "1 to 7 days" {
+		t.Errorf("Expected \"1 to 7 days\", got %q", got)
+	}
+	report.PRs = report.PRs[:1]
+	if got := report.StaleThreshold(); got != "1 day" {
+		t.Errorf("Expected \"1 day\", got %q", got)
+	}
+}
+
+func TestReport_Days(t *testing.T) {
+	tests := []struct {
+		businessDays bool
+		days         int
+		expected     string
+	}{
+		{false, 4, "4 days"},
+		{false, 1, "1 day"},
+		{true, 4, "4 business days"},
+		{true, 1, "1 business day"},
+		{true, 0, "0 business days"},
+	}
+	for _, tt := range tests {
+		if got := (&Report{BusinessDays: tt.businessDays}).Days(tt.days); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
+
+func TestReport_ByProject(t *testing.T) {
+	pr := func(server, project, repo string, id int) EnrichedPR {
+		var p EnrichedPR
+		p.ID = id
+		p.Ref = PRRef{Server: server, Project: project, Repo: repo, ID: id}
+		return p
+	}
+	report := &Report{PRs: []EnrichedPR{
+		pr("h", "WEB", "site", 1),
+		pr("h", "CORE", "web", 2),
+		pr("h", "CORE", "api", 12),
+		pr("h", "WEB", "site", 3),
+		pr("other", "CORE", "api", 12),
+	}}
+
+	groups := report.ByProject()
+	if len(groups) != 3 {
+		t.Fatalf("Expected 3 project groups, got %d", len(groups))
+	}
+	if groups[0].Project != "CORE" || groups[0].Server != "h" || groups[1].Server != "other" || groups[2].Project != "WEB" {
+		t.Errorf("Unexpected project order: %+v", groups)
+	}
+	if groups[0].Repos[0].Repo != "api" || groups[0].Repos[1].Repo != "web" {
+		t.Errorf("Expected repositories sorted by name, got %+v", groups[0].Repos)
+	}
+	site := groups[2].Repos[0].PRs
+	if len(site) != 2 || site[0].ID != 1 || site[1].ID != 3 {
+		t.Errorf("Expected PRs to keep report order within a repository, got %+v", site)
+	}
+}